@@ -1,75 +1,525 @@
 package config
 
 import (
-	"context"
-	"log"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/joho/godotenv"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
 )
 
-var (
-	Client             *mongo.Client
-	Database           *mongo.Database
-	DiaryCollection    *mongo.Collection
-	UserCollection     *mongo.Collection
-	GeminiFlashAPIKey  string
+// Backend analisis emosi yang didukung oleh EMOTION_ANALYZER
+const (
+	AnalyzerGemini  = "gemini"
+	AnalyzerLexicon = "lexicon"
 )
 
-func LoadEnv() {
+// Backend pengiriman email yang didukung oleh MAILER
+const (
+	MailerLog  = "log"
+	MailerSMTP = "smtp"
+)
+
+// Kebijakan untuk user yang belum memverifikasi email (UNVERIFIED_POLICY)
+const (
+	UnverifiedAllow    = "allow"     // boleh melakukan semua hal
+	UnverifiedReadOnly = "read_only" // boleh login dan membaca, tetapi tidak bisa menulis diary
+	UnverifiedBlock    = "block"     // tidak bisa login sama sekali
+)
+
+// Store counter rate limit yang didukung oleh RATE_LIMIT_STORE
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreMongo  = "mongo"
+)
+
+// Penyimpanan file lampiran yang didukung oleh ATTACHMENT_STORE
+const (
+	BlobStoreLocal  = "local"
+	BlobStoreGridFS = "gridfs"
+)
+
+// Config adalah seluruh konfigurasi aplikasi. Nilai diambil berurutan dari default,
+// file konfigurasi opsional (CONFIG_FILE, YAML atau TOML), lalu environment / .env.
+type Config struct {
+	Port      string      `yaml:"port" toml:"port"`
+	JWTSecret string      `yaml:"jwt_secret" toml:"jwt_secret"`
+	Proxy     ProxyConfig `yaml:"proxy" toml:"proxy"`
+	// BodyLimit adalah ukuran body request maksimum dalam byte. Upload lampiran dan impor
+	// memakai batas dari Attachments.MaxSize dan Import.MaxSize.
+	BodyLimit int `yaml:"body_limit" toml:"body_limit"`
+
+	Mongo     MongoConfig     `yaml:"mongo" toml:"mongo"`
+	Analyzer  AnalyzerConfig  `yaml:"analyzer" toml:"analyzer"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Trash     TrashConfig     `yaml:"trash" toml:"trash"`
+	Revisions RevisionConfig  `yaml:"revisions" toml:"revisions"`
+
+	Attachments AttachmentConfig `yaml:"attachments" toml:"attachments"`
+	AtRest      AtRestConfig     `yaml:"at_rest" toml:"at_rest"`
+	Import      ImportConfig     `yaml:"import" toml:"import"`
+}
+
+// ProxyConfig mengatur cara membaca IP client saat aplikasi berjalan di belakang reverse proxy
+// (mis. Railway). Tanpa ini c.IP() adalah alamat proxy, sehingga rate limit per IP dan IP di
+// sesi login berlaku untuk semua client sekaligus.
+type ProxyConfig struct {
+	// Header berisi IP client, mis. X-Real-IP. Pakai header yang ditimpa proxy, bukan yang
+	// hanya ditambah, karena nilai pertama X-Forwarded-For bisa diisi client sendiri.
+	Header string `yaml:"header" toml:"header"`
+	// TrustedProxies berisi IP atau CIDR proxy. Header dari alamat lain diabaikan.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+type MongoConfig struct {
+	URI      string `yaml:"uri" toml:"uri"`
+	Database string `yaml:"database" toml:"database"`
+}
+
+// AnalyzerConfig mengatur backend analisis emosi dan worker asinkronnya
+type AnalyzerConfig struct {
+	Backend      string `yaml:"backend" toml:"backend"`
+	GeminiAPIKey string `yaml:"gemini_api_key" toml:"gemini_api_key"`
+	Workers      int    `yaml:"workers" toml:"workers"`
+	MaxAttempts  int    `yaml:"max_attempts" toml:"max_attempts"`
+}
+
+// AuthConfig mengatur masa berlaku token dan kebijakan akun
+type AuthConfig struct {
+	// Access token pendek, refresh token dirotasi setiap dipakai
+	AccessTokenTTL       time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL      time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	EmailVerificationTTL time.Duration `yaml:"email_verification_ttl" toml:"email_verification_ttl"`
+	UnverifiedPolicy     string        `yaml:"unverified_policy" toml:"unverified_policy"`
+	// Halaman frontend yang menampilkan form password baru; token ditambahkan sebagai ?token=
+	PasswordResetURL string        `yaml:"password_reset_url" toml:"password_reset_url"`
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+}
+
+type MailConfig struct {
+	Backend    string     `yaml:"backend" toml:"backend"`
+	AppBaseURL string     `yaml:"app_base_url" toml:"app_base_url"`
+	From       string     `yaml:"from" toml:"from"`
+	SinkFile   string     `yaml:"sink_file" toml:"sink_file"`
+	SMTP       SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// RateLimitConfig mengatur batas per IP untuk /api/auth, per akun untuk route
+// yang butuh login, dan penguncian login setelah gagal berulang kali
+type RateLimitConfig struct {
+	Store            string        `yaml:"store" toml:"store"`
+	AuthMax          int           `yaml:"auth_max" toml:"auth_max"`
+	AuthWindow       time.Duration `yaml:"auth_window" toml:"auth_window"`
+	APIMax           int           `yaml:"api_max" toml:"api_max"`
+	APIWindow        time.Duration `yaml:"api_window" toml:"api_window"`
+	LockoutThreshold int           `yaml:"lockout_threshold" toml:"lockout_threshold"`
+	LockoutBase      time.Duration `yaml:"lockout_base" toml:"lockout_base"`
+	LockoutMax       time.Duration `yaml:"lockout_max" toml:"lockout_max"`
+}
+
+// TrashConfig mengatur berapa lama entri diary yang dihapus disimpan di tempat sampah
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" toml:"retention"`
+	PurgeInterval time.Duration `yaml:"purge_interval" toml:"purge_interval"`
+}
+
+// RevisionConfig mengatur riwayat versi entri diary
+type RevisionConfig struct {
+	// MaxPerEntry adalah jumlah revisi terbaru yang disimpan per entri
+	MaxPerEntry int `yaml:"max_per_entry" toml:"max_per_entry"`
+}
+
+// AttachmentConfig mengatur penyimpanan dan batasan lampiran foto/file pada entri diary
+type AttachmentConfig struct {
+	Store string `yaml:"store" toml:"store"`
+	// Dir adalah folder penyimpanan untuk store "local"
+	Dir         string `yaml:"dir" toml:"dir"`
+	MaxSize     int    `yaml:"max_size" toml:"max_size"` // byte per file
+	MaxPerEntry int    `yaml:"max_per_entry" toml:"max_per_entry"`
+	// AllowedTypes dicocokkan dengan tipe hasil deteksi isi file, bukan header dari client
+	AllowedTypes []string `yaml:"allowed_types" toml:"allowed_types"`
+	// ThumbnailSize adalah sisi terpanjang thumbnail gambar dalam pixel
+	ThumbnailSize int `yaml:"thumbnail_size" toml:"thumbnail_size"`
+}
+
+// AtRestConfig mengatur enkripsi judul dan isi diary di database memakai data key per user
+// yang dibungkus master key. Kosongkan MasterKeyID untuk menyimpan plaintext (mis. development).
+type AtRestConfig struct {
+	// MasterKeyID memilih master key untuk membungkus data key baru
+	MasterKeyID string `yaml:"master_key_id" toml:"master_key_id"`
+	// MasterKeys berisi "id:base64" dari kunci 32 byte. Kunci lama tetap dicantumkan sampai
+	// semua data key dibungkus ulang dengan perintah reencrypt.
+	MasterKeys []string `yaml:"master_keys" toml:"master_keys"`
+}
+
+// Enabled melaporkan apakah enkripsi at rest aktif
+func (c AtRestConfig) Enabled() bool {
+	return c.MasterKeyID != ""
+}
+
+// Keyring mengurai MasterKeys menjadi map id ke kunci
+func (c AtRestConfig) Keyring() (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, item := range c.MasterKeys {
+		id, encoded, ok := strings.Cut(item, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, errors.New("ENCRYPTION_MASTER_KEYS entries must look like id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("ENCRYPTION_MASTER_KEYS key %q must be 32 bytes encoded as base64", id)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("ENCRYPTION_MASTER_KEYS has duplicate key id %q", id)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// ImportConfig membatasi file yang diunggah ke endpoint impor diary
+type ImportConfig struct {
+	MaxSize    int `yaml:"max_size" toml:"max_size"` // ukuran file dalam byte
+	MaxEntries int `yaml:"max_entries" toml:"max_entries"`
+}
+
+// Default mengembalikan konfigurasi bawaan sebelum file dan env diterapkan
+func Default() *Config {
+	return &Config{
+		Port:      "8080",
+		BodyLimit: 1 << 20,
+		Analyzer: AnalyzerConfig{
+			Backend:     AnalyzerGemini,
+			Workers:     4,
+			MaxAttempts: 5,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:       15 * time.Minute,
+			RefreshTokenTTL:      30 * 24 * time.Hour,
+			EmailVerificationTTL: 24 * time.Hour,
+			UnverifiedPolicy:     UnverifiedReadOnly,
+			PasswordResetTTL:     time.Hour,
+		},
+		Mail: MailConfig{
+			Backend:    MailerLog,
+			AppBaseURL: "http://localhost:8080",
+			From:       "no-reply@web-diary.local",
+			SMTP:       SMTPConfig{Port: "587"},
+		},
+		RateLimit: RateLimitConfig{
+			Store:            RateLimitStoreMemory,
+			AuthMax:          20,
+			AuthWindow:       time.Minute,
+			APIMax:           120,
+			APIWindow:        time.Minute,
+			LockoutThreshold: 5,
+			LockoutBase:      time.Minute,
+			LockoutMax:       time.Hour,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Revisions: RevisionConfig{
+			MaxPerEntry: 50,
+		},
+		Attachments: AttachmentConfig{
+			Store:       BlobStoreLocal,
+			Dir:         "uploads",
+			MaxSize:     10 << 20,
+			MaxPerEntry: 10,
+			AllowedTypes: []string{
+				"image/jpeg", "image/png", "image/gif", "image/webp",
+				"application/pdf", "text/plain",
+			},
+			ThumbnailSize: 320,
+		},
+		Import: ImportConfig{
+			MaxSize:    50 << 20,
+			MaxEntries: 10000,
+		},
+	}
+}
+
+// Load membaca konfigurasi dari default, CONFIG_FILE (jika ada) dan environment,
+// lalu memvalidasinya. Semua masalah dilaporkan sekaligus dalam satu error.
+func Load() (*Config, error) {
 	// Aman untuk local, aman untuk Railway
 	_ = godotenv.Load()
 
-	GeminiFlashAPIKey = os.Getenv("GEMINI_FLASH_API_KEY")
-	if GeminiFlashAPIKey == "" {
-		log.Fatal("GEMINI_FLASH_API_KEY not set")
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Auth.PasswordResetURL == "" {
+		cfg.Auth.PasswordResetURL = strings.TrimSuffix(cfg.Mail.AppBaseURL, "/") + "/reset-password"
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
+	return cfg, nil
 }
 
+// loadFile menimpa cfg dengan isi file YAML (.yaml/.yml) atau TOML (.toml)
+func loadFile(path string, cfg *Config) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
 
-func ConnectDB() {
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		log.Fatal("MONGO_URI not set in .env")
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(raw), cfg)
+		if err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported extension (use .yaml, .yml or .toml)", path)
 	}
+	return nil
+}
+
+// applyEnv menimpa cfg dengan environment variable yang diisi
+func applyEnv(cfg *Config) error {
+	env := &envReader{}
+
+	env.str("PORT", &cfg.Port)
+	env.str("JWT_SECRET", &cfg.JWTSecret)
+	env.str("PROXY_HEADER", &cfg.Proxy.Header)
+	env.list("TRUSTED_PROXIES", &cfg.Proxy.TrustedProxies)
+	env.int("BODY_LIMIT", &cfg.BodyLimit)
+
+	env.str("MONGO_URI", &cfg.Mongo.URI)
+	env.str("MONGO_DB_NAME", &cfg.Mongo.Database)
+
+	env.str("EMOTION_ANALYZER", &cfg.Analyzer.Backend)
+	env.str("GEMINI_FLASH_API_KEY", &cfg.Analyzer.GeminiAPIKey)
+	env.int("ANALYSIS_WORKERS", &cfg.Analyzer.Workers)
+	env.int("ANALYSIS_MAX_ATTEMPTS", &cfg.Analyzer.MaxAttempts)
+
+	env.duration("ACCESS_TOKEN_TTL", &cfg.Auth.AccessTokenTTL)
+	env.duration("REFRESH_TOKEN_TTL", &cfg.Auth.RefreshTokenTTL)
+	env.duration("EMAIL_VERIFICATION_TTL", &cfg.Auth.EmailVerificationTTL)
+	env.str("UNVERIFIED_POLICY", &cfg.Auth.UnverifiedPolicy)
+	env.str("PASSWORD_RESET_URL", &cfg.Auth.PasswordResetURL)
+	env.duration("PASSWORD_RESET_TTL", &cfg.Auth.PasswordResetTTL)
+
+	env.str("MAILER", &cfg.Mail.Backend)
+	env.str("APP_BASE_URL", &cfg.Mail.AppBaseURL)
+	env.str("MAIL_FROM", &cfg.Mail.From)
+	env.str("MAIL_SINK_FILE", &cfg.Mail.SinkFile)
+	env.str("SMTP_HOST", &cfg.Mail.SMTP.Host)
+	env.str("SMTP_PORT", &cfg.Mail.SMTP.Port)
+	env.str("SMTP_USERNAME", &cfg.Mail.SMTP.Username)
+	env.str("SMTP_PASSWORD", &cfg.Mail.SMTP.Password)
+
+	env.str("RATE_LIMIT_STORE", &cfg.RateLimit.Store)
+	env.int("RATE_LIMIT_AUTH_MAX", &cfg.RateLimit.AuthMax)
+	env.duration("RATE_LIMIT_AUTH_WINDOW", &cfg.RateLimit.AuthWindow)
+	env.int("RATE_LIMIT_API_MAX", &cfg.RateLimit.APIMax)
+	env.duration("RATE_LIMIT_API_WINDOW", &cfg.RateLimit.APIWindow)
+	env.int("LOGIN_LOCKOUT_THRESHOLD", &cfg.RateLimit.LockoutThreshold)
+	env.duration("LOGIN_LOCKOUT_BASE", &cfg.RateLimit.LockoutBase)
+	env.duration("LOGIN_LOCKOUT_MAX", &cfg.RateLimit.LockoutMax)
+
+	env.duration("TRASH_RETENTION", &cfg.Trash.Retention)
+	env.duration("TRASH_PURGE_INTERVAL", &cfg.Trash.PurgeInterval)
+
+	env.int("DIARY_MAX_REVISIONS", &cfg.Revisions.MaxPerEntry)
 
-	dbName := os.Getenv("MONGO_DB_NAME")
-	if dbName == "" {
-		log.Fatal("MONGO_DB_NAME not set in .env")
+	env.str("ATTACHMENT_STORE", &cfg.Attachments.Store)
+	env.str("ATTACHMENT_DIR", &cfg.Attachments.Dir)
+	env.int("ATTACHMENT_MAX_SIZE", &cfg.Attachments.MaxSize)
+	env.int("ATTACHMENT_MAX_PER_ENTRY", &cfg.Attachments.MaxPerEntry)
+	env.list("ATTACHMENT_ALLOWED_TYPES", &cfg.Attachments.AllowedTypes)
+	env.int("ATTACHMENT_THUMBNAIL_SIZE", &cfg.Attachments.ThumbnailSize)
+
+	env.str("ENCRYPTION_MASTER_KEY_ID", &cfg.AtRest.MasterKeyID)
+	env.list("ENCRYPTION_MASTER_KEYS", &cfg.AtRest.MasterKeys)
+
+	env.int("IMPORT_MAX_SIZE", &cfg.Import.MaxSize)
+	env.int("IMPORT_MAX_ENTRIES", &cfg.Import.MaxEntries)
+
+	return errors.Join(env.errs...)
+}
+
+// Validate memeriksa nilai wajib dan pilihan yang didukung
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s %q not supported (use one of %s)", key, value, strings.Join(allowed, ", ")))
 	}
 
-	clientOptions := options.Client().ApplyURI(mongoURI)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if _, err := strconv.Atoi(c.Port); err != nil {
+		errs = append(errs, fmt.Errorf("PORT must be a number, got %q", c.Port))
+	}
+	check(c.JWTSecret != "", "JWT_SECRET not set")
+	if c.Proxy.Header != "" {
+		check(len(c.Proxy.TrustedProxies) > 0, "TRUSTED_PROXIES must be set when PROXY_HEADER is set")
+	}
+	for _, proxy := range c.Proxy.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES entry %q is not an IP or CIDR", proxy)
+	}
+	check(c.BodyLimit > 0, "BODY_LIMIT must be positive")
+	check(c.Mongo.URI != "", "MONGO_URI not set")
+	check(c.Mongo.Database != "", "MONGO_DB_NAME not set")
 
-	client, err := mongo.Connect(ctx, clientOptions)
-	if err != nil {
-		log.Fatal(err)
+	oneOf("EMOTION_ANALYZER", c.Analyzer.Backend, AnalyzerGemini, AnalyzerLexicon)
+	if c.Analyzer.Backend == AnalyzerGemini {
+		check(c.Analyzer.GeminiAPIKey != "", "GEMINI_FLASH_API_KEY not set")
 	}
+	check(c.Analyzer.Workers > 0, "ANALYSIS_WORKERS must be positive")
+	check(c.Analyzer.MaxAttempts > 0, "ANALYSIS_MAX_ATTEMPTS must be positive")
 
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		log.Fatal(err)
+	check(c.Auth.AccessTokenTTL > 0, "ACCESS_TOKEN_TTL must be positive")
+	check(c.Auth.RefreshTokenTTL > 0, "REFRESH_TOKEN_TTL must be positive")
+	check(c.Auth.EmailVerificationTTL > 0, "EMAIL_VERIFICATION_TTL must be positive")
+	check(c.Auth.PasswordResetTTL > 0, "PASSWORD_RESET_TTL must be positive")
+	oneOf("UNVERIFIED_POLICY", c.Auth.UnverifiedPolicy, UnverifiedAllow, UnverifiedReadOnly, UnverifiedBlock)
+
+	oneOf("MAILER", c.Mail.Backend, MailerLog, MailerSMTP)
+	if c.Mail.Backend == MailerSMTP {
+		check(c.Mail.SMTP.Host != "", "SMTP_HOST not set")
+		check(c.Mail.SMTP.Port != "", "SMTP_PORT not set")
 	}
+	check(c.Mail.From != "", "MAIL_FROM not set")
 
-	log.Println("✅ Connected to MongoDB!")
-	Client = client
-	Database = client.Database(dbName)
+	oneOf("RATE_LIMIT_STORE", c.RateLimit.Store, RateLimitStoreMemory, RateLimitStoreMongo)
+	check(c.RateLimit.AuthMax > 0 && c.RateLimit.AuthWindow > 0, "RATE_LIMIT_AUTH_MAX and RATE_LIMIT_AUTH_WINDOW must be positive")
+	check(c.RateLimit.APIMax > 0 && c.RateLimit.APIWindow > 0, "RATE_LIMIT_API_MAX and RATE_LIMIT_API_WINDOW must be positive")
+	check(c.RateLimit.LockoutThreshold > 0, "LOGIN_LOCKOUT_THRESHOLD must be positive")
+	check(c.RateLimit.LockoutBase > 0 && c.RateLimit.LockoutMax >= c.RateLimit.LockoutBase,
+		"LOGIN_LOCKOUT_BASE must be positive and not larger than LOGIN_LOCKOUT_MAX")
 
-	DiaryCollection = Database.Collection("diary_entries")
-	UserCollection = Database.Collection("users")
+	check(c.Trash.Retention > 0, "TRASH_RETENTION must be positive")
+	check(c.Trash.PurgeInterval > 0, "TRASH_PURGE_INTERVAL must be positive")
+	check(c.Revisions.MaxPerEntry > 0, "DIARY_MAX_REVISIONS must be positive")
+
+	oneOf("ATTACHMENT_STORE", c.Attachments.Store, BlobStoreLocal, BlobStoreGridFS)
+	if c.Attachments.Store == BlobStoreLocal {
+		check(c.Attachments.Dir != "", "ATTACHMENT_DIR not set")
+	}
+	check(c.Attachments.MaxSize > 0, "ATTACHMENT_MAX_SIZE must be positive")
+	check(c.Attachments.MaxPerEntry > 0, "ATTACHMENT_MAX_PER_ENTRY must be positive")
+	check(len(c.Attachments.AllowedTypes) > 0, "ATTACHMENT_ALLOWED_TYPES cannot be empty")
+	check(c.Attachments.ThumbnailSize > 0, "ATTACHMENT_THUMBNAIL_SIZE must be positive")
+
+	check(c.Import.MaxSize > 0, "IMPORT_MAX_SIZE must be positive")
+	check(c.Import.MaxEntries > 0, "IMPORT_MAX_ENTRIES must be positive")
+
+	if keys, err := c.AtRest.Keyring(); err != nil {
+		errs = append(errs, err)
+	} else if c.AtRest.Enabled() {
+		_, ok := keys[c.AtRest.MasterKeyID]
+		check(ok, "ENCRYPTION_MASTER_KEY_ID %q not found in ENCRYPTION_MASTER_KEYS", c.AtRest.MasterKeyID)
+	} else {
+		check(len(keys) == 0, "ENCRYPTION_MASTER_KEY_ID not set but ENCRYPTION_MASTER_KEYS is")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
 }
 
-func DisconnectDB() {
-	if Client == nil {
+// envReader menerapkan env ke field config dan mengumpulkan error parsing
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) str(key string, dst *string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = v
+	}
+}
+
+func (r *envReader) int(key string, dst *int) {
+	raw := os.Getenv(key)
+	if raw == "" {
 		return
 	}
-	err := Client.Disconnect(context.Background())
+	n, err := strconv.Atoi(raw)
 	if err != nil {
-		log.Fatal(err)
+		r.errs = append(r.errs, fmt.Errorf("%s must be an integer, got %q", key, raw))
+		return
+	}
+	*dst = n
+}
+
+// list menerima daftar dipisah koma (mis. "image/png,image/jpeg")
+func (r *envReader) list(key string, dst *[]string) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// duration menerima format durasi Go (mis. "15m", "720h")
+func (r *envReader) duration(key string, dst *time.Duration) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a duration like 15m or 720h, got %q", key, raw))
+		return
 	}
-	log.Println("🔌 Disconnected from MongoDB.")
+	*dst = d
 }
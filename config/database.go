@@ -0,0 +1,299 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DB menyimpan koneksi MongoDB dan koleksi yang dipakai aplikasi
+type DB struct {
+	Client    *mongo.Client
+	Database  *mongo.Database
+	Diaries   *mongo.Collection
+	Revisions *mongo.Collection
+	// Attachments menyimpan metadata lampiran; isi file ada di blob store
+	Attachments *mongo.Collection
+	Users       *mongo.Collection
+	Jobs        *mongo.Collection
+	Sessions    *mongo.Collection
+	Resets      *mongo.Collection
+	RateLimits  *mongo.Collection
+	// DataKeys menyimpan data key enkripsi at rest per user, terbungkus master key
+	DataKeys *mongo.Collection
+	// Feedback menyimpan koreksi user atas hasil analisis emosi
+	Feedback *mongo.Collection
+}
+
+// ConnectDB membuka koneksi ke MongoDB, memastikan index tersedia dan menjalankan migrasi ringan.
+// textSearch false (enkripsi at rest aktif) berarti text index diary tidak dibuat dan dihapus jika ada.
+func ConnectDB(cfg MongoConfig, textSearch bool) (*DB, error) {
+	clientOptions := options.Client().ApplyURI(cfg.URI)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("✅ Connected to MongoDB!")
+	database := client.Database(cfg.Database)
+	db := &DB{
+		Client:      client,
+		Database:    database,
+		Diaries:     database.Collection("diary_entries"),
+		Revisions:   database.Collection("diary_revisions"),
+		Attachments: database.Collection("attachments"),
+		DataKeys:    database.Collection("data_keys"),
+		Users:       database.Collection("users"),
+		Jobs:        database.Collection("analysis_jobs"),
+		Feedback:    database.Collection("analysis_feedback"),
+		Sessions:    database.Collection("sessions"),
+		Resets:      database.Collection("password_resets"),
+		RateLimits:  database.Collection("rate_limits"),
+	}
+
+	// Migrasi dulu: index unik email baru bisa dibuat setelah email lama dinormalisasi
+	if err := db.migrateUsers(ctx); err != nil {
+		return nil, err
+	}
+	if err := db.ensureIndexes(ctx, textSearch); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) Disconnect() {
+	if db == nil || db.Client == nil {
+		return
+	}
+	err := db.Client.Disconnect(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("🔌 Disconnected from MongoDB.")
+}
+
+// Nama text index untuk full-text search judul dan isi diary
+const diaryTextIndex = "diary_text"
+
+// ensureIndexes membuat index yang dibutuhkan aplikasi (idempotent)
+func (db *DB) ensureIndexes(ctx context.Context, textSearch bool) error {
+	diaryIndexes := []mongo.IndexModel{
+		// Listing diary per user diurutkan created_at desc, _id desc (cursor pagination)
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		// Filter listing berdasarkan tag dan hitungan pemakaian tag per user
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tags", Value: 1}},
+		},
+		// Purger tempat sampah mencari entri berdasarkan deleted_at
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+	if textSearch {
+		// Full-text search judul dan isi diary. Bahasa "none" agar teks campuran
+		// Indonesia/Inggris tidak di-stem atau dibuang stop word-nya secara keliru.
+		diaryIndexes = append(diaryIndexes, mongo.IndexModel{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
+			Options: options.Index().
+				SetName(diaryTextIndex).
+				SetWeights(bson.D{{Key: "title", Value: 3}, {Key: "content", Value: 1}}).
+				SetDefaultLanguage("none"),
+		})
+	} else if err := db.dropDiaryTextIndex(ctx); err != nil {
+		return err
+	}
+
+	indexes := []struct {
+		collection *mongo.Collection
+		models     []mongo.IndexModel
+	}{
+		{db.Diaries, diaryIndexes},
+		{db.Revisions, []mongo.IndexModel{
+			// Riwayat per entri diurutkan dari yang terbaru
+			{
+				Keys: bson.D{{Key: "entry_id", Value: 1}, {Key: "_id", Value: -1}},
+			},
+			{
+				Keys: bson.D{{Key: "user_id", Value: 1}},
+			},
+		}},
+		{db.Attachments, []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "entry_id", Value: 1}, {Key: "_id", Value: 1}},
+			},
+			{
+				Keys: bson.D{{Key: "user_id", Value: 1}},
+			},
+		}},
+		{db.DataKeys, []mongo.IndexModel{
+			// Satu versi data key per user; mencegah dua request membuat kunci pertama bersamaan
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "version", Value: -1}},
+				Options: options.Index().SetUnique(true),
+			},
+		}},
+		{db.Users, []mongo.IndexModel{
+			// Satu akun per email; email selalu disimpan dalam huruf kecil
+			{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		}},
+		{db.Jobs, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "entry_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_run_at", Value: 1}}},
+		}},
+		{db.Feedback, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		}},
+		{db.Sessions, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "refresh_token_hash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "previous_token_hashes", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			// Sesi dihapus otomatis oleh MongoDB setelah refresh token kedaluwarsa
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		}},
+		{db.Resets, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "token_hash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		}},
+		{db.RateLimits, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		}},
+	}
+
+	for _, idx := range indexes {
+		if _, err := idx.collection.Indexes().CreateMany(ctx, idx.models); err != nil {
+			return fmt.Errorf("create %s indexes: %w", idx.collection.Name(), err)
+		}
+	}
+	return nil
+}
+
+// dropDiaryTextIndex menghapus text index yang dibuat sebelum enkripsi at rest diaktifkan;
+// index itu berisi kata-kata dari judul/isi plaintext lama
+func (db *DB) dropDiaryTextIndex(ctx context.Context) error {
+	_, err := db.Diaries.Indexes().DropOne(ctx, diaryTextIndex)
+	var cmdErr mongo.CommandError
+	// 26 NamespaceNotFound (koleksi belum ada), 27 IndexNotFound
+	if errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("drop %s index: %w", diaryTextIndex, err)
+	}
+	log.Printf("Dropped %s index because encryption at rest is enabled", diaryTextIndex)
+	return nil
+}
+
+// migrateUsers menyiapkan akun lama untuk fitur yang datang belakangan:
+//   - email dinormalisasi ke huruf kecil tanpa spasi seperti akun baru, agar akun lama tetap bisa
+//     login; gagal jika ada akun yang emailnya hanya berbeda huruf besar/kecil
+//   - akun yang dibuat sebelum ada verifikasi email ditandai terverifikasi, agar mereka tidak
+//     tiba-tiba terkena UNVERIFIED_POLICY
+func (db *DB) migrateUsers(ctx context.Context) error {
+	if err := db.normalizeUserEmails(ctx); err != nil {
+		return fmt.Errorf("migrate users: %w", err)
+	}
+
+	res, err := db.Users.UpdateMany(
+		ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("migrate users: %w", err)
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("Marked %d existing users as email verified", res.ModifiedCount)
+	}
+	return nil
+}
+
+// normalizedEmail adalah ekspresi agregasi untuk email huruf kecil tanpa spasi di tepi,
+// sama dengan normalisasi di repository user
+var normalizedEmail = bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}
+
+// normalizeUserEmails menulis ulang email akun lama yang masih memakai huruf besar atau spasi.
+// Akun yang bentrok setelah dinormalisasi tidak digabung otomatis; semuanya dilaporkan agar
+// diselesaikan manual sebelum server dijalankan.
+func (db *DB) normalizeUserEmails(ctx context.Context) error {
+	cursor, err := db.Users.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"email": bson.M{"$type": "string"}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    normalizedEmail,
+			"count":  bson.M{"$sum": 1},
+			"emails": bson.M{"$push": "$email"},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var collisions []struct {
+		Emails []string `bson:"emails"`
+	}
+	if err := cursor.All(ctx, &collisions); err != nil {
+		return err
+	}
+	if len(collisions) > 0 {
+		lines := make([]string, 0, len(collisions))
+		for _, c := range collisions {
+			lines = append(lines, "  - "+strings.Join(c.Emails, ", "))
+		}
+		return fmt.Errorf(
+			"%d emails belong to more than one account when case is ignored; merge or rename these accounts first:\n%s",
+			len(collisions), strings.Join(lines, "\n"),
+		)
+	}
+
+	res, err := db.Users.UpdateMany(
+		ctx,
+		bson.M{"email": bson.M{"$type": "string"}, "$expr": bson.M{"$ne": bson.A{"$email", normalizedEmail}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"email": normalizedEmail}}}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("Normalized %d existing user emails to lowercase", res.ModifiedCount)
+	}
+	return nil
+}
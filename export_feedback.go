@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"web-diary-be/config"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// runExportFeedback menjalankan perintah "export-feedback [-out file]": semua koreksi user atas
+// emosi/sentimen hasil analyzer ditulis sebagai JSON Lines (ke stdout jika -out kosong) untuk
+// mengevaluasi akurasi analyzer
+func runExportFeedback(db *config.DB, args []string) error {
+	flags := flag.NewFlagSet("export-feedback", flag.ContinueOnError)
+	out := flags.String("out", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	n, err := services.ExportAnalysisFeedback(context.Background(), w, repositories.NewMongoAnalysisFeedbackRepository(db.Feedback))
+	log.Printf("Exported %d analysis feedback records", n)
+	return err
+}
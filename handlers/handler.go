@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"web-diary-be/config"
+	"web-diary-be/middleware"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// Handler menyimpan dependensi yang dipakai semua handler HTTP, dirakit di main
+type Handler struct {
+	Config      *config.Config
+	Diaries     repositories.DiaryRepository
+	Revisions   repositories.RevisionRepository
+	Attachments *services.AttachmentService
+	Exports     *services.AccountExporter
+	Users       repositories.UserRepository
+	Auth        *middleware.Auth
+	Sessions    *services.SessionService
+	Verifier    *services.EmailVerifier
+	Resets      *services.PasswordResetService
+	Analysis    *services.AnalysisQueue
+	Lockout     *services.LoginLockout // penguncian login setelah gagal berulang kali
+	Limits      services.CounterStore  // counter rate limit
+	// Feedback menyimpan koreksi user atas emosi/sentimen hasil analyzer
+	Feedback repositories.AnalysisFeedbackRepository
+}
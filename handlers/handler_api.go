@@ -2,30 +2,46 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"web-diary-be/config"
 	models "web-diary-be/models"
+	"web-diary-be/repositories"
 	"web-diary-be/services"
 )
 
-// CreateDiaryEntry membuat entri diary baru dengan analisis emosi
-func CreateDiaryEntry(c *fiber.Ctx) error {
-	entry := new(models.DiaryEntry)
-
-	if err := c.BodyParser(entry); err != nil {
+// CreateDiaryEntry membuat entri diary baru; analisis emosi dijalankan di background
+func (h *Handler) CreateDiaryEntry(c *fiber.Ctx) error {
+	// Hanya field yang boleh diisi client; id, waktu, status analisis dan deleted_at diisi server
+	var input struct {
+		Title         string   `json:"title"`
+		Content       string   `json:"content"`
+		ContentFormat string   `json:"content_format"`
+		Tags          []string `json:"tags"`
+		Encrypted     bool     `json:"encrypted"`
+		// Emotion dan Sentiment dari user tidak ditimpa analyzer; untuk entri terenkripsi
+		// keduanya adalah hasil analisis dari client
+		Emotion   string `json:"emotion"`
+		Sentiment string `json:"sentiment"`
+	}
+	if err := c.BodyParser(&input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "Invalid request body",
 			"error":   err.Error(),
 		})
 	}
+	entry := &models.DiaryEntry{
+		Title:         input.Title,
+		Content:       input.Content,
+		ContentFormat: input.ContentFormat,
+		Tags:          input.Tags,
+		Encrypted:     input.Encrypted,
+	}
 
 	if entry.Content == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -33,6 +49,18 @@ func CreateDiaryEntry(c *fiber.Ctx) error {
 		})
 	}
 
+	tags, err := normalizeTags(entry.Tags)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid tags",
+			"error":   err.Error(),
+		})
+	}
+	entry.Tags = tags
+	if len(entry.Tags) == 0 {
+		entry.Tags = nil
+	}
+
 	// Dapatkan user ID dari token
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
@@ -50,22 +78,61 @@ func CreateDiaryEntry(c *fiber.Ctx) error {
 	}
 	entry.UserID = userObjID
 
-	// Analisis emosi
-	emotion, sentiment, err := services.AnalyzeEmotion(entry.Content)
+	key, err := h.userEncryption(context.Background(), userObjID)
 	if err != nil {
-		log.Printf("Failed to analyze emotion: %v", err)
-		entry.Emotion = "Unknown"
-		entry.Sentiment = "Neutral"
+		log.Printf("Error fetching user encryption: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create diary entry",
+			"error":   err.Error(),
+		})
+	}
+	if err := checkEncryptedWrite(key, entry.Encrypted, false, &entry.Title, &entry.Content); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid diary entry",
+			"error":   err.Error(),
+		})
+	}
+
+	if entry.Encrypted {
+		// Isi terenkripsi tidak bisa disanitasi server; client yang merender sesuai formatnya
+		entry.ContentFormat, err = services.NormalizeContentFormat(entry.ContentFormat)
 	} else {
-		entry.Emotion = emotion
-		entry.Sentiment = sentiment
+		entry.ContentFormat, entry.Content, err = services.PrepareContent(entry.ContentFormat, entry.Content)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid content format",
+			"error":   err.Error(),
+		})
+	}
+	if entry.Content == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Diary content cannot be empty",
+		})
 	}
 
+	var analysis *repositories.DiaryAnalysis
+	if entry.Encrypted {
+		// Server tidak bisa membaca entri terenkripsi, emosi hanya bisa dikirim oleh client
+		analysis, err = clientAnalysis(nilIfEmpty(input.Emotion), nilIfEmpty(input.Sentiment))
+	} else {
+		// Emosi diisi oleh worker analisis, client bisa polling analysis_status.
+		// Emosi/sentimen yang dikirim di sini berasal dari user dan tidak ditimpa worker.
+		pending := &models.DiaryEntry{AnalysisStatus: models.AnalysisPending}
+		analysis, err = userAnalysis(pending, nilIfEmpty(input.Emotion), nilIfEmpty(input.Sentiment))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid emotion analysis",
+			"error":   err.Error(),
+		})
+	}
+	analysis.ApplyTo(entry)
+
 	entry.ID = primitive.NewObjectID()
 	entry.CreatedAt = time.Now()
 
-	_, err = config.DiaryCollection.InsertOne(context.Background(), entry)
-	if err != nil {
+	if err := h.Diaries.Create(context.Background(), entry); err != nil {
 		log.Printf("Error inserting diary entry: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to create diary entry",
@@ -73,10 +140,19 @@ func CreateDiaryEntry(c *fiber.Ctx) error {
 		})
 	}
 
+	if !entry.Encrypted {
+		if err := h.Analysis.Enqueue(context.Background(), entry.ID, entry.UserID); err != nil {
+			log.Printf("Failed to enqueue emotion analysis: %v", err)
+			h.markAnalysisFailed(entry)
+		}
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(entry)
 }
 
-func GetDiaryEntries(c *fiber.Ctx) error {
+// GetDiaryEntries mengembalikan entri diary user per halaman (cursor-based),
+// dengan filter opsional emotion, sentiment, from dan to
+func (h *Handler) GetDiaryEntries(c *fiber.Ctx) error {
 	// Ambil user_id dari JWT (disimpan oleh middleware di Locals)
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
@@ -95,52 +171,74 @@ func GetDiaryEntries(c *fiber.Ctx) error {
 		})
 	}
 
-	// // Log debug (opsional)
-	// log.Println("✅ user_id (string):", userID)
-	// log.Println("✅ user_id (ObjectID):", objID)
+	filter, err := diaryFilter(c, objID, time.UTC)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	// Persiapkan query dan sorting
-	filter := bson.M{"user_id": objID}
-	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return h.listDiaryPage(c, filter)
+}
 
-	// Query ke database
-	cursor, err := config.DiaryCollection.Find(context.Background(), filter, findOptions)
+// listDiaryPage mengirim satu halaman entri yang cocok dengan filter beserta cursor halaman berikutnya
+func (h *Handler) listDiaryPage(c *fiber.Ctx, filter repositories.DiaryFilter) error {
+	limit, err := parsePageSize(c)
 	if err != nil {
-		log.Printf("Error finding diary entries: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Total dihitung dari filter tanpa cursor agar konsisten di semua halaman
+	total, err := h.Diaries.Count(context.Background(), filter)
+	if err != nil {
+		log.Printf("Error counting diary entries: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to retrieve diary entries",
 			"error":   err.Error(),
 		})
 	}
-	defer cursor.Close(context.Background())
 
-	// Iterasi hasil
-	var entries []models.DiaryEntry
-	for cursor.Next(context.Background()) {
-		var entry models.DiaryEntry
-		if err := cursor.Decode(&entry); err != nil {
-			log.Printf("Error decoding diary entry: %v", err)
-			continue
+	var after *repositories.DiaryCursor
+	if token := c.Query("cursor"); token != "" {
+		after, err = decodeCursor(token)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
 		}
-		entries = append(entries, entry)
 	}
 
-	// Cek jika ada error di cursor
-	if err := cursor.Err(); err != nil {
-		log.Printf("Cursor error: %v", err)
+	// Ambil satu entri lebih untuk mengetahui apakah masih ada halaman berikutnya
+	entries, err := h.Diaries.List(context.Background(), filter, after, limit+1)
+	if err != nil {
+		log.Printf("Error finding diary entries: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Error while processing diary entries",
+			"message": "Failed to retrieve diary entries",
 			"error":   err.Error(),
 		})
 	}
 
-	// Kembalikan hasil
-	return c.Status(fiber.StatusOK).JSON(entries)
-}
+	var nextCursor *string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		token := encodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &token
+	}
 
+	// Kembalikan hasil dalam envelope
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":        entries,
+		"next_cursor": nextCursor,
+		"limit":       limit,
+		"total":       total,
+	})
+}
 
 // GetDiaryEntryByID mengambil satu entri diary berdasarkan ID
-func GetDiaryEntryByID(c *fiber.Ctx) error {
+func (h *Handler) GetDiaryEntryByID(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
 	if !ok {
@@ -149,6 +247,13 @@ func GetDiaryEntryByID(c *fiber.Ctx) error {
 		})
 	}
 
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id in token",
+		})
+	}
+
 	idParam := c.Params("id")
 	objID, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
@@ -157,11 +262,10 @@ func GetDiaryEntryByID(c *fiber.Ctx) error {
 		})
 	}
 
-	var entry models.DiaryEntry
-	filter := bson.M{"_id": objID, "user_id": userID}
-	err = config.DiaryCollection.FindOne(context.Background(), filter).Decode(&entry)
+	// Client bisa polling analysis_status entri miliknya lewat endpoint ini
+	entry, err := h.Diaries.FindByID(context.Background(), userObjID, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, repositories.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"message": "Diary entry not found or not authorized",
 			})
@@ -177,7 +281,7 @@ func GetDiaryEntryByID(c *fiber.Ctx) error {
 }
 
 // UpdateDiaryEntry memperbarui entri diary milik user yang terautentikasi
-func UpdateDiaryEntry(c *fiber.Ctx) error {
+func (h *Handler) UpdateDiaryEntry(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
 	if !ok {
@@ -197,68 +301,193 @@ func UpdateDiaryEntry(c *fiber.Ctx) error {
 
 	// Parse update payload (allow partial updates)
 	var payload struct {
-		Title   *string `json:"title"`
-		Content *string `json:"content"`
+		Title   *string   `json:"title"`
+		Content *string   `json:"content"`
+		Tags    *[]string `json:"tags"` // menggantikan seluruh tag entri
+		// ContentFormat mengubah format isi (plain, markdown, html); tanpa content, isi lama dipakai
+		ContentFormat *string `json:"content_format"`
+		// Encrypted mengubah mode entri; harus dikirim bersama judul dan isi dalam bentuk baru
+		Encrypted *bool `json:"encrypted"`
+		// Emotion dan Sentiment mengoreksi hasil analyzer (string kosong mengembalikannya ke
+		// analyzer); untuk entri terenkripsi keduanya adalah hasil analisis dari client
+		Emotion   *string `json:"emotion"`
+		Sentiment *string `json:"sentiment"`
 	}
 	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "detail": err.Error()})
 	}
 
 	// Ensure the entry exists and belongs to the user
-	var existing models.DiaryEntry
-	filter := bson.M{"_id": objID, "user_id": userObjID}
-	if err := config.DiaryCollection.FindOne(context.Background(), filter).Decode(&existing); err != nil {
-		if err == mongo.ErrNoDocuments {
+	existing, err := h.Diaries.FindByID(context.Background(), userObjID, objID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Diary entry not found or not authorized"})
 		}
 		log.Printf("Error fetching existing diary entry: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to fetch diary entry", "error": err.Error()})
 	}
 
-	updateDoc := bson.M{}
-	setFields := bson.M{}
-	if payload.Title != nil {
-		setFields["title"] = *payload.Title
+	if payload.Title == nil && payload.Content == nil && payload.ContentFormat == nil && payload.Tags == nil && payload.Encrypted == nil && payload.Emotion == nil && payload.Sentiment == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "No updatable fields provided"})
 	}
-	if payload.Content != nil {
-		if *payload.Content == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Diary content cannot be empty"})
+	if payload.Content != nil && *payload.Content == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Diary content cannot be empty"})
+	}
+
+	if payload.Tags != nil {
+		tags, err := normalizeTags(*payload.Tags)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid tags", "error": err.Error()})
 		}
-		setFields["content"] = *payload.Content
-		// jika content berubah, lakukan analisis emosi ulang
-		if *payload.Content != existing.Content {
-			emotion, sentiment, err := services.AnalyzeEmotion(*payload.Content)
-			if err != nil {
-				log.Printf("AnalyzeEmotion failed on update: %v", err)
-				setFields["emotion"] = "Unknown"
-				setFields["sentiment"] = "Neutral"
-			} else {
-				setFields["emotion"] = emotion
-				setFields["sentiment"] = sentiment
-			}
+		payload.Tags = &tags
+	}
+
+	key, err := h.userEncryption(context.Background(), userObjID)
+	if err != nil {
+		log.Printf("Error fetching user encryption: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update diary entry", "error": err.Error()})
+	}
+	encrypted := existing.Encrypted
+	if payload.Encrypted != nil {
+		encrypted = *payload.Encrypted
+	}
+	if encrypted != existing.Encrypted && (payload.Title == nil || payload.Content == nil) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Changing encryption requires both title and content"})
+	}
+	if payload.Title != nil || payload.Content != nil {
+		if err := checkEncryptedWrite(key, encrypted, payload.Encrypted != nil, payload.Title, payload.Content); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid diary entry", "error": err.Error()})
 		}
 	}
 
-	if len(setFields) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "No updatable fields provided"})
+	if payload.Content != nil || payload.ContentFormat != nil {
+		format, content := existing.ContentFormat, existing.Content
+		if payload.ContentFormat != nil {
+			format = *payload.ContentFormat
+		}
+		if payload.Content != nil {
+			content = *payload.Content
+		}
+		if encrypted {
+			format, err = services.NormalizeContentFormat(format)
+		} else {
+			// Isi lama ikut disanitasi ulang jika formatnya diubah menjadi html
+			format, content, err = services.PrepareContent(format, content)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid content format", "error": err.Error()})
+		}
+		if content == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Diary content cannot be empty"})
+		}
+		payload.ContentFormat = &format
+		if payload.Content != nil || !encrypted {
+			payload.Content = &content
+		}
 	}
 
-	setFields["updated_at"] = time.Now()
-	updateDoc["$set"] = setFields
+	var analysis *repositories.DiaryAnalysis
+	if payload.Emotion != nil || payload.Sentiment != nil {
+		if encrypted {
+			analysis, err = clientAnalysis(payload.Emotion, payload.Sentiment)
+		} else {
+			analysis, err = userAnalysis(existing, payload.Emotion, payload.Sentiment)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid emotion analysis", "error": err.Error()})
+		}
+	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var updated models.DiaryEntry
-	err = config.DiaryCollection.FindOneAndUpdate(context.Background(), filter, updateDoc, opts).Decode(&updated)
+	updated, err := h.applyDiaryUpdate(context.Background(), existing, repositories.DiaryUpdate{
+		Title:         payload.Title,
+		Content:       payload.Content,
+		ContentFormat: payload.ContentFormat,
+		Tags:          payload.Tags,
+		Encrypted:     payload.Encrypted,
+		Analysis:      analysis,
+	})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Diary entry not found or not authorized"})
+	}
 	if err != nil {
 		log.Printf("Error updating diary entry: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update diary entry", "error": err.Error()})
 	}
+	if analysis != nil && !encrypted {
+		h.recordFeedback(context.Background(), existing, analysis)
+	}
 
 	return c.Status(fiber.StatusOK).JSON(updated)
 }
 
-// DeleteDiaryEntry menghapus entri diary milik user yang terautentikasi
-func DeleteDiaryEntry(c *fiber.Ctx) error {
+// applyDiaryUpdate menerapkan perubahan judul/isi/format/tag, menyimpan versi sebelumnya sebagai
+// revisi jika judul, isi atau format berubah dan menjadwalkan analisis emosi ulang jika isi atau
+// format berubah (teks yang dianalisis diambil sesuai format).
+// Emosi/sentimen yang diisi user dipertahankan saat analisis ulang; melepas koreksi user juga
+// menjadwalkan analisis ulang. Entri terenkripsi tidak dianalisis server; hasilnya hanya datang
+// dari update.Analysis.
+func (h *Handler) applyDiaryUpdate(ctx context.Context, existing *models.DiaryEntry, update repositories.DiaryUpdate) (*models.DiaryEntry, error) {
+	encrypted := existing.Encrypted
+	if update.Encrypted != nil {
+		encrypted = *update.Encrypted
+	}
+	modeChanged := encrypted != existing.Encrypted
+	if !modeChanged {
+		update.Encrypted = nil
+	}
+
+	if update.ContentFormat != nil && *update.ContentFormat == contentFormat(existing.ContentFormat) {
+		update.ContentFormat = nil
+	}
+	contentChanged := update.Content != nil && *update.Content != existing.Content || update.ContentFormat != nil
+	changed := contentChanged || update.Title != nil && *update.Title != existing.Title
+
+	// jika content berubah, jadwalkan analisis emosi ulang
+	reanalyze := !encrypted && (contentChanged || releasesOverride(existing, update.Analysis))
+	if reanalyze {
+		base := update.Analysis
+		if base == nil {
+			base = currentAnalysis(existing)
+		}
+		update.Analysis = pendingAnalysis(base)
+	}
+	if encrypted && update.Analysis == nil && (contentChanged || modeChanged) {
+		update.Analysis = &repositories.DiaryAnalysis{Status: models.AnalysisSkipped}
+	}
+
+	now := time.Now()
+	update.UpdatedAt = now
+	updated, err := h.Diaries.Update(ctx, existing.UserID, existing.ID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if modeChanged {
+		// Riwayat dalam mode lama dibuang: versi plaintext tidak boleh tersisa setelah entri
+		// dienkripsi, dan versi terenkripsi tidak bisa dibuka lagi setelah enkripsi dimatikan
+		if _, err := h.Revisions.DeleteByEntries(ctx, []primitive.ObjectID{existing.ID}); err != nil {
+			log.Printf("Error deleting diary revisions after encryption change: %v", err)
+		}
+		if encrypted {
+			if err := h.Analysis.Cancel(ctx, existing.ID); err != nil {
+				log.Printf("Error deleting analysis job for encrypted entry: %v", err)
+			}
+		}
+	} else if changed {
+		h.recordRevision(ctx, existing, now)
+	}
+
+	if reanalyze {
+		if err := h.Analysis.Enqueue(ctx, updated.ID, updated.UserID); err != nil {
+			log.Printf("Failed to enqueue emotion analysis on update: %v", err)
+			h.markAnalysisFailed(updated)
+		}
+	}
+	return updated, nil
+}
+
+// DeleteDiaryEntry memindahkan entri diary milik user yang terautentikasi ke tempat sampah
+func (h *Handler) DeleteDiaryEntry(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
 	if !ok {
@@ -276,15 +505,95 @@ func DeleteDiaryEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid ID format"})
 	}
 
-	filter := bson.M{"_id": objID, "user_id": userObjID}
-	res, err := config.DiaryCollection.DeleteOne(context.Background(), filter)
+	// Entri hanya dipindah ke tempat sampah dan bisa dipulihkan sampai masa retensi habis
+	trashed, err := h.Diaries.Trash(context.Background(), userObjID, objID, time.Now())
 	if err != nil {
 		log.Printf("Error deleting diary entry: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to delete diary entry", "error": err.Error()})
 	}
-	if res.DeletedCount == 0 {
+	if !trashed {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Diary entry not found or not authorized"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Diary entry deleted"})
+	if err := h.Analysis.Cancel(context.Background(), objID); err != nil {
+		log.Printf("Error deleting analysis job for diary entry: %v", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Diary entry moved to trash"})
+}
+
+// markAnalysisFailed dipakai saat job analisis tidak bisa di-enqueue,
+// agar entri tidak tertahan di status pending selamanya
+func (h *Handler) markAnalysisFailed(entry *models.DiaryEntry) {
+	entry.Emotion = models.EmotionUnknown
+	entry.Sentiment = models.SentimentNeutral
+	entry.AnalysisStatus = models.AnalysisFailed
+
+	_, err := h.Diaries.SetAnalysis(context.Background(), entry.ID, repositories.DiaryAnalysis{
+		Emotion:         entry.Emotion,
+		EmotionSource:   models.SourceModel,
+		Sentiment:       entry.Sentiment,
+		SentimentSource: models.SourceModel,
+		Status:          entry.AnalysisStatus,
+	})
+	if err != nil {
+		log.Printf("Error marking diary entry analysis as failed: %v", err)
+	}
+}
+
+// checkEncryptedWrite memeriksa judul/isi yang ditulis sesuai mode enkripsi user dan entri.
+// Selama enkripsi aktif, teks plaintext hanya diterima jika client memintanya secara eksplisit
+// (explicit), yaitu saat mengembalikan entri ke plaintext.
+func checkEncryptedWrite(key *models.EncryptionKey, encrypted, explicit bool, title, content *string) error {
+	if !encrypted {
+		if key != nil && !explicit {
+			return errors.New("entries must be encrypted while encryption is enabled")
+		}
+		return nil
+	}
+	if key == nil {
+		return errors.New("encryption is not enabled for this account")
+	}
+	if title != nil {
+		if err := services.ValidateCiphertext(*title, true); err != nil {
+			return fmt.Errorf("invalid encrypted title: %w", err)
+		}
+	}
+	if content != nil {
+		if err := services.ValidateCiphertext(*content, false); err != nil {
+			return fmt.Errorf("invalid encrypted content: %w", err)
+		}
+	}
+	return nil
+}
+
+// clientAnalysis membuat hasil analisis dari emosi/sentimen yang dikirim client untuk entri
+// terenkripsi; tanpa keduanya entri ditandai skipped
+func clientAnalysis(emotion, sentiment *string) (*repositories.DiaryAnalysis, error) {
+	if emotion == nil && sentiment == nil {
+		return &repositories.DiaryAnalysis{Status: models.AnalysisSkipped}, nil
+	}
+	if emotion == nil || sentiment == nil {
+		return nil, errors.New("emotion and sentiment must be sent together")
+	}
+	e, s, err := services.NormalizeClientAnalysis(*emotion, *sentiment)
+	if err != nil {
+		return nil, err
+	}
+	return &repositories.DiaryAnalysis{
+		Emotion:         e,
+		EmotionSource:   models.SourceClient,
+		Sentiment:       s,
+		SentimentSource: models.SourceClient,
+		Status:          models.AnalysisDone,
+		AnalyzedAt:      time.Now(),
+	}, nil
+}
+
+func nilIfEmpty[T ~string](s T) *string {
+	if s == "" {
+		return nil
+	}
+	value := string(s)
+	return &value
 }
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// ownedAttachment mengambil lampiran :aid dari entri milik user yang terautentikasi.
+// Seperti ownedEntry, attachment bernilai nil jika response error sudah dikirim.
+func (h *Handler) ownedAttachment(c *fiber.Ctx) (*models.Attachment, error) {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("aid"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Attachment not found"})
+	}
+
+	attachment, err := h.Attachments.Find(context.Background(), entry.UserID, entry.ID, objID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Attachment not found"})
+		}
+		log.Printf("Error finding attachment: %v", err)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to retrieve attachment", "error": err.Error()})
+	}
+	return attachment, nil
+}
+
+// UploadAttachment menerima file multipart di field 'file' dan melampirkannya ke entri
+func (h *Handler) UploadAttachment(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Missing file field"})
+	}
+	if header.Size > int64(h.Attachments.MaxSize()) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"message": "File is too large", "max_size": h.Attachments.MaxSize()})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded file: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Failed to read file", "error": err.Error()})
+	}
+	defer file.Close()
+
+	attachment, err := h.Attachments.Upload(context.Background(), entry, header.Filename, file)
+	switch {
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"message": "File is too large", "max_size": h.Attachments.MaxSize()})
+	case errors.Is(err, services.ErrAttachmentType):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"message": "File type is not allowed", "error": err.Error()})
+	case errors.Is(err, services.ErrTooManyAttachments):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Too many attachments on this entry", "error": err.Error()})
+	case err != nil:
+		log.Printf("Error uploading attachment: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to upload attachment", "error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Attachment uploaded",
+		"data":    attachment,
+	})
+}
+
+// ListAttachments menampilkan lampiran sebuah entri sesuai urutan upload
+func (h *Handler) ListAttachments(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+
+	attachments, err := h.Attachments.List(context.Background(), entry.UserID, entry.ID)
+	if err != nil {
+		log.Printf("Error listing attachments: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to retrieve attachments", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"data": attachments})
+}
+
+// DownloadAttachment mengirim isi file lampiran
+func (h *Handler) DownloadAttachment(c *fiber.Ctx) error {
+	return h.sendAttachment(c, false)
+}
+
+// DownloadAttachmentThumbnail mengirim thumbnail JPEG dari lampiran gambar
+func (h *Handler) DownloadAttachmentThumbnail(c *fiber.Ctx) error {
+	return h.sendAttachment(c, true)
+}
+
+func (h *Handler) sendAttachment(c *fiber.Ctx, thumbnail bool) error {
+	attachment, err := h.ownedAttachment(c)
+	if attachment == nil {
+		return err
+	}
+
+	body, err := h.Attachments.Open(context.Background(), attachment, thumbnail)
+	if err != nil {
+		if errors.Is(err, services.ErrAttachmentNoPreview) || errors.Is(err, services.ErrBlobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Attachment file not found"})
+		}
+		log.Printf("Error opening attachment: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to read attachment", "error": err.Error()})
+	}
+
+	contentType := attachment.ContentType
+	if thumbnail {
+		contentType = "image/jpeg"
+	}
+	if contentType == "text/plain" {
+		contentType += "; charset=utf-8"
+	}
+	// Hanya gambar yang boleh ditampilkan langsung di browser, file lain selalu diunduh
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	if !thumbnail {
+		c.Set(fiber.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+		return c.SendStream(body, int(attachment.Size))
+	}
+	return c.SendStream(body)
+}
+
+// DeleteAttachment menghapus lampiran beserta file-nya
+func (h *Handler) DeleteAttachment(c *fiber.Ctx) error {
+	attachment, err := h.ownedAttachment(c)
+	if attachment == nil {
+		return err
+	}
+
+	if err := h.Attachments.Delete(context.Background(), attachment); err != nil {
+		log.Printf("Error deleting attachment: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to delete attachment", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "Attachment deleted"})
+}
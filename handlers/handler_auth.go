@@ -2,76 +2,301 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/mail"
+	"strings"
 	"time"
 	"web-diary-be/config"
 	"web-diary-be/middleware"
 	models "web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func Register(c *fiber.Ctx) error {
-    collection := config.UserCollection
+func (h *Handler) Register(c *fiber.Ctx) error {
+	var input struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	addr, err := mail.ParseAddress(strings.TrimSpace(input.Email))
+	if err != nil || addr.Name != "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid email address"})
+	}
+
+	user := models.User{
+		Username:  input.Username,
+		Email:     strings.ToLower(addr.Address),
+		CreatedAt: time.Now(),
+	}
+
+	_, err = h.Users.FindByEmail(context.TODO(), user.Email)
+	if err == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Email already exists"})
+	}
+	if !errors.Is(err, repositories.ErrNotFound) {
+		log.Printf("Error checking existing email: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Register failed"})
+	}
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(input.Password), 14)
+	user.Password = string(hashedPassword)
+
+	if err := h.Users.Create(context.TODO(), &user); err != nil {
+		// Registrasi bersamaan dengan email yang sama ditolak oleh index unik email
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return c.Status(400).JSON(fiber.Map{"error": "Email already exists"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Register failed"})
+	}
+
+	// Gagal kirim email tidak menggagalkan registrasi; user bisa minta kirim ulang
+	if err := h.Verifier.StartEmailVerification(context.TODO(), &user); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Registration successful, please check your email to verify your account"})
+}
+
+// VerifyEmail memverifikasi email memakai token dari email verifikasi.
+// Token bisa dikirim lewat query ?token= (tautan email) atau body JSON.
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		var input struct {
+			Token string `json:"token"`
+		}
+		_ = c.BodyParser(&input)
+		token = input.Token
+	}
+	if token == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "token is required"})
+	}
+
+	if err := h.Verifier.VerifyEmail(context.TODO(), token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("Error verifying email: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Email verification failed"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Email verified"})
+}
+
+// ResendVerification mengirim ulang email verifikasi. Respons selalu sama
+// agar endpoint ini tidak bisa dipakai untuk mengecek email terdaftar.
+func (h *Handler) ResendVerification(c *fiber.Ctx) error {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Email == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "email is required"})
+	}
+
+	user, err := h.Users.FindByEmail(context.TODO(), input.Email)
+	if err == nil {
+		if !user.EmailVerified {
+			if err := h.Verifier.StartEmailVerification(context.TODO(), user); err != nil {
+				log.Printf("Error resending verification email: %v", err)
+			}
+		}
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		log.Printf("Error finding user for verification: %v", err)
+	}
+
+	return c.JSON(fiber.Map{"message": "If the account exists and is not verified, a verification email has been sent"})
+}
+
+func (h *Handler) Login(c *fiber.Ctx) error {
+	var input models.User
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	// Email yang sedang terkunci ditolak sebelum bcrypt dijalankan
+	if wait, err := h.Lockout.Locked(context.TODO(), input.Email); err != nil {
+		log.Printf("Error checking login lockout: %v", err)
+	} else if wait > 0 {
+		return middleware.TooManyRequests(c, wait)
+	}
+
+	user, err := h.Users.FindByEmail(context.TODO(), input.Email)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			log.Printf("Error finding user on login: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Login failed"})
+		}
+		return h.loginFailed(c, input.Email, "Email not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		return h.loginFailed(c, input.Email, "Wrong password")
+	}
+
+	if err := h.Lockout.Succeed(context.TODO(), input.Email); err != nil {
+		log.Printf("Error resetting login lockout: %v", err)
+	}
+
+	if !user.EmailVerified && h.Config.Auth.UnverifiedPolicy == config.UnverifiedBlock {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Email not verified"})
+	}
 
-    var user models.User
-    if err := c.BodyParser(&user); err != nil {
-        return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
-    }
+	// Setiap login membuat sesi baru dengan refresh token sendiri
+	session, refreshToken, err := h.Sessions.CreateSession(context.TODO(), user.ID, c.Get("User-Agent"), c.IP())
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Token creation failed"})
+	}
 
-    var existing models.User
-    err := collection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(&existing)
-    if err == nil {
-        return c.Status(400).JSON(fiber.Map{"error": "Email already exists"})
-    }
+	return h.issueTokens(c, session, refreshToken)
+}
 
-    hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), 14)
-    user.Password = string(hashedPassword)
+// loginFailed mencatat kegagalan login untuk email tersebut (terdaftar atau tidak)
+// dan mengembalikan 429 jika email sekarang terkunci
+func (h *Handler) loginFailed(c *fiber.Ctx, email, message string) error {
+	lock, err := h.Lockout.Fail(context.TODO(), email)
+	if err != nil {
+		log.Printf("Error recording login failure: %v", err)
+	}
+	if lock > 0 {
+		return middleware.TooManyRequests(c, lock)
+	}
+	return c.Status(400).JSON(fiber.Map{"error": message})
+}
 
-    _, err = collection.InsertOne(context.TODO(), user)
-    if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Register failed"})
-    }
+// Refresh menukar refresh token dengan pasangan access/refresh token baru (rotasi)
+func (h *Handler) Refresh(c *fiber.Ctx) error {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "refresh_token is required"})
+	}
 
-    return c.JSON(fiber.Map{"message": "Registration successful"})
+	session, refreshToken, err := h.Sessions.RotateRefreshToken(context.TODO(), input.RefreshToken, c.Get("User-Agent"), c.IP())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("Error rotating refresh token: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Token refresh failed"})
+	}
+
+	return h.issueTokens(c, session, refreshToken)
 }
 
-func Login(c *fiber.Ctx) error {
-    collection := config.UserCollection
-
-    var input models.User
-    if err := c.BodyParser(&input); err != nil {
-        return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
-    }
-
-    var user models.User
-    err := collection.FindOne(context.TODO(), bson.M{"email": input.Email}).Decode(&user)
-    if err != nil {
-        return c.Status(400).JSON(fiber.Map{"error": "Email not found"})
-    }
-
-    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-        return c.Status(400).JSON(fiber.Map{"error": "Wrong password"})
-    }
-
-    // Gunakan GenerateJWT agar konsisten
-    t, err := middleware.GenerateJWT(user.ID.Hex())
-    if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Token creation failed"})
-    }
-    return c.JSON(fiber.Map{"token": t})
+// issueTokens mengirim access token baru untuk sesi beserta refresh token-nya
+func (h *Handler) issueTokens(c *fiber.Ctx, session *models.Session, refreshToken string) error {
+	t, err := h.Auth.GenerateJWT(session.UserID.Hex(), session.ID.Hex())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Token creation failed"})
+	}
+	return c.JSON(fiber.Map{
+		"token":              t, // dipertahankan untuk client lama
+		"access_token":       t,
+		"refresh_token":      refreshToken,
+		"token_type":         "Bearer",
+		"expires_in":         int(h.Config.Auth.AccessTokenTTL.Seconds()),
+		"refresh_expires_at": session.ExpiresAt,
+	})
 }
 
-func Logout(c *fiber.Ctx) error {
-    return c.JSON(fiber.Map{"message": "Logout success (client should delete token)"})
+// Logout mencabut sesi di server. Sesi ditentukan dari refresh_token di body,
+// atau dari access token di header Authorization jika body kosong.
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.BodyParser(&input)
+
+	var sessionID primitive.ObjectID
+	if input.RefreshToken != "" {
+		session, err := h.Sessions.FindSessionByRefreshToken(context.TODO(), input.RefreshToken)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidRefreshToken) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+			}
+			log.Printf("Error finding session on logout: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Logout failed"})
+		}
+		sessionID = session.ID
+	} else {
+		claims, err := h.Auth.ParseAccessToken(strings.TrimPrefix(c.Get("Authorization"), "Bearer "))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+		}
+		sessionID, err = primitive.ObjectIDFromHex(claims.SessionID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+		}
+	}
+
+	if err := h.Sessions.RevokeSession(context.TODO(), sessionID, "logout"); err != nil {
+		log.Printf("Error revoking session: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Logout failed"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Logout success"})
+}
+
+// ForgotPassword mengirim email reset password. Respons selalu sama dan pengiriman
+// dilakukan di background, sehingga tidak bocor apakah email terdaftar (termasuk dari waktu respons).
+func (h *Handler) ForgotPassword(c *fiber.Ctx) error {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Email == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "email is required"})
+	}
+
+	email, ip := input.Email, c.IP()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := h.Resets.RequestPasswordReset(ctx, email, ip); err != nil {
+			log.Printf("Error requesting password reset: %v", err)
+		}
+	}()
+
+	return c.JSON(fiber.Map{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// ResetPassword mengganti password memakai token dari email reset
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Token == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "token is required"})
+	}
+	if len(input.Password) < 6 {
+		return c.Status(400).JSON(fiber.Map{"error": "Password must be at least 6 characters"})
+	}
+
+	if err := h.Resets.ResetPassword(context.TODO(), input.Token, input.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("Error resetting password: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Password reset failed"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Password has been reset, please log in again"})
 }
 
 // Me mengembalikan profil user yang sedang login
-func Me(c *fiber.Ctx) error {
+func (h *Handler) Me(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
 	if !ok {
@@ -87,13 +312,9 @@ func Me(c *fiber.Ctx) error {
 		})
 	}
 
-	var user models.User
-	err = config.UserCollection.
-		FindOne(context.Background(), bson.M{"_id": objID}).
-		Decode(&user)
-
+	user, err := h.Users.FindByID(context.Background(), objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, repositories.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"message": "User not found",
 			})
@@ -107,19 +328,25 @@ func Me(c *fiber.Ctx) error {
 
 	// Response tanpa password
 	type UserResponse struct {
-		ID        primitive.ObjectID `json:"id"`
-		Username  string             `json:"username"`
-		Email     string             `json:"email"`
-		CreatedAt time.Time          `json:"created_at"`
-		UpdatedAt time.Time          `json:"updated_at,omitempty"`
+		ID            primitive.ObjectID `json:"id"`
+		Username      string             `json:"username"`
+		Email         string             `json:"email"`
+		EmailVerified bool               `json:"email_verified"`
+		CreatedAt     time.Time          `json:"created_at"`
+		UpdatedAt     time.Time          `json:"updated_at,omitempty"`
+		// Client memakai flag ini untuk meminta passphrase sebelum membuka diary
+		EncryptionEnabled bool `json:"encryption_enabled"`
 	}
 
 	resp := UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+
+		EncryptionEnabled: user.Encryption != nil,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
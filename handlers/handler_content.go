@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"web-diary-be/models"
+	"web-diary-be/services"
+)
+
+// contentFormat mengembalikan format isi yang tersimpan; entri lama tanpa format adalah plain
+func contentFormat(format string) string {
+	if format == "" {
+		return models.ContentPlain
+	}
+	return format
+}
+
+// RenderDiaryEntry mengembalikan isi entri sebagai HTML yang sudah disanitasi, siap ditampilkan
+// client tanpa perlu merender Markdown sendiri
+func (h *Handler) RenderDiaryEntry(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+	if entry.Encrypted {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Encrypted entries are rendered by the client"})
+	}
+
+	rendered, err := services.RenderContent(contentFormat(entry.ContentFormat), entry.Content)
+	if err != nil {
+		log.Printf("Error rendering diary entry: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to render diary entry", "error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id":             entry.ID,
+		"title":          entry.Title,
+		"content_format": contentFormat(entry.ContentFormat),
+		"html":           rendered,
+	})
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// currentUser mengambil akun user yang terautentikasi. Jika gagal, response error
+// sudah dikirim dan user bernilai nil.
+func (h *Handler) currentUser(c *fiber.Ctx) (*models.User, error) {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id format"})
+	}
+
+	user, err := h.Users.FindByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "user not found"})
+		}
+		log.Printf("Error fetching user: %v", err)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to fetch user", "error": err.Error()})
+	}
+	return user, nil
+}
+
+// userEncryption mengembalikan kunci enkripsi end-to-end user, nil jika mode enkripsi tidak aktif
+func (h *Handler) userEncryption(ctx context.Context, userID primitive.ObjectID) (*models.EncryptionKey, error) {
+	user, err := h.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.Encryption, nil
+}
+
+// parseEncryptionKey membaca dan memvalidasi metadata kunci dari body request
+func parseEncryptionKey(c *fiber.Ctx) (*models.EncryptionKey, error) {
+	var payload struct {
+		Cipher     string           `json:"cipher"`
+		KDF        models.KDFParams `json:"kdf"`
+		WrappedKey string           `json:"wrapped_key"`
+		Version    int              `json:"version"` // versi kunci saat ini, hanya untuk PUT
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return nil, err
+	}
+
+	key := &models.EncryptionKey{
+		Cipher:     payload.Cipher,
+		KDF:        payload.KDF,
+		WrappedKey: payload.WrappedKey,
+		Version:    payload.Version,
+	}
+	services.NormalizeEncryptionKey(key)
+	if err := services.ValidateEncryptionKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetEncryption mengembalikan metadata kunci agar client bisa membuka data key dengan passphrase
+func (h *Handler) GetEncryption(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"enabled": user.Encryption != nil,
+		"key":     user.Encryption,
+	})
+}
+
+// EnableEncryption mengaktifkan mode enkripsi end-to-end. Setelah aktif, entri baru harus
+// dikirim dalam bentuk ciphertext; entri lama dienkripsi client lewat PUT /api/diary/:id.
+func (h *Handler) EnableEncryption(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if user == nil {
+		return err
+	}
+	if user.Encryption != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "encryption is already enabled"})
+	}
+
+	key, err := parseEncryptionKey(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid encryption key", "error": err.Error()})
+	}
+
+	now := time.Now()
+	key.Version = 1
+	key.CreatedAt = now
+	key.UpdatedAt = now
+
+	ok, err := h.Users.SetEncryption(context.Background(), user.ID, key, 0)
+	if err != nil {
+		log.Printf("Error enabling encryption: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to enable encryption"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "encryption is already enabled"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"enabled": true, "key": key})
+}
+
+// RewrapEncryptionKey menyimpan data key yang dibungkus ulang, mis. setelah passphrase diganti.
+// Data key dan cipher tetap sama sehingga entri yang sudah ada tidak perlu dienkripsi ulang.
+func (h *Handler) RewrapEncryptionKey(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if user == nil {
+		return err
+	}
+	current := user.Encryption
+	if current == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "encryption is not enabled"})
+	}
+
+	key, err := parseEncryptionKey(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "invalid encryption key", "error": err.Error()})
+	}
+	if key.Cipher != current.Cipher {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "cipher cannot be changed while encryption is enabled"})
+	}
+	// Versi dari client mencegah dua perangkat saling menimpa kunci yang baru dibungkus
+	if key.Version != current.Version {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "encryption key has changed, reload it first", "version": current.Version})
+	}
+
+	key.Version = current.Version + 1
+	key.CreatedAt = current.CreatedAt
+	key.UpdatedAt = time.Now()
+
+	ok, err := h.Users.SetEncryption(context.Background(), user.ID, key, current.Version)
+	if err != nil {
+		log.Printf("Error rewrapping encryption key: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to update encryption key"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "encryption key has changed, reload it first"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"enabled": true, "key": key})
+}
+
+// DisableEncryption menonaktifkan mode enkripsi. Hanya boleh jika tidak ada lagi entri
+// terenkripsi (termasuk di tempat sampah), karena tanpa kunci entri tersebut tidak bisa dibuka.
+func (h *Handler) DisableEncryption(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if user == nil {
+		return err
+	}
+	if user.Encryption == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "encryption is not enabled"})
+	}
+
+	encrypted := true
+	var remaining int64
+	for _, trashed := range []bool{false, true} {
+		n, err := h.Diaries.Count(context.Background(), repositories.DiaryFilter{UserID: user.ID, Trashed: trashed, Encrypted: &encrypted})
+		if err != nil {
+			log.Printf("Error counting encrypted diary entries: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to disable encryption"})
+		}
+		remaining += n
+	}
+	if remaining > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message":           "decrypt or delete all encrypted entries before disabling encryption",
+			"encrypted_entries": remaining,
+		})
+	}
+
+	ok, err := h.Users.SetEncryption(context.Background(), user.ID, nil, user.Encryption.Version)
+	if err != nil {
+		log.Printf("Error disabling encryption: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "failed to disable encryption"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "encryption key has changed, reload it first"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"enabled": false})
+}
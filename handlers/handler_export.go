@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/repositories"
+)
+
+// ExportAccount mengunduh seluruh data akun: ?format=zip (default) berisi JSON, Markdown per
+// entri dan file lampiran, sedangkan ?format=json hanya satu dokumen JSON.
+// Arsip dikirim bertahap sehingga status 200 sudah terkirim sebelum semua entri dibaca;
+// kegagalan di tengah jalan hanya dicatat dan client menerima arsip yang terpotong.
+func (h *Handler) ExportAccount(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id format"})
+	}
+
+	format := c.Query("format", "zip")
+	if format != "zip" && format != "json" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "format must be zip or json"})
+	}
+
+	user, err := h.Users.FindByID(context.Background(), objID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User not found"})
+	}
+	if err != nil {
+		log.Printf("Error fetching user for export: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to export account", "error": err.Error()})
+	}
+
+	write := h.Exports.WriteZip
+	contentType := "application/zip"
+	if format == "json" {
+		write = h.Exports.WriteJSON
+		contentType = fiber.MIMEApplicationJSONCharsetUTF8
+	}
+	filename := "web-diary-export-" + time.Now().UTC().Format("2006-01-02") + "." + format
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := write(context.Background(), w, user); err != nil {
+			log.Printf("Error writing account export for %s: %v", user.ID.Hex(), err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("Error flushing account export for %s: %v", user.ID.Hex(), err)
+		}
+	})
+	return nil
+}
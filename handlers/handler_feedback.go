@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// currentAnalysis mengembalikan hasil analisis yang sedang tersimpan di entri
+func currentAnalysis(entry *models.DiaryEntry) *repositories.DiaryAnalysis {
+	a := &repositories.DiaryAnalysis{
+		Emotion:         entry.Emotion,
+		EmotionSource:   entry.EmotionSource,
+		Sentiment:       entry.Sentiment,
+		SentimentSource: entry.SentimentSource,
+		Status:          entry.AnalysisStatus,
+		Detail:          entry.AnalysisDetail,
+	}
+	if entry.AnalyzedAt != nil {
+		a.AnalyzedAt = *entry.AnalyzedAt
+	}
+	return a
+}
+
+// userAnalysis menerapkan emosi/sentimen yang diisi user di atas hasil analisis entri.
+// Nilai kosong melepas koreksi user sehingga field tersebut kembali diisi analyzer.
+func userAnalysis(entry *models.DiaryEntry, emotion, sentiment *string) (*repositories.DiaryAnalysis, error) {
+	a := currentAnalysis(entry)
+	if emotion != nil {
+		if *emotion == "" {
+			if a.EmotionSource == models.SourceUser {
+				a.Emotion, a.EmotionSource = "", ""
+			}
+		} else {
+			value, err := services.NormalizeEmotion(*emotion)
+			if err != nil {
+				return nil, err
+			}
+			a.Emotion, a.EmotionSource = value, models.SourceUser
+		}
+	}
+	if sentiment != nil {
+		if *sentiment == "" {
+			if a.SentimentSource == models.SourceUser {
+				a.Sentiment, a.SentimentSource = "", ""
+			}
+		} else {
+			value, err := services.NormalizeSentiment(*sentiment)
+			if err != nil {
+				return nil, err
+			}
+			a.Sentiment, a.SentimentSource = value, models.SourceUser
+		}
+	}
+	return a, nil
+}
+
+// releasesOverride melaporkan apakah update melepas koreksi user yang ada di entri
+func releasesOverride(entry *models.DiaryEntry, update *repositories.DiaryAnalysis) bool {
+	if update == nil {
+		return false
+	}
+	return entry.EmotionSource == models.SourceUser && update.EmotionSource != models.SourceUser ||
+		entry.SentimentSource == models.SourceUser && update.SentimentSource != models.SourceUser
+}
+
+// pendingAnalysis mengosongkan hasil analyzer untuk dianalisis ulang, tetapi mempertahankan
+// nilai yang diisi user
+func pendingAnalysis(base *repositories.DiaryAnalysis) *repositories.DiaryAnalysis {
+	a := &repositories.DiaryAnalysis{Status: models.AnalysisPending}
+	if base.EmotionSource == models.SourceUser {
+		a.Emotion, a.EmotionSource = base.Emotion, base.EmotionSource
+	}
+	if base.SentimentSource == models.SourceUser {
+		a.Sentiment, a.SentimentSource = base.Sentiment, base.SentimentSource
+	}
+	return a
+}
+
+// recordFeedback mencatat setiap field yang dikoreksi user dari nilai yang dihasilkan analyzer.
+// Kegagalan hanya dicatat di log agar koreksi tetap tersimpan.
+func (h *Handler) recordFeedback(ctx context.Context, previous *models.DiaryEntry, update *repositories.DiaryAnalysis) {
+	if previous.Encrypted || previous.AnalysisStatus != models.AnalysisDone {
+		return
+	}
+	now := time.Now()
+	for _, field := range []struct {
+		name, modelValue, modelSource, userValue, userSource string
+	}{
+		{"emotion", string(previous.Emotion), previous.EmotionSource, string(update.Emotion), update.EmotionSource},
+		{"sentiment", string(previous.Sentiment), previous.SentimentSource, string(update.Sentiment), update.SentimentSource},
+	} {
+		fromModel := field.modelSource == models.SourceModel || field.modelSource == ""
+		if !fromModel || field.modelValue == "" || field.userSource != models.SourceUser || strings.EqualFold(field.userValue, field.modelValue) {
+			continue
+		}
+		err := h.Feedback.Create(ctx, &models.AnalysisFeedback{
+			EntryID:    previous.ID,
+			UserID:     previous.UserID,
+			Field:      field.name,
+			ModelValue: field.modelValue,
+			UserValue:  field.userValue,
+			Analysis:   previous.AnalysisDetail,
+			CreatedAt:  now,
+		})
+		if err != nil {
+			log.Printf("Error saving analysis feedback: %v", err)
+		}
+	}
+}
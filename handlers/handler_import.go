@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// Status setiap entri di hasil impor
+const (
+	importNew       = "new"
+	importDuplicate = "duplicate"
+	importSkipped   = "skipped"
+)
+
+type importResult struct {
+	Source    string    `json:"source"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	ID        string    `json:"id,omitempty"` // kosong pada dry run
+}
+
+// ImportDiaryEntries mengimpor entri dari aplikasi jurnal lain lewat file multipart 'file'.
+// Query: format (auto, dayone, journey, markdown, web-diary), dry_run=true untuk hanya melihat
+// preview, dan analyze=true untuk menganalisis emosi semua entri yang diimpor di background.
+// Tanggal asli entri dipertahankan; entri dengan isi yang sama dengan entri yang sudah ada
+// (atau entri lain di file yang sama) dilewati sebagai duplikat.
+func (h *Handler) ImportDiaryEntries(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+	dryRun := c.QueryBool("dry_run")
+	analyze := c.QueryBool("analyze")
+
+	key, err := h.userEncryption(context.Background(), userObjID)
+	if err != nil {
+		log.Printf("Error fetching user encryption: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to import diary entries", "error": err.Error()})
+	}
+	if key != nil {
+		// Server tidak bisa mengenkripsi entri atas nama user
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Import is not available while end-to-end encryption is enabled; import on the client instead"})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Missing file field"})
+	}
+	if header.Size > int64(h.Config.Import.MaxSize) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"message": "Import file is too large", "max_size": h.Config.Import.MaxSize})
+	}
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("Error opening import file: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to read import file", "error": err.Error()})
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		log.Printf("Error reading import file: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to read import file", "error": err.Error()})
+	}
+
+	format, imported, err := services.ParseImport(data, header.Filename, c.Query("format", services.ImportAuto), h.Config.Import)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid import file", "error": err.Error()})
+	}
+
+	seen, err := h.contentHashes(context.Background(), userObjID)
+	if err != nil {
+		log.Printf("Error reading diary entries for import: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to import diary entries", "error": err.Error()})
+	}
+
+	results := make([]importResult, 0, len(imported))
+	counts := map[string]int{importNew: 0, importDuplicate: 0, importSkipped: 0}
+	for _, item := range imported {
+		result := importResult{Source: item.Source, Title: item.Title, CreatedAt: item.CreatedAt, Status: importNew}
+		var formatErr error
+		item.ContentFormat, item.Content, formatErr = services.PrepareContent(item.ContentFormat, item.Content)
+		hash := services.ContentHash(item.Content)
+		switch {
+		case item.Skip != "":
+			result.Status, result.Reason = importSkipped, item.Skip
+		case formatErr != nil:
+			result.Status, result.Reason = importSkipped, formatErr.Error()
+		case hash == services.ContentHash(""):
+			result.Status, result.Reason = importSkipped, "empty content"
+		case seen[hash]:
+			result.Status = importDuplicate
+		}
+
+		if result.Status == importNew {
+			seen[hash] = true
+			if !dryRun {
+				entry, err := h.createImported(context.Background(), userObjID, item, analyze)
+				if err != nil {
+					// Entri yang sudah tersimpan tetap ada; impor ulang akan melewatinya sebagai duplikat
+					log.Printf("Error importing diary entry: %v", err)
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+						"message":  "Failed to import diary entries",
+						"error":    err.Error(),
+						"imported": counts[importNew],
+					})
+				}
+				result.ID = entry.ID.Hex()
+			}
+		}
+		counts[result.Status]++
+		results = append(results, result)
+	}
+
+	status := fiber.StatusCreated
+	if dryRun {
+		status = fiber.StatusOK
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"format":     format,
+		"dry_run":    dryRun,
+		"total":      len(results),
+		"new":        counts[importNew],
+		"duplicates": counts[importDuplicate],
+		"skipped":    counts[importSkipped],
+		"entries":    results,
+	})
+}
+
+// contentHashes mengumpulkan hash isi semua entri user, termasuk yang ada di tempat sampah
+func (h *Handler) contentHashes(ctx context.Context, userID primitive.ObjectID) (map[string]bool, error) {
+	hashes := map[string]bool{}
+	for _, trashed := range []bool{false, true} {
+		filter := repositories.DiaryFilter{UserID: userID, Trashed: trashed}
+		var after *repositories.DiaryCursor
+		for {
+			entries, err := h.Diaries.List(ctx, filter, after, maxPageSize)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				hashes[services.ContentHash(entry.Content)] = true
+			}
+			if len(entries) < maxPageSize {
+				break
+			}
+			last := entries[len(entries)-1]
+			after = &repositories.DiaryCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+	return hashes, nil
+}
+
+// createImported menyimpan satu entri hasil impor. Tag yang tidak valid dibuang, dan emosi
+// dari ekspor web-diary dipakai kembali kecuali analyze diminta.
+func (h *Handler) createImported(ctx context.Context, userID primitive.ObjectID, item services.ImportedEntry, analyze bool) (*models.DiaryEntry, error) {
+	entry := &models.DiaryEntry{
+		ID:             primitive.NewObjectID(),
+		UserID:         userID,
+		Title:          item.Title,
+		Content:        item.Content,
+		ContentFormat:  item.ContentFormat,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		AnalysisStatus: models.AnalysisSkipped,
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	seen := map[string]bool{}
+	for _, raw := range item.Tags {
+		tag, err := normalizeTag(raw)
+		if err != nil || seen[tag] || len(entry.Tags) == maxTagsPerEntry {
+			continue
+		}
+		seen[tag] = true
+		entry.Tags = append(entry.Tags, tag)
+	}
+
+	switch {
+	case analyze:
+		entry.AnalysisStatus = models.AnalysisPending
+	case item.Emotion != "" && item.Sentiment != "":
+		// Hasil analisis dari ekspor web-diary berasal dari analyzer yang sama, jadi dipakai apa adanya
+		entry.Emotion, entry.Sentiment, entry.AnalysisStatus = item.Emotion, item.Sentiment, models.AnalysisDone
+		entry.EmotionSource, entry.SentimentSource = item.EmotionSource, item.SentimentSource
+		entry.AnalysisDetail, entry.AnalyzedAt = item.AnalysisDetail, item.AnalyzedAt
+	}
+
+	if err := h.Diaries.Create(ctx, entry); err != nil {
+		return nil, err
+	}
+	if analyze {
+		if err := h.Analysis.Enqueue(ctx, entry.ID, entry.UserID); err != nil {
+			log.Printf("Failed to enqueue emotion analysis for imported entry: %v", err)
+			h.markAnalysisFailed(entry)
+		}
+	}
+	return entry, nil
+}
@@ -2,21 +2,21 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 
-	"web-diary-be/config"
-	"web-diary-be/models"
+	"web-diary-be/repositories"
 )
 
 // UpdateMe memperbarui profil user yang sedang login
-func UpdateProfile(c *fiber.Ctx) error {
+func (h *Handler) UpdateProfile(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
 	if !ok {
@@ -45,17 +45,15 @@ func UpdateProfile(c *fiber.Ctx) error {
 		})
 	}
 
-	update := bson.M{}
+	update := repositories.UserUpdate{Username: payload.Username}
 
-	if payload.Username != nil {
-		if *payload.Username == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "username cannot be empty",
-			})
-		}
-		update["username"] = *payload.Username
+	if payload.Username != nil && *payload.Username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "username cannot be empty",
+		})
 	}
 
+	emailChanged := false
 	if payload.Email != nil {
 		if *payload.Email == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -63,19 +61,30 @@ func UpdateProfile(c *fiber.Ctx) error {
 			})
 		}
 
+		addr, err := mail.ParseAddress(strings.TrimSpace(*payload.Email))
+		if err != nil || addr.Name != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "invalid email address",
+			})
+		}
+		email := strings.ToLower(addr.Address)
+
 		// optional: cek email unik
-		var existing models.User
-		err := config.UserCollection.FindOne(
-			context.Background(),
-			bson.M{"email": *payload.Email, "_id": bson.M{"$ne": objID}},
-		).Decode(&existing)
-		if err == nil {
+		existing, err := h.Users.FindByEmail(context.Background(), email)
+		if err == nil && existing.ID != objID {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"message": "email already in use",
 			})
 		}
 
-		update["email"] = *payload.Email
+		// email baru harus diverifikasi ulang
+		if current, err := h.Users.FindByID(context.Background(), objID); err == nil && current.Email != email {
+			emailChanged = true
+			verified := false
+			update.EmailVerified = &verified
+		}
+
+		update.Email = &email
 	}
 
 	if payload.Password != nil {
@@ -92,29 +101,29 @@ func UpdateProfile(c *fiber.Ctx) error {
 			})
 		}
 
-		update["password"] = string(hashed)
+		password := string(hashed)
+		update.Password = &password
 	}
 
-	if len(update) == 0 {
+	if update.Username == nil && update.Email == nil && update.Password == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"message": "no updatable fields provided",
 		})
 	}
 
-	update["updated_at"] = time.Now()
-
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-
-	var user models.User
-	err = config.UserCollection.
-		FindOneAndUpdate(
-			context.Background(),
-			bson.M{"_id": objID},
-			bson.M{"$set": update},
-			opts,
-		).
-		Decode(&user)
+	update.UpdatedAt = time.Now()
 
+	user, err := h.Users.Update(context.Background(), objID, update)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "user not found",
+		})
+	}
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "email already in use",
+		})
+	}
 	if err != nil {
 		log.Printf("Error updating profile: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -122,19 +131,26 @@ func UpdateProfile(c *fiber.Ctx) error {
 		})
 	}
 
+	if emailChanged {
+		if err := h.Verifier.StartEmailVerification(context.Background(), user); err != nil {
+			log.Printf("Error sending verification email after email change: %v", err)
+		}
+	}
+
 	// response konsisten dengan Me
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"created_at": user.CreatedAt,
-		"updated_at": user.UpdatedAt,
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"created_at":     user.CreatedAt,
+		"updated_at":     user.UpdatedAt,
 	})
 }
 
 
 // DeleteMe menghapus akun user yang sedang login
-func DeleteProfile(c *fiber.Ctx) error {
+func (h *Handler) DeleteProfile(c *fiber.Ctx) error {
 	val := c.Locals("user_id")
 	userID, ok := val.(string)
 	if !ok {
@@ -151,10 +167,7 @@ func DeleteProfile(c *fiber.Ctx) error {
 	}
 
 	// (opsional) hapus semua diary user
-	_, err = config.DiaryCollection.DeleteMany(
-		context.Background(),
-		bson.M{"user_id": objID},
-	)
+	_, err = h.Diaries.DeleteByUser(context.Background(), objID)
 	if err != nil {
 		log.Printf("Failed deleting user diaries: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -162,11 +175,32 @@ func DeleteProfile(c *fiber.Ctx) error {
 		})
 	}
 
+	if _, err := h.Revisions.DeleteByUser(context.Background(), objID); err != nil {
+		log.Printf("Failed deleting user diary revisions: %v", err)
+	}
+
+	if err := h.Attachments.DeleteForUser(context.Background(), objID); err != nil {
+		log.Printf("Failed deleting user attachments: %v", err)
+	}
+
+	if err := h.Analysis.CancelUser(context.Background(), objID); err != nil {
+		log.Printf("Failed deleting user analysis jobs: %v", err)
+	}
+
+	if _, err := h.Feedback.DeleteByUser(context.Background(), objID); err != nil {
+		log.Printf("Failed deleting user analysis feedback: %v", err)
+	}
+
+	if err := h.Sessions.DeleteUserSessions(context.Background(), objID); err != nil {
+		log.Printf("Failed deleting user sessions: %v", err)
+	}
+
+	if err := h.Resets.DeleteUserResets(context.Background(), objID); err != nil {
+		log.Printf("Failed deleting user password resets: %v", err)
+	}
+
 	// hapus user
-	res, err := config.UserCollection.DeleteOne(
-		context.Background(),
-		bson.M{"_id": objID},
-	)
+	deleted, err := h.Users.Delete(context.Background(), objID)
 	if err != nil {
 		log.Printf("Failed deleting user: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -174,7 +208,7 @@ func DeleteProfile(c *fiber.Ctx) error {
 		})
 	}
 
-	if res.DeletedCount == 0 {
+	if !deleted {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "user not found",
 		})
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor adalah posisi terakhir di listing (created_at desc, _id desc).
+// Dikirim ke client sebagai string base64 yang tidak perlu dipahami client.
+type pageCursor struct {
+	CreatedAt time.Time          `json:"t"`
+	ID        primitive.ObjectID `json:"id"`
+}
+
+func encodeCursor(createdAt time.Time, id primitive.ObjectID) string {
+	raw, _ := json.Marshal(pageCursor{CreatedAt: createdAt, ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(token string) (*repositories.DiaryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID.IsZero() {
+		return nil, errors.New("invalid cursor")
+	}
+	return &repositories.DiaryCursor{CreatedAt: cur.CreatedAt, ID: cur.ID}, nil
+}
+
+// parsePageSize membaca query "limit" dengan default dan batas maksimum
+func parsePageSize(c *fiber.Ctx) (int, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultPageSize, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if n > maxPageSize {
+		n = maxPageSize
+	}
+	return n, nil
+}
+
+// diaryFilter membangun filter entri milik user dari query emotion, sentiment, tags, from dan to.
+// tags berisi daftar tag dipisah koma; entri harus punya semua tag tersebut.
+// from/to menerima RFC3339 atau tanggal YYYY-MM-DD (di zona waktu loc); tanggal "to" bersifat inklusif.
+func diaryFilter(c *fiber.Ctx, userObjID primitive.ObjectID, loc *time.Location) (repositories.DiaryFilter, error) {
+	filter := repositories.DiaryFilter{
+		UserID:    userObjID,
+		Emotion:   models.Emotion(c.Query("emotion")),
+		Sentiment: models.Sentiment(c.Query("sentiment")),
+	}
+	// Penulisan label yang longgar ("Senang", "Positive") tetap cocok dengan label kanonik
+	if emotion, ok := models.ParseEmotion(c.Query("emotion")); ok {
+		filter.Emotion = emotion
+	}
+	if sentiment, ok := models.ParseSentiment(c.Query("sentiment")); ok {
+		filter.Sentiment = sentiment
+	}
+
+	if raw := c.Query("tags"); raw != "" {
+		tags, err := normalizeTags(strings.Split(raw, ","))
+		if err != nil {
+			return filter, err
+		}
+		filter.Tags = tags
+	}
+
+	if raw := c.Query("from"); raw != "" {
+		from, _, err := parseDateParam(raw, loc)
+		if err != nil {
+			return filter, errors.New("from must be RFC3339 or YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, dateOnly, err := parseDateParam(raw, loc)
+		if err != nil {
+			return filter, errors.New("to must be RFC3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			filter.To = to.AddDate(0, 0, 1)
+		} else {
+			filter.To = to
+			filter.IncludeTo = true
+		}
+	}
+
+	return filter, nil
+}
+
+// parseDateParam mem-parse RFC3339 atau YYYY-MM-DD (di zona waktu loc)
+// dan melaporkan apakah inputnya hanya tanggal
+func parseDateParam(raw string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, loc)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// currentVersion dipakai di query diff untuk menunjuk isi entri saat ini
+const currentVersion = "current"
+
+// recordRevision menyimpan versi entri sebelum diubah lalu membuang revisi
+// terlama di atas batas DIARY_MAX_REVISIONS. Kegagalan hanya dicatat di log
+// agar perubahan entri tetap berhasil.
+func (h *Handler) recordRevision(ctx context.Context, previous *models.DiaryEntry, replacedAt time.Time) {
+	writtenAt := previous.UpdatedAt
+	if writtenAt.IsZero() {
+		writtenAt = previous.CreatedAt
+	}
+
+	revision := &models.DiaryRevision{
+		EntryID:       previous.ID,
+		UserID:        previous.UserID,
+		Title:         previous.Title,
+		Content:       previous.Content,
+		ContentFormat: previous.ContentFormat,
+		Emotion:       previous.Emotion,
+		Sentiment:     previous.Sentiment,
+		Encrypted:     previous.Encrypted,
+		WrittenAt:     writtenAt,
+		ReplacedAt:    replacedAt,
+	}
+	if err := h.Revisions.Create(ctx, revision); err != nil {
+		log.Printf("Error saving diary revision: %v", err)
+		return
+	}
+	if _, err := h.Revisions.Prune(ctx, previous.ID, h.Config.Revisions.MaxPerEntry); err != nil {
+		log.Printf("Error pruning diary revisions: %v", err)
+	}
+}
+
+// ownedEntry mengambil entri aktif dari parameter :id milik user yang terautentikasi.
+// Jika entri tidak bisa diambil, response error sudah dikirim dan entry bernilai nil.
+func (h *Handler) ownedEntry(c *fiber.Ctx) (*models.DiaryEntry, error) {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid ID format"})
+	}
+
+	entry, err := h.Diaries.FindByID(context.Background(), userObjID, objID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Diary entry not found or not authorized"})
+		}
+		log.Printf("Error finding diary entry by ID: %v", err)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to retrieve diary entry", "error": err.Error()})
+	}
+	return entry, nil
+}
+
+// findRevision mengambil revisi dari entri; id "current" menghasilkan isi entri saat ini
+func (h *Handler) findRevision(ctx context.Context, entry *models.DiaryEntry, id string) (*models.DiaryRevision, error) {
+	if id == currentVersion {
+		writtenAt := entry.UpdatedAt
+		if writtenAt.IsZero() {
+			writtenAt = entry.CreatedAt
+		}
+		return &models.DiaryRevision{
+			EntryID:       entry.ID,
+			UserID:        entry.UserID,
+			Title:         entry.Title,
+			Content:       entry.Content,
+			ContentFormat: entry.ContentFormat,
+			Emotion:       entry.Emotion,
+			Sentiment:     entry.Sentiment,
+			Encrypted:     entry.Encrypted,
+			WrittenAt:     writtenAt,
+		}, nil
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repositories.ErrNotFound
+	}
+	return h.Revisions.FindByID(ctx, entry.UserID, entry.ID, objID)
+}
+
+// revisionError mengirim response untuk error dari findRevision
+func revisionError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Revision not found"})
+	}
+	log.Printf("Error finding diary revision: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to retrieve revision", "error": err.Error()})
+}
+
+// ListDiaryRevisions menampilkan versi lama sebuah entri, terbaru lebih dulu
+func (h *Handler) ListDiaryRevisions(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+
+	revisions, err := h.Revisions.ListByEntry(context.Background(), entry.UserID, entry.ID)
+	if err != nil {
+		log.Printf("Error listing diary revisions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to retrieve revisions", "error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":  revisions,
+		"total": len(revisions),
+	})
+}
+
+// GetDiaryRevision menampilkan satu versi lama sebuah entri
+func (h *Handler) GetDiaryRevision(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+
+	revision, err := h.findRevision(context.Background(), entry, c.Params("rid"))
+	if err != nil {
+		return revisionError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(revision)
+}
+
+// DiffDiaryRevisions membandingkan dua versi entri per baris. Query from dan to berisi
+// id revisi atau "current"; to default ke isi entri saat ini.
+func (h *Handler) DiffDiaryRevisions(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+
+	fromID := c.Query("from")
+	if fromID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from is required"})
+	}
+	toID := c.Query("to", currentVersion)
+
+	from, err := h.findRevision(context.Background(), entry, fromID)
+	if err != nil {
+		return revisionError(c, err)
+	}
+	to, err := h.findRevision(context.Background(), entry, toID)
+	if err != nil {
+		return revisionError(c, err)
+	}
+	if from.Encrypted || to.Encrypted {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Encrypted revisions must be compared on the client"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"from": fiber.Map{"id": fromID, "written_at": from.WrittenAt},
+		"to":   fiber.Map{"id": toID, "written_at": to.WrittenAt},
+		"title": fiber.Map{
+			"from":    from.Title,
+			"to":      to.Title,
+			"changed": from.Title != to.Title,
+		},
+		"content": services.DiffLines(from.Content, to.Content),
+	})
+}
+
+// RestoreDiaryRevision mengembalikan judul dan isi entri ke versi lama.
+// Isi saat ini disimpan sebagai revisi baru sehingga restore juga bisa dibatalkan.
+func (h *Handler) RestoreDiaryRevision(c *fiber.Ctx) error {
+	entry, err := h.ownedEntry(c)
+	if entry == nil {
+		return err
+	}
+
+	rid := c.Params("rid")
+	if rid == currentVersion {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Cannot restore the current version"})
+	}
+	revision, err := h.findRevision(context.Background(), entry, rid)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	format := contentFormat(revision.ContentFormat)
+	update := repositories.DiaryUpdate{
+		Title:         &revision.Title,
+		Content:       &revision.Content,
+		ContentFormat: &format,
+	}
+	if revision.Encrypted != entry.Encrypted {
+		// Revisi dalam mode lama sudah dibuang saat mode berubah, jadi ini hanya data lama
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Revision was saved with a different encryption mode"})
+	}
+	if entry.Encrypted {
+		// Hasil analisis dari client ikut dipulihkan karena server tidak bisa menganalisis ulang
+		update.Analysis = &repositories.DiaryAnalysis{
+			Emotion:         revision.Emotion,
+			EmotionSource:   models.SourceClient,
+			Sentiment:       revision.Sentiment,
+			SentimentSource: models.SourceClient,
+			Status:          models.AnalysisDone,
+		}
+		if revision.Emotion == "" {
+			update.Analysis = &repositories.DiaryAnalysis{Status: models.AnalysisSkipped}
+		}
+	}
+
+	updated, err := h.applyDiaryUpdate(context.Background(), entry, update)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Diary entry not found or not authorized"})
+	}
+	if err != nil {
+		log.Printf("Error restoring diary revision: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to restore revision", "error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// Panjang potongan konten (dalam karakter) di sekitar kata yang cocok
+const snippetRadius = 60
+
+// SearchDiaryEntries mencari entri diary milik user memakai text index MongoDB,
+// diurutkan berdasarkan relevansi dan bisa dikombinasikan dengan filter emotion/sentiment/from/to.
+// Saat enkripsi at rest aktif tidak ada text index, sehingga endpoint ini menjawab 501.
+func (h *Handler) SearchDiaryEntries(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Search query cannot be empty"})
+	}
+
+	filter, err := diaryFilter(c, userObjID, time.UTC)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// Isi entri terenkripsi hanya bisa dicari di client
+	plaintext := false
+	filter.Encrypted = &plaintext
+
+	limit, err := parsePageSize(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	offset := 0
+	if raw := c.Query("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offset must be a non-negative integer"})
+		}
+	}
+
+	hits, total, err := h.Diaries.Search(context.Background(), filter, q, offset, limit)
+	if errors.Is(err, repositories.ErrSearchUnavailable) {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		log.Printf("Error searching diary entries: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to search diary entries", "error": err.Error()})
+	}
+
+	terms := searchTerms(q)
+	results := []fiber.Map{}
+	for _, hit := range hits {
+		results = append(results, fiber.Map{
+			"entry":           hit.Entry,
+			"score":           hit.Score,
+			"title_highlight": highlight(hit.Entry.Title, terms, 0),
+			"snippet":         highlight(services.PlainText(hit.Entry.ContentFormat, hit.Entry.Content), terms, snippetRadius),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":   results,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// searchTerms mengambil kata dari query $text untuk di-highlight.
+// Kata yang dinegasikan ("-kata") diabaikan dan frasa dalam tanda kutip dipecah per kata.
+func searchTerms(q string) []string {
+	var terms []string
+	for _, field := range strings.Fields(q) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		terms = append(terms, tokenizeSearch(field)...)
+	}
+	return terms
+}
+
+func tokenizeSearch(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlight meng-escape teks sebagai HTML dan membungkus kata yang cocok dengan <mark>.
+// Jika radius > 0, hanya potongan di sekitar kecocokan pertama yang dikembalikan.
+func highlight(text string, terms []string, radius int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Tandai kata yang sama persis dengan salah satu term; text index memakai
+	// bahasa "none" sehingga MongoDB juga hanya mencocokkan kata utuh
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			end := i + len(t)
+			if i > 0 && isWordRune(lower[i-1]) || end < len(lower) && isWordRune(lower[end]) {
+				continue
+			}
+			if string(lower[i:end]) != term {
+				continue
+			}
+			for j := i; j < end; j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, stop := 0, len(runes)
+	if radius > 0 {
+		if first == -1 {
+			first = 0
+		}
+		start = max(0, first-radius)
+		stop = min(len(runes), first+radius)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < stop; i++ {
+		if marked[i] != inMark {
+			if marked[i] {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			inMark = marked[i]
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if inMark {
+		b.WriteString("</mark>")
+	}
+	if stop < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionOwner mengambil user id dan session id dari Locals yang diset JWTProtected
+func sessionOwner(c *fiber.Ctx) (primitive.ObjectID, primitive.ObjectID, error) {
+	userID, _ := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	sessionObjID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	return userObjID, sessionObjID, nil
+}
+
+// ListSessions menampilkan semua sesi aktif user beserta perangkat, IP dan waktu terakhir dipakai
+func (h *Handler) ListSessions(c *fiber.Ctx) error {
+	userObjID, currentID, err := sessionOwner(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid or missing token",
+		})
+	}
+
+	sessions, err := h.Sessions.ListActiveSessions(context.Background(), userObjID)
+	if err != nil {
+		log.Printf("Error listing sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "failed to list sessions",
+		})
+	}
+
+	type SessionResponse struct {
+		ID         primitive.ObjectID `json:"id"`
+		UserAgent  string             `json:"user_agent"`
+		IP         string             `json:"ip"`
+		CreatedAt  time.Time          `json:"created_at"`
+		LastSeenAt time.Time          `json:"last_seen_at"`
+		ExpiresAt  time.Time          `json:"expires_at"`
+		Current    bool               `json:"current"`
+	}
+
+	resp := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, SessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == currentID,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// RevokeSession mencabut satu sesi milik user (termasuk sesi saat ini)
+func (h *Handler) RevokeSession(c *fiber.Ctx) error {
+	userObjID, _, err := sessionOwner(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid or missing token",
+		})
+	}
+
+	sessionObjID, err := primitive.ObjectIDFromHex(c.Params("sid"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "invalid session id format",
+		})
+	}
+
+	found, err := h.Sessions.RevokeUserSession(context.Background(), userObjID, sessionObjID, "revoked by user")
+	if err != nil {
+		log.Printf("Error revoking session: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "failed to revoke session",
+		})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "session not found",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "session revoked",
+	})
+}
+
+// RevokeOtherSessions mencabut semua sesi user selain sesi yang sedang dipakai
+func (h *Handler) RevokeOtherSessions(c *fiber.Ctx) error {
+	userObjID, currentID, err := sessionOwner(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid or missing token",
+		})
+	}
+
+	revoked, err := h.Sessions.RevokeOtherSessions(context.Background(), userObjID, currentID, "revoked by user")
+	if err != nil {
+		log.Printf("Error revoking other sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "failed to revoke sessions",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "other sessions revoked",
+		"revoked": revoked,
+	})
+}
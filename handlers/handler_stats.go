@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/repositories"
+)
+
+// Nama hari sesuai $isoDayOfWeek (1 = Senin ... 7 = Minggu)
+var isoWeekdays = []string{"", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// timelineBucket adalah satu titik di timeline harian/mingguan/bulanan
+type timelineBucket struct {
+	Period   string         `json:"period"`
+	Total    int            `json:"total"`
+	Positive int            `json:"positive"`
+	Negative int            `json:"negative"`
+	Neutral  int            `json:"neutral"`
+	Emotions map[string]int `json:"emotions"`
+}
+
+// timelineBuckets meringkas emosi mentah tiap periode menjadi jumlah per emosi
+func timelineBuckets(rows []repositories.TimelineRow) []timelineBucket {
+	buckets := make([]timelineBucket, 0, len(rows))
+	for _, row := range rows {
+		buckets = append(buckets, timelineBucket{
+			Period:   row.Period,
+			Total:    row.Total,
+			Positive: row.Positive,
+			Negative: row.Negative,
+			Neutral:  row.Neutral,
+			Emotions: countEmotions(row.Emotions),
+		})
+	}
+	return buckets
+}
+
+// GetDiaryStats mengembalikan analitik mood user: distribusi emosi, rasio sentimen,
+// timeline harian/mingguan/bulanan, streak positif terpanjang dan emosi tersering per hari.
+// Query: from, to (RFC3339 atau YYYY-MM-DD) dan tz (nama zona IANA, default UTC).
+func (h *Handler) GetDiaryStats(c *fiber.Ctx) error {
+	val := c.Locals("user_id")
+	userID, ok := val.(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+
+	tz := c.Query("tz", "UTC")
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid timezone"})
+	}
+
+	filter, err := diaryFilter(c, userObjID, loc)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// Entri yang belum selesai dianalisis tidak ikut dihitung
+	filter.AnalyzedOnly = true
+
+	stats, err := h.Diaries.Stats(context.Background(), filter, loc)
+	if err != nil {
+		log.Printf("Error aggregating diary stats: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to compute diary stats", "error": err.Error()})
+	}
+
+	total := 0
+	distribution := map[string]int{}
+	for _, row := range stats.Emotions {
+		distribution[row.Key] = row.Count
+		total += row.Count
+	}
+
+	sentimentCounts := map[string]int{"positive": 0, "negative": 0, "neutral": 0}
+	for _, row := range stats.Sentiments {
+		if _, known := sentimentCounts[row.Key]; known {
+			sentimentCounts[row.Key] = row.Count
+		}
+	}
+	sentimentRatio := map[string]float64{"positive": 0, "negative": 0, "neutral": 0}
+	if total > 0 {
+		for key, count := range sentimentCounts {
+			sentimentRatio[key] = float64(count) / float64(total)
+		}
+	}
+
+	daily := timelineBuckets(stats.Daily)
+
+	// Emosi tersering per hari; jika seri, ambil yang urutan abjadnya lebih dulu
+	type weekdayTop struct {
+		Emotion string `json:"emotion"`
+		Count   int    `json:"count"`
+	}
+	weekdays := map[string]weekdayTop{}
+	for _, row := range stats.Weekdays {
+		if row.Day < 1 || row.Day > 7 {
+			continue
+		}
+		day := isoWeekdays[row.Day]
+		current, exists := weekdays[day]
+		if !exists || row.Count > current.Count || row.Count == current.Count && row.Emotion < current.Emotion {
+			weekdays[day] = weekdayTop{Emotion: row.Emotion, Count: row.Count}
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"timezone":                tz,
+		"total_entries":           total,
+		"emotion_distribution":    distribution,
+		"sentiment_counts":        sentimentCounts,
+		"sentiment_ratio":         sentimentRatio,
+		"timeline":                fiber.Map{"daily": daily, "weekly": timelineBuckets(stats.Weekly), "monthly": timelineBuckets(stats.Monthly)},
+		"longest_positive_streak": longestPositiveStreak(daily),
+		"top_emotion_by_weekday":  weekdays,
+	})
+}
+
+func countEmotions(emotions []string) map[string]int {
+	counts := map[string]int{}
+	for _, emotion := range emotions {
+		counts[emotion]++
+	}
+	return counts
+}
+
+// positiveStreak adalah rangkaian hari berturut-turut yang mayoritas entrinya positif
+type positiveStreak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// longestPositiveStreak mencari hari berturut-turut terpanjang di mana entri positif
+// lebih banyak dari entri negatif. Hari tanpa entri memutus streak.
+func longestPositiveStreak(daily []timelineBucket) positiveStreak {
+	days := make([]timelineBucket, len(daily))
+	copy(days, daily)
+	sort.Slice(days, func(i, j int) bool { return days[i].Period < days[j].Period })
+
+	var best, current positiveStreak
+	var prev time.Time
+	for _, day := range days {
+		date, err := time.Parse("2006-01-02", day.Period)
+		if err != nil || day.Positive <= day.Negative {
+			current = positiveStreak{}
+			continue
+		}
+
+		if current.Days > 0 && date.Sub(prev) == 24*time.Hour {
+			current.Days++
+			current.End = day.Period
+		} else {
+			current = positiveStreak{Days: 1, Start: day.Period, End: day.Period}
+		}
+		prev = date
+
+		if current.Days > best.Days {
+			best = current
+		}
+	}
+	return best
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxTagsPerEntry = 20
+	maxTagLength    = 32
+)
+
+// normalizeTag merapikan satu tag: huruf kecil, tanpa spasi berlebih
+func normalizeTag(raw string) (string, error) {
+	tag := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if tag == "" {
+		return "", errors.New("tag cannot be empty")
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+	}
+	if strings.Contains(tag, ",") {
+		return "", fmt.Errorf("tag %q cannot contain a comma", tag)
+	}
+	return tag, nil
+}
+
+// normalizeTags merapikan dan membuang tag duplikat dengan urutan tetap
+func normalizeTags(raw []string) ([]string, error) {
+	tags := make([]string, 0, len(raw))
+	seen := map[string]bool{}
+	for _, r := range raw {
+		tag, err := normalizeTag(r)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTagsPerEntry {
+		return nil, fmt.Errorf("an entry can have at most %d tags", maxTagsPerEntry)
+	}
+	return tags, nil
+}
+
+// tagParam membaca tag dari parameter URL :tag
+func tagParam(c *fiber.Ctx) (string, error) {
+	raw, err := url.PathUnescape(c.Params("tag"))
+	if err != nil {
+		return "", errors.New("invalid tag")
+	}
+	return normalizeTag(raw)
+}
+
+// tagUser membaca user_id dari token; jika gagal response error sudah dikirim
+func tagUser(c *fiber.Ctx) (primitive.ObjectID, error) {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok {
+		return primitive.NilObjectID, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return primitive.NilObjectID, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+	return userObjID, nil
+}
+
+// ListTags menampilkan semua tag milik user beserta jumlah entri yang memakainya
+func (h *Handler) ListTags(c *fiber.Ctx) error {
+	userObjID, err := tagUser(c)
+	if userObjID.IsZero() {
+		return err
+	}
+
+	counts, err := h.Diaries.TagCounts(context.Background(), userObjID)
+	if err != nil {
+		log.Printf("Error counting tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to retrieve tags", "error": err.Error()})
+	}
+
+	tags := make([]fiber.Map, 0, len(counts))
+	for _, row := range counts {
+		tags = append(tags, fiber.Map{"tag": row.Key, "count": row.Count})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": tags})
+}
+
+// RenameTag mengganti nama tag di semua entri user. Jika nama baru sudah dipakai,
+// kedua tag otomatis tergabung.
+func (h *Handler) RenameTag(c *fiber.Ctx) error {
+	userObjID, err := tagUser(c)
+	if userObjID.IsZero() {
+		return err
+	}
+
+	tag, err := tagParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "detail": err.Error()})
+	}
+	name, err := normalizeTag(payload.Name)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if name == tag {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "new tag name must be different"})
+	}
+
+	return h.replaceTags(c, userObjID, []string{tag}, name)
+}
+
+// MergeTags menggabungkan beberapa tag menjadi satu tag tujuan
+func (h *Handler) MergeTags(c *fiber.Ctx) error {
+	userObjID, err := tagUser(c)
+	if userObjID.IsZero() {
+		return err
+	}
+
+	var payload struct {
+		Tags []string `json:"tags"`
+		Into string   `json:"into"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "detail": err.Error()})
+	}
+	if len(payload.Tags) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tags cannot be empty"})
+	}
+	from, err := normalizeTags(payload.Tags)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	into, err := normalizeTag(payload.Into)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Entri yang sudah memakai tag tujuan tidak perlu diubah
+	sources := from[:0]
+	for _, tag := range from {
+		if tag != into {
+			sources = append(sources, tag)
+		}
+	}
+	if len(sources) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tags must contain a tag other than into"})
+	}
+
+	return h.replaceTags(c, userObjID, sources, into)
+}
+
+// DeleteTag melepas tag dari semua entri user
+func (h *Handler) DeleteTag(c *fiber.Ctx) error {
+	userObjID, err := tagUser(c)
+	if userObjID.IsZero() {
+		return err
+	}
+
+	tag, err := tagParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return h.replaceTags(c, userObjID, []string{tag}, "")
+}
+
+func (h *Handler) replaceTags(c *fiber.Ctx, userObjID primitive.ObjectID, from []string, into string) error {
+	updated, err := h.Diaries.ReplaceTags(context.Background(), userObjID, from, into)
+	if err != nil {
+		log.Printf("Error updating tags: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to update tags", "error": err.Error()})
+	}
+	if updated == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Tag not found"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Tags updated", "updated_entries": updated})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+// GetTrashedEntries menampilkan entri diary di tempat sampah milik user yang terautentikasi.
+// Mendukung filter dan cursor yang sama dengan GET /api/diary.
+func (h *Handler) GetTrashedEntries(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+
+	filter, err := diaryFilter(c, userObjID, time.UTC)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	filter.Trashed = true
+
+	return h.listDiaryPage(c, filter)
+}
+
+// RestoreDiaryEntry mengembalikan entri dari tempat sampah
+func (h *Handler) RestoreDiaryEntry(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or missing token"})
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id in token"})
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid ID format"})
+	}
+
+	// Entri yang sudah lewat masa retensi tidak bisa dipulihkan lagi, sehingga purger aman
+	// menghapus lampirannya sebelum entrinya
+	since := time.Now().Add(-h.Config.Trash.Retention)
+	entry, err := h.Diaries.Restore(context.Background(), userObjID, objID, since)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Diary entry not found in trash"})
+		}
+		log.Printf("Error restoring diary entry: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Failed to restore diary entry", "error": err.Error()})
+	}
+
+	// Job analisis dibuang saat entri dihapus, jadi entri yang belum selesai dianalisis dijadwalkan ulang
+	if entry.AnalysisStatus == models.AnalysisPending {
+		if err := h.Analysis.Enqueue(context.Background(), entry.ID, entry.UserID); err != nil {
+			log.Printf("Error enqueueing emotion analysis: %v", err)
+			h.markAnalysisFailed(entry)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(entry)
+}
@@ -1,41 +1,152 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
+	"os"
+	_ "time/tzdata" // database zona waktu untuk statistik mood (image alpine tidak membawanya)
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors" // Untuk menangani CORS
 
 	"web-diary-be/config"
+	"web-diary-be/handlers"
+	"web-diary-be/middleware"
+	"web-diary-be/repositories"
 	"web-diary-be/routes"
+	"web-diary-be/services"
 )
 
 func main() {
-	// Load environment variables dari .env
-	config.LoadEnv()
+	// Konfigurasi dari default, file CONFIG_FILE (opsional), lalu environment/.env
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
-	// Koneksi ke MongoDB
-	config.ConnectDB()
-	defer config.DisconnectDB() // Pastikan koneksi ditutup saat aplikasi berhenti
+	// Koneksi ke MongoDB; text index diary tidak berguna jika isinya terenkripsi at rest
+	db, err := config.ConnectDB(cfg.Mongo, !cfg.AtRest.Enabled())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Disconnect() // Pastikan koneksi ditutup saat aplikasi berhenti
 
-	app := fiber.New()
+	// "reencrypt" mengenkripsi ulang diary dengan kunci at rest terbaru lalu keluar
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt" {
+		if err := runReencrypt(cfg, db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// "export-feedback" menulis koreksi user atas hasil analisis emosi sebagai JSON Lines lalu keluar
+	if len(os.Args) > 1 && os.Args[1] == "export-feedback" {
+		if err := runExportFeedback(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// "normalize-labels" merapikan emosi/sentimen lama di diary_entries ke label kanonik lalu keluar
+	if len(os.Args) > 1 && os.Args[1] == "normalize-labels" {
+		if err := runNormalizeLabels(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Backend analisis emosi sesuai EMOTION_ANALYZER
+	analyzer, err := services.NewEmotionAnalyzer(cfg.Analyzer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if closer, ok := analyzer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Backend email sesuai MAILER
+	mailer, err := services.NewMailer(cfg.Mail)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Counter untuk rate limit dan lockout login sesuai RATE_LIMIT_STORE
+	limits, err := services.NewCounterStore(cfg.RateLimit, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Penyimpanan file lampiran sesuai ATTACHMENT_STORE
+	blobs, err := services.NewBlobStore(cfg.Attachments, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var diaries repositories.DiaryRepository = repositories.NewMongoDiaryRepository(db.Diaries)
+	var revisions repositories.RevisionRepository = repositories.NewMongoRevisionRepository(db.Revisions)
+
+	// Judul dan isi diary dienkripsi di database jika ENCRYPTION_MASTER_KEY_ID diisi
+	if cfg.AtRest.Enabled() {
+		atRest, err := services.NewAtRestCipher(cfg.AtRest, repositories.NewMongoDataKeyRepository(db.DataKeys))
+		if err != nil {
+			log.Fatal(err)
+		}
+		diaries = repositories.NewEncryptedDiaryRepository(diaries, atRest)
+		revisions = repositories.NewEncryptedRevisionRepository(revisions, atRest)
+	}
+	users := repositories.NewMongoUserRepository(db.Users)
+	jobs := repositories.NewMongoAnalysisJobRepository(db.Jobs)
+	sessions := services.NewSessionService(repositories.NewMongoSessionRepository(db.Sessions), cfg.Auth)
+	resets := repositories.NewMongoPasswordResetRepository(db.Resets)
+	attachments := services.NewAttachmentService(repositories.NewMongoAttachmentRepository(db.Attachments), blobs, cfg.Attachments)
+	h := &handlers.Handler{
+		Config:      cfg,
+		Diaries:     diaries,
+		Revisions:   revisions,
+		Attachments: attachments,
+		Exports:     services.NewAccountExporter(diaries, attachments),
+		Users:       users,
+		Auth:        middleware.NewAuth(cfg, users, sessions),
+		Sessions:    sessions,
+		Verifier:    services.NewEmailVerifier(cfg, users, mailer),
+		Resets:      services.NewPasswordResetService(cfg.Auth, resets, users, sessions, mailer),
+		Analysis:    services.NewAnalysisQueue(jobs),
+		Feedback:    repositories.NewMongoAnalysisFeedbackRepository(db.Feedback),
+		Lockout:     services.NewLoginLockout(limits, cfg.RateLimit),
+		Limits:      limits,
+	}
+
+	// Worker analisis emosi berjalan di background, entri diary disimpan dulu dengan status pending
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := services.NewAnalysisWorkerPool(jobs, diaries, analyzer, cfg.Analyzer)
+	workers.Start(workerCtx)
+	defer workers.Wait()
+
+	// Entri di tempat sampah dihapus permanen setelah TRASH_RETENTION
+	purger := services.NewTrashPurger(diaries, revisions, attachments, cfg.Trash)
+	purger.Start(workerCtx)
+	defer purger.Wait()
+	defer stopWorkers()
+
+	app := routes.NewApp(cfg)
 
 	// Middleware CORS agar frontend bisa mengakses API ini
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "*",
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
 		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		ExposeHeaders:    "Content-Length, Access-Control-Allow-Origin",
+		ExposeHeaders:    "Content-Length, Access-Control-Allow-Origin, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset",
 		AllowCredentials: false, // set to true only if frontend sends cookies/credentials
 		MaxAge:           3600,
 	}))
-	routes.AuthRoutes(app) // Rute untuk otentikasi
-	routes.DiaryRoutes(app)
-	routes.ProfileRoutes(app)
 
+	routes.AuthRoutes(app, h) // Rute untuk otentikasi
+	routes.DiaryRoutes(app, h)
+	routes.TagRoutes(app, h)
+	routes.ProfileRoutes(app, h)
 
 	// Jalankan server
-	port := ":8080"
+	port := ":" + cfg.Port
 	log.Printf("Server is running on port %s", port)
 	log.Fatal(app.Listen(port))
 }
@@ -1,15 +1,47 @@
 package middleware
 
 import (
-	"os"
+	"context"
+	"errors"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
 )
 
-func JWTProtected() fiber.Handler {
+// AccessClaims adalah isi access token yang dipakai handler
+type AccessClaims struct {
+	UserID    string
+	SessionID string
+}
+
+// Auth menerbitkan dan memvalidasi access token serta memeriksa sesi pemiliknya
+type Auth struct {
+	secret    []byte
+	accessTTL time.Duration
+	policy    string
+	sessions  *services.SessionService
+	users     repositories.UserRepository
+}
+
+func NewAuth(cfg *config.Config, users repositories.UserRepository, sessions *services.SessionService) *Auth {
+	return &Auth{
+		secret:    []byte(cfg.JWTSecret),
+		accessTTL: cfg.Auth.AccessTokenTTL,
+		policy:    cfg.Auth.UnverifiedPolicy,
+		sessions:  sessions,
+		users:     users,
+	}
+}
+
+func (a *Auth) JWTProtected() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
 		if auth == "" || !strings.HasPrefix(auth, "Bearer ") {
@@ -20,44 +52,117 @@ func JWTProtected() fiber.Handler {
 
 		tokenStr := strings.TrimPrefix(auth, "Bearer ")
 
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := a.ParseAccessToken(tokenStr)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
+				"error": err.Error(),
 			})
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		// Sesi dicek di database agar logout / pencabutan sesi langsung berlaku
+		userObjID, errUser := primitive.ObjectIDFromHex(claims.UserID)
+		sessionObjID, errSession := primitive.ObjectIDFromHex(claims.SessionID)
+		if errUser != nil || errSession != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid claims structure",
 			})
 		}
+		active, err := a.sessions.IsSessionActive(context.Background(), sessionObjID, userObjID)
+		if err != nil {
+			log.Printf("Error checking session: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify session",
+			})
+		}
+		if !active {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session has been revoked or expired",
+			})
+		}
+
+		if err := a.sessions.TouchSession(context.Background(), sessionObjID, c.Get("User-Agent"), c.IP()); err != nil {
+			log.Printf("Error updating session last seen: %v", err)
+		}
+
+		c.Locals("user_id", claims.UserID)
+		c.Locals("session_id", claims.SessionID)
+		return c.Next()
+	}
+}
+
+// RequireVerifiedEmail menerapkan UNVERIFIED_POLICY "read_only": user yang belum
+// memverifikasi email hanya boleh memakai method yang tidak mengubah data.
+// Harus dipasang setelah JWTProtected.
+func (a *Auth) RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if a.policy != config.UnverifiedReadOnly {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
 
-		val, ok := claims["user_id"]
-		userID, isString := val.(string)
-		if !ok || !isString || userID == "" {
+		userID, _ := c.Locals("user_id").(string)
+		objID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "user_id not found in token",
+				"error": "invalid or missing token",
 			})
 		}
 
-		c.Locals("user_id", userID)
+		user, err := a.users.FindByID(context.Background(), objID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			log.Printf("Error checking email verification: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify account",
+			})
+		}
+		if user == nil || !user.EmailVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Email not verified",
+			})
+		}
 		return c.Next()
 	}
 }
 
-// GenerateJWT membuat token JWT untuk user
-func GenerateJWT(userID string) (string, error) {
+// ParseAccessToken memvalidasi signature dan masa berlaku access token lalu mengambil claims-nya.
+// Status sesi tidak dicek di sini.
+func (a *Auth) ParseAccessToken(tokenStr string) (*AccessClaims, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("Invalid or expired token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("Invalid claims structure")
+	}
+
+	userID, _ := claims["user_id"].(string)
+	if userID == "" {
+		return nil, errors.New("user_id not found in token")
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		return nil, errors.New("session not found in token")
+	}
+
+	return &AccessClaims{UserID: userID, SessionID: sessionID}, nil
+}
+
+// GenerateJWT membuat access token JWT untuk user yang terikat ke satu sesi
+func (a *Auth) GenerateJWT(userID, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":    jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // expired 24 jam
+		"sid":     sessionID,
+		"exp":     jwt.NewNumericDate(time.Now().Add(a.accessTTL)),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := os.Getenv("JWT_SECRET")
-	return token.SignedString([]byte(secret))
+	return token.SignedString(a.secret)
 }
-
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"web-diary-be/services"
+)
+
+// RateLimitConfig mengatur satu rate limiter untuk satu group route
+type RateLimitConfig struct {
+	Store  services.CounterStore
+	Name   string // prefix key, agar limiter untuk group berbeda tidak saling berbagi counter
+	Max    int
+	Window time.Duration
+	// KeyFunc menentukan siapa yang dibatasi; default per IP
+	KeyFunc func(c *fiber.Ctx) string
+}
+
+// ByIP membatasi berdasarkan alamat IP client
+func ByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// ByAccount membatasi berdasarkan user_id dari token (pasang setelah JWTProtected),
+// dengan fallback ke IP jika belum ada user
+func ByAccount(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return ByIP(c)
+}
+
+// RateLimit menolak request dengan 429 dan header Retry-After jika batas terlampaui
+func RateLimit(cfg RateLimitConfig) fiber.Handler {
+	keyFunc := cfg.KeyFunc
+	if keyFunc == nil {
+		keyFunc = ByIP
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := "rl:" + cfg.Name + ":" + keyFunc(c)
+		count, resetAt, err := cfg.Store.Increment(context.Background(), key, cfg.Window)
+		if err != nil {
+			// Store bermasalah tidak boleh membuat API mati total
+			log.Printf("Rate limit store error: %v", err)
+			return c.Next()
+		}
+
+		remaining := cfg.Max - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Set("X-RateLimit-Limit", strconv.Itoa(cfg.Max))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+		if count > cfg.Max {
+			return TooManyRequests(c, time.Until(resetAt))
+		}
+		return c.Next()
+	}
+}
+
+// TooManyRequests mengirim respons 429 dengan Retry-After (dibulatkan ke atas, dalam detik)
+func TooManyRequests(c *fiber.Ctx, retryAfter time.Duration) error {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "Too many requests, please try again later",
+		"retry_after": seconds,
+	})
+}
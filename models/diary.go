@@ -8,22 +8,193 @@ import (
 
 // User merepresentasikan satu dokumen pengguna di koleksi 'users' MongoDB
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	Username     string             `bson:"username"`
-	Email        string             `bson:"email"` 
-	Password     string             `bson:"password"`        
-	CreatedAt    time.Time          `bson:"created_at"` 
-	UpdatedAt    time.Time          `bson:"updated_at,omitempty"`
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Username  string             `bson:"username"`
+	Email     string             `bson:"email"`
+	Password  string             `bson:"password"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at,omitempty"`
+
+	// Verifikasi email: nonce hanya berlaku untuk token verifikasi terakhir yang dikirim
+	EmailVerified     bool       `bson:"email_verified"`
+	EmailVerifiedAt   *time.Time `bson:"email_verified_at,omitempty"`
+	VerificationNonce string     `bson:"verification_nonce,omitempty"`
+
+	// Encryption diisi jika user mengaktifkan mode enkripsi end-to-end
+	Encryption *EncryptionKey `bson:"encryption,omitempty"`
+}
+
+// EncryptionKey adalah metadata kunci enkripsi end-to-end milik user. Data key dibuat dan
+// dipakai di client; server hanya menyimpan data key yang sudah dibungkus (wrapped) dengan
+// kunci turunan passphrase, beserta parameter KDF untuk menurunkannya kembali.
+type EncryptionKey struct {
+	Cipher     string    `json:"cipher" bson:"cipher"` // cipher untuk isi entri, mis. "aes-256-gcm"
+	KDF        KDFParams `json:"kdf" bson:"kdf"`
+	WrappedKey string    `json:"wrapped_key" bson:"wrapped_key"` // base64, termasuk nonce/tag pembungkus
+	// Version naik setiap kali data key dibungkus ulang (mis. passphrase diganti)
+	Version   int       `json:"version" bson:"version"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// KDFParams adalah parameter penurunan kunci dari passphrase
+type KDFParams struct {
+	Algorithm   string `json:"algorithm" bson:"algorithm"` // "argon2id" atau "pbkdf2-sha256"
+	Salt        string `json:"salt" bson:"salt"`           // base64
+	Iterations  int    `json:"iterations" bson:"iterations"`
+	MemoryKiB   int    `json:"memory_kib,omitempty" bson:"memory_kib,omitempty"` // hanya argon2id
+	Parallelism int    `json:"parallelism,omitempty" bson:"parallelism,omitempty"`
 }
 
 type DiaryEntry struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Title     string             `json:"title" bson:"title,omitempty"`
-	Content   string             `json:"content" bson:"content,omitempty"`
-	Emotion   string             `json:"emotion,omitempty" bson:"emotion,omitempty"`     // Contoh: "Joy", "Sadness", "Anger"
-	Sentiment string             `json:"sentiment,omitempty" bson:"sentiment,omitempty"` // Contoh: "Positive", "Negative", "Neutral"
-	CreatedAt time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`	
-	UpdatedAt time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Title          string             `json:"title" bson:"title,omitempty"`
+	Content        string             `json:"content" bson:"content,omitempty"`
+	ContentFormat  string             `json:"content_format,omitempty" bson:"content_format,omitempty"`   // "plain" (juga jika kosong), "markdown", "html"
+	Emotion        Emotion            `json:"emotion,omitempty" bson:"emotion,omitempty"`                 // label kanonik, lihat Emotions
+	Sentiment      Sentiment          `json:"sentiment,omitempty" bson:"sentiment,omitempty"`             // "positive", "negative", "neutral"
+	AnalysisStatus string             `json:"analysis_status,omitempty" bson:"analysis_status,omitempty"` // "pending", "done", "failed"
+	Tags           []string           `json:"tags,omitempty" bson:"tags,omitempty"`                       // huruf kecil, unik per entri
+	// EmotionSource dan SentimentSource mencatat asal nilai emosi/sentimen (lihat SourceModel)
+	EmotionSource   string `json:"emotion_source,omitempty" bson:"emotion_source,omitempty"`
+	SentimentSource string `json:"sentiment_source,omitempty" bson:"sentiment_source,omitempty"`
+	// AnalysisDetail berisi skor lengkap dari analyzer server; kosong untuk analisis dari client
+	AnalysisDetail *AnalysisDetail `json:"analysis,omitempty" bson:"analysis,omitempty"`
+	// AnalyzedAt adalah waktu hasil analisis emosi terakhir ditulis
+	AnalyzedAt *time.Time `json:"analyzed_at,omitempty" bson:"analyzed_at,omitempty"`
+	// Encrypted berarti judul dan isi adalah ciphertext base64 dari client (mode end-to-end)
+	Encrypted bool      `json:"encrypted,omitempty" bson:"encrypted,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	// DeletedAt diisi saat entri dipindah ke tempat sampah; entri dihapus permanen setelah masa retensi
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+
+	// StorageFormat dan KeyVersion menandai cara judul/isi disimpan di database (lihat StoragePlaintext)
+	StorageFormat int `json:"-" bson:"storage_format,omitempty"`
+	KeyVersion    int `json:"-" bson:"key_version,omitempty"`
 }
 
+// AnalysisDetail adalah rincian hasil analisis emosi sebuah entri
+type AnalysisDetail struct {
+	// Scores berisi skor 0..1 untuk setiap label emosi yang didukung; beberapa emosi bisa
+	// bernilai tinggi sekaligus
+	Scores map[string]float64 `json:"scores" bson:"scores"`
+	// Emotions adalah label dengan skor di atas ambang, urut dari skor tertinggi
+	Emotions       []string `json:"emotions,omitempty" bson:"emotions,omitempty"`
+	SentimentScore float64  `json:"sentiment_score" bson:"sentiment_score"` // -1 (negatif) sampai 1 (positif)
+	Confidence     float64  `json:"confidence" bson:"confidence"`           // 0..1
+	Provider       string   `json:"provider" bson:"provider"`               // "gemini" atau "lexicon"
+	Model          string   `json:"model,omitempty" bson:"model,omitempty"`
+	PromptVersion  string   `json:"prompt_version,omitempty" bson:"prompt_version,omitempty"`
+}
+
+// Format isi entri diary
+const (
+	ContentPlain    = "plain"
+	ContentMarkdown = "markdown"
+	ContentHTML     = "html" // disanitasi server sebelum disimpan
+)
+
+// Format penyimpanan judul/isi entri dan revisi di database
+const (
+	StoragePlaintext = 0 // dokumen lama sebelum enkripsi at rest, dimigrasi oleh perintah reencrypt
+	StorageAESGCM    = 1 // AES-256-GCM dengan data key user versi KeyVersion
+)
+
+// DataKey adalah kunci enkripsi at rest milik satu user di koleksi 'data_keys'.
+// Kunci disimpan terbungkus (AES-GCM) oleh master key MasterKeyID dari konfigurasi.
+type DataKey struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	Version     int                `bson:"version"` // versi terbesar dipakai untuk enkripsi baru
+	MasterKeyID string             `bson:"master_key_id"`
+	WrappedKey  []byte             `bson:"wrapped_key"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+// DiaryRevision adalah salinan versi lama entri diary di koleksi 'diary_revisions',
+// disimpan setiap kali judul atau isi entri diubah
+type DiaryRevision struct {
+	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	EntryID       primitive.ObjectID `json:"entry_id" bson:"entry_id"`
+	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Title         string             `json:"title" bson:"title,omitempty"`
+	Content       string             `json:"content" bson:"content,omitempty"`
+	ContentFormat string             `json:"content_format,omitempty" bson:"content_format,omitempty"`
+	Emotion       Emotion            `json:"emotion,omitempty" bson:"emotion,omitempty"`
+	Sentiment     Sentiment          `json:"sentiment,omitempty" bson:"sentiment,omitempty"`
+	Encrypted     bool               `json:"encrypted,omitempty" bson:"encrypted,omitempty"`
+	// WrittenAt adalah waktu versi ini ditulis, ReplacedAt waktu versi ini digantikan
+	WrittenAt  time.Time `json:"written_at" bson:"written_at"`
+	ReplacedAt time.Time `json:"replaced_at" bson:"replaced_at"`
+
+	StorageFormat int `json:"-" bson:"storage_format,omitempty"`
+	KeyVersion    int `json:"-" bson:"key_version,omitempty"`
+}
+
+// Attachment adalah metadata foto/file yang dilampirkan ke entri diary, di koleksi 'attachments'.
+// Isi file dan thumbnail disimpan di blob store dengan kunci BlobKey dan ThumbnailKey.
+type Attachment struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	EntryID      primitive.ObjectID `json:"entry_id" bson:"entry_id"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Filename     string             `json:"filename" bson:"filename"`
+	ContentType  string             `json:"content_type" bson:"content_type"`
+	Size         int64              `json:"size" bson:"size"`
+	Width        int                `json:"width,omitempty" bson:"width,omitempty"` // hanya untuk gambar
+	Height       int                `json:"height,omitempty" bson:"height,omitempty"`
+	HasThumbnail bool               `json:"has_thumbnail" bson:"has_thumbnail"`
+	BlobKey      string             `json:"-" bson:"blob_key"`
+	ThumbnailKey string             `json:"-" bson:"thumbnail_key,omitempty"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Status analisis emosi, dipakai di DiaryEntry dan AnalysisJob
+const (
+	AnalysisPending    = "pending"
+	AnalysisProcessing = "processing" // hanya untuk job yang sedang dikerjakan worker
+	AnalysisDone       = "done"
+	AnalysisFailed     = "failed"
+	// AnalysisSkipped untuk entri terenkripsi yang tidak disertai hasil analisis dari client,
+	// atau entri hasil impor yang tidak diminta untuk dianalisis
+	AnalysisSkipped = "skipped"
+)
+
+// Asal nilai emosi dan sentimen entri. Kosong pada entri lama, diperlakukan seperti SourceModel.
+const (
+	SourceModel  = "model"  // hasil analyzer server
+	SourceClient = "client" // dianalisis client untuk entri terenkripsi
+	SourceUser   = "user"   // diisi atau dikoreksi user; tidak pernah ditimpa analisis ulang
+)
+
+// AnalysisFeedback dicatat di koleksi 'analysis_feedback' setiap kali user mengoreksi emosi
+// atau sentimen hasil analyzer, untuk mengevaluasi akurasi analyzer. Isi entri tidak ikut disimpan.
+type AnalysisFeedback struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	EntryID    primitive.ObjectID `json:"entry_id" bson:"entry_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Field      string             `json:"field" bson:"field"` // "emotion" atau "sentiment"
+	ModelValue string             `json:"model_value" bson:"model_value"`
+	UserValue  string             `json:"user_value" bson:"user_value"`
+	// Analysis adalah rincian analisis yang dikoreksi, termasuk provider, model dan versi prompt
+	Analysis  *AnalysisDetail `json:"analysis,omitempty" bson:"analysis,omitempty"`
+	CreatedAt time.Time       `json:"created_at" bson:"created_at"`
+}
+
+// AnalysisJob merepresentasikan satu antrian analisis emosi di koleksi 'analysis_jobs'.
+// Satu entri diary hanya punya satu job; Revision naik setiap kali entri di-enqueue ulang
+// sehingga hasil worker untuk konten lama tidak menimpa job yang lebih baru.
+type AnalysisJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	EntryID     primitive.ObjectID `bson:"entry_id"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	Status      string             `bson:"status"`
+	Revision    int64              `bson:"revision"`
+	Attempts    int                `bson:"attempts"`
+	LastError   string             `bson:"last_error,omitempty"`
+	NextRunAt   time.Time          `bson:"next_run_at"`
+	LockedUntil time.Time          `bson:"locked_until,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import "strings"
+
+// Emotion adalah label emosi kanonik yang disimpan di entri diary
+type Emotion string
+
+const (
+	EmotionSenang      Emotion = "senang"
+	EmotionSedih       Emotion = "sedih"
+	EmotionMarah       Emotion = "marah"
+	EmotionTakut       Emotion = "takut"
+	EmotionMengantuk   Emotion = "mengantuk"
+	EmotionBerpikir    Emotion = "berpikir"
+	EmotionCinta       Emotion = "cinta"
+	EmotionPercayaDiri Emotion = "percaya_diri"
+	// EmotionUnknown hanya ditulis server saat analisis gagal atau tidak ada emosi yang terdeteksi;
+	// nilai ini tidak diterima dari client maupun user
+	EmotionUnknown Emotion = "unknown"
+)
+
+// Emotions adalah label emosi yang bisa dipilih, urutannya dipakai untuk memutus skor yang seri
+var Emotions = []Emotion{
+	EmotionSenang, EmotionSedih, EmotionMarah, EmotionTakut,
+	EmotionMengantuk, EmotionBerpikir, EmotionCinta, EmotionPercayaDiri,
+}
+
+// Sentiment adalah label sentimen kanonik yang disimpan di entri diary
+type Sentiment string
+
+const (
+	SentimentPositive Sentiment = "positive"
+	SentimentNegative Sentiment = "negative"
+	SentimentNeutral  Sentiment = "neutral"
+)
+
+// Sentiments adalah semua label sentimen yang valid
+var Sentiments = []Sentiment{SentimentPositive, SentimentNegative, SentimentNeutral}
+
+// emotionAliases adalah sinonim yang sering dikembalikan model untuk setiap label, sesuai
+// contoh di prompt analyzer
+var emotionAliases = aliasIndex(map[Emotion][]string{
+	EmotionSenang:      {"happy", "happiness", "joy", "excited", "gembira", "bahagia"},
+	EmotionSedih:       {"sad", "sadness", "crying", "disappointed", "kecewa"},
+	EmotionMarah:       {"angry", "anger", "frustrated", "annoyed", "kesal"},
+	EmotionTakut:       {"fear", "scared", "afraid", "anxious", "cemas"},
+	EmotionMengantuk:   {"tired", "sleepy", "exhausted", "lelah"},
+	EmotionBerpikir:    {"thinking", "thoughtful", "confused", "wondering"},
+	EmotionCinta:       {"love", "crush", "affection"},
+	EmotionPercayaDiri: {"confident", "confidence", "proud", "cool", "percayadiri"},
+})
+
+var sentimentAliases = aliasIndex(map[Sentiment][]string{
+	SentimentPositive: {"positif", "pos"},
+	SentimentNegative: {"negatif", "neg"},
+	SentimentNeutral:  {"netral", "neu"},
+})
+
+// aliasIndex membalik daftar sinonim per label menjadi map sinonim -> label
+func aliasIndex[T ~string](aliases map[T][]string) map[string]T {
+	index := map[string]T{}
+	for label, words := range aliases {
+		for _, word := range words {
+			index[word] = label
+		}
+	}
+	return index
+}
+
+// normalizeLabel menyeragamkan penulisan label: huruf kecil, tanpa spasi/tanda kutip di tepi,
+// dan spasi atau tanda hubung di tengah menjadi garis bawah ("Percaya Diri" -> "percaya_diri")
+func normalizeLabel(label string) string {
+	label = strings.ToLower(strings.Trim(label, " \t\r\n\"'`."))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(label)
+}
+
+// ParseEmotion menerima label emosi kanonik dengan penulisan yang longgar (huruf besar, spasi).
+// EmotionUnknown tidak termasuk label yang valid.
+func ParseEmotion(label string) (Emotion, bool) {
+	emotion := Emotion(normalizeLabel(label))
+	for _, e := range Emotions {
+		if e == emotion {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// ParseSentiment menerima label sentimen kanonik dengan penulisan yang longgar
+func ParseSentiment(label string) (Sentiment, bool) {
+	sentiment := Sentiment(normalizeLabel(label))
+	for _, s := range Sentiments {
+		if s == sentiment {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// MatchEmotion seperti ParseEmotion, tetapi juga menerima sinonim dalam bahasa Inggris atau
+// Indonesia. Dipakai untuk keluaran model, bukan untuk input user.
+func MatchEmotion(label string) (Emotion, bool) {
+	if emotion, ok := ParseEmotion(label); ok {
+		return emotion, true
+	}
+	emotion, ok := emotionAliases[strings.ReplaceAll(normalizeLabel(label), "_", "")]
+	return emotion, ok
+}
+
+// MatchSentiment seperti ParseSentiment, tetapi juga menerima sinonim
+func MatchSentiment(label string) (Sentiment, bool) {
+	if sentiment, ok := ParseSentiment(label); ok {
+		return sentiment, true
+	}
+	sentiment, ok := sentimentAliases[normalizeLabel(label)]
+	return sentiment, ok
+}
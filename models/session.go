@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session merepresentasikan satu login (keluarga refresh token) di koleksi 'sessions'.
+// Refresh token hanya disimpan dalam bentuk hash; token lama yang sudah dirotasi
+// disimpan di PreviousTokenHashes untuk mendeteksi pemakaian ulang.
+type Session struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty"`
+	UserID              primitive.ObjectID `bson:"user_id"`
+	RefreshTokenHash    string             `bson:"refresh_token_hash"`
+	PreviousTokenHashes []string           `bson:"previous_token_hashes,omitempty"`
+	UserAgent           string             `bson:"user_agent,omitempty"`
+	IP                  string             `bson:"ip,omitempty"`
+	CreatedAt           time.Time          `bson:"created_at"`
+	LastSeenAt          time.Time          `bson:"last_seen_at"`
+	ExpiresAt           time.Time          `bson:"expires_at"`
+	RevokedAt           *time.Time         `bson:"revoked_at,omitempty"`
+	RevokedReason       string             `bson:"revoked_reason,omitempty"`
+}
+
+// PasswordReset adalah token reset password sekali pakai di koleksi 'password_resets'.
+// Token hanya disimpan dalam bentuk hash.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	TokenHash string             `bson:"token_hash"`
+	RequestIP string             `bson:"request_ip,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"web-diary-be/config"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// runNormalizeLabels menjalankan perintah "normalize-labels [-dry-run]": emosi dan sentimen
+// entri diary yang belum kanonik (mis. "Senang", "happy", "positive ") ditulis ulang ke label
+// di models.Emotions dan models.Sentiments. Label yang tidak dikenal diganti "unknown"/"neutral".
+func runNormalizeLabels(db *config.DB, args []string) error {
+	flags := flag.NewFlagSet("normalize-labels", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report entries that would change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := services.NormalizeLabels(context.Background(), repositories.NewMongoDiaryRepository(db.Diaries), *dryRun)
+	if report != nil {
+		log.Printf(
+			"Label normalization (dry run: %t): %d entries scanned, %d normalized, %d invalid, %d skipped",
+			*dryRun, report.Scanned, report.Normalized, report.Invalid, report.Skipped,
+		)
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"web-diary-be/config"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+// runReencrypt menjalankan perintah "reencrypt [-rotate-data-keys]":
+//   - data key yang masih dibungkus master key lama dibungkus ulang dengan ENCRYPTION_MASTER_KEY_ID,
+//     setelah itu master key lama boleh dihapus dari ENCRYPTION_MASTER_KEYS
+//   - dengan -rotate-data-keys setiap user mendapat data key versi baru
+//   - entri dan revisi plaintext lama atau yang memakai data key lama dienkripsi ulang
+func runReencrypt(cfg *config.Config, db *config.DB, args []string) error {
+	flags := flag.NewFlagSet("reencrypt", flag.ContinueOnError)
+	rotate := flags.Bool("rotate-data-keys", false, "create a new data key version for every user before re-encrypting")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !cfg.AtRest.Enabled() {
+		return errors.New("reencrypt requires ENCRYPTION_MASTER_KEY_ID and ENCRYPTION_MASTER_KEYS")
+	}
+
+	atRest, err := services.NewAtRestCipher(cfg.AtRest, repositories.NewMongoDataKeyRepository(db.DataKeys))
+	if err != nil {
+		return err
+	}
+	report, err := atRest.Reencrypt(
+		context.Background(),
+		*rotate,
+		repositories.NewMongoDiaryRepository(db.Diaries),
+		repositories.NewMongoRevisionRepository(db.Revisions),
+	)
+	if report != nil {
+		log.Printf(
+			"Re-encryption: %d data keys rewrapped, %d rotated, %d documents re-encrypted, %d skipped",
+			report.RewrappedKeys, report.RotatedKeys, report.Documents, report.Skipped,
+		)
+	}
+	return err
+}
@@ -0,0 +1,357 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// ErrNotFound dikembalikan jika dokumen tidak ada atau bukan milik user yang meminta
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate dikembalikan jika dokumen dengan kunci unik yang sama sudah ada
+var ErrDuplicate = errors.New("duplicate")
+
+// ErrSearchUnavailable dikembalikan Search jika isi entri tidak bisa diindeks, mis. saat enkripsi at rest aktif
+var ErrSearchUnavailable = errors.New("search is unavailable while diary content is encrypted at rest")
+
+// DiaryFilter menyaring entri diary milik satu user. Field kosong berarti tidak difilter.
+type DiaryFilter struct {
+	UserID    primitive.ObjectID
+	Emotion   models.Emotion
+	Sentiment models.Sentiment
+	From      time.Time // inklusif
+	To        time.Time // eksklusif, kecuali IncludeTo
+	IncludeTo bool
+	// AnalyzedOnly hanya mengambil entri yang sudah punya emosi
+	AnalyzedOnly bool
+	// Trashed mengambil entri di tempat sampah; tanpa ini hanya entri aktif yang diambil
+	Trashed bool
+	// Tags hanya mengambil entri yang punya semua tag ini
+	Tags []string
+	// Encrypted menyaring entri terenkripsi (true) atau plaintext (false); nil berarti keduanya
+	Encrypted *bool
+}
+
+// DiaryCursor adalah posisi terakhir di listing (created_at desc, _id desc)
+type DiaryCursor struct {
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+}
+
+// DiaryUpdate berisi perubahan parsial untuk satu entri; field nil tidak diubah
+type DiaryUpdate struct {
+	Title         *string
+	Content       *string
+	ContentFormat *string
+	Tags          *[]string
+	// Encrypted mengubah mode entri; dikirim bersama judul dan isi dalam bentuk yang baru
+	Encrypted *bool
+	// Analysis menulis emosi/sentimen, asal dan status analisis persis seperti isinya
+	// (termasuk nilai dari user); field kosong dihapus
+	Analysis *DiaryAnalysis
+	// Storage menandai format penyimpanan judul/isi baru; diisi oleh lapisan enkripsi at rest
+	Storage   *StorageTag
+	UpdatedAt time.Time
+}
+
+// StorageTag adalah format penyimpanan judul/isi dan versi data key yang dipakai
+type StorageTag struct {
+	Format     int
+	KeyVersion int
+}
+
+// StoredText adalah judul dan isi satu entri atau revisi persis seperti tersimpan di database
+type StoredText struct {
+	ID      primitive.ObjectID
+	UserID  primitive.ObjectID
+	Title   string
+	Content string
+	StorageTag
+}
+
+// TextStore memberi akses mentah ke judul/isi tersimpan milik semua user (termasuk entri di
+// tempat sampah), dipakai perintah reencrypt untuk migrasi dan rotasi kunci
+type TextStore interface {
+	// ScanText mengembalikan paling banyak limit dokumen dengan _id setelah after, urut _id
+	ScanText(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredText, error)
+	// ReplaceText menulis next hanya jika judul, isi dan format tersimpan masih sama dengan prev
+	ReplaceText(ctx context.Context, prev, next StoredText) (bool, error)
+}
+
+// StoredLabels adalah emosi dan sentimen satu entri persis seperti tersimpan di database,
+// termasuk label lama yang belum kanonik
+type StoredLabels struct {
+	ID        primitive.ObjectID
+	Emotion   string
+	Sentiment string
+}
+
+// LabelStore memberi akses mentah ke label emosi/sentimen semua entri (termasuk entri di
+// tempat sampah), dipakai perintah normalize-labels untuk merapikan label lama
+type LabelStore interface {
+	// ScanLabels mengembalikan paling banyak limit entri dengan _id setelah after, urut _id
+	ScanLabels(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredLabels, error)
+	// ReplaceLabels menulis next hanya jika emosi dan sentimen tersimpan masih sama dengan prev
+	ReplaceLabels(ctx context.Context, prev, next StoredLabels) (bool, error)
+}
+
+// TextSealer mengenkripsi judul/isi sebelum disimpan dan membukanya kembali setelah dibaca.
+// Dipakai oleh EncryptedDiaryRepository dan EncryptedRevisionRepository.
+type TextSealer interface {
+	// Seal mengenkripsi text plaintext dengan data key terbaru milik text.UserID
+	Seal(ctx context.Context, text StoredText) (StoredText, error)
+	// Open mengembalikan plaintext; text yang belum dienkripsi dikembalikan apa adanya
+	Open(ctx context.Context, text StoredText) (StoredText, error)
+	// Forget menghapus semua data key milik user sehingga ciphertext tersisa tidak bisa dibuka
+	Forget(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// DiaryAnalysis adalah hasil analisis emosi yang ditulis ke entri.
+// Jika ForContent diisi, hasil hanya ditulis selama konten entri masih sama.
+// Detail dan AnalyzedAt yang kosong menghapus nilai lama di entri.
+type DiaryAnalysis struct {
+	Emotion         models.Emotion
+	EmotionSource   string
+	Sentiment       models.Sentiment
+	SentimentSource string
+	Status          string
+	Detail          *models.AnalysisDetail
+	AnalyzedAt      time.Time
+	ForContent      *string
+}
+
+// ApplyTo menyalin hasil analisis ke entri, seperti yang dilakukan Update dengan Analysis
+func (a DiaryAnalysis) ApplyTo(entry *models.DiaryEntry) {
+	entry.Emotion = a.Emotion
+	entry.EmotionSource = a.EmotionSource
+	entry.Sentiment = a.Sentiment
+	entry.SentimentSource = a.SentimentSource
+	entry.AnalysisStatus = a.Status
+	entry.AnalysisDetail = a.Detail
+	entry.AnalyzedAt = nil
+	if !a.AnalyzedAt.IsZero() {
+		analyzedAt := a.AnalyzedAt
+		entry.AnalyzedAt = &analyzedAt
+	}
+}
+
+// DiarySearchHit adalah satu hasil pencarian beserta skor relevansinya
+type DiarySearchHit struct {
+	Entry models.DiaryEntry
+	Score float64
+}
+
+// CountRow adalah jumlah entri per kunci (emosi atau sentimen)
+type CountRow struct {
+	Key   string `bson:"_id"`
+	Count int    `bson:"count"`
+}
+
+// TimelineRow adalah jumlah entri dalam satu periode timeline
+type TimelineRow struct {
+	Period   string   `bson:"_id"`
+	Total    int      `bson:"total"`
+	Positive int      `bson:"positive"`
+	Negative int      `bson:"negative"`
+	Neutral  int      `bson:"neutral"`
+	Emotions []string `bson:"emotions"`
+}
+
+// WeekdayRow adalah jumlah entri per hari ISO (1 = Senin ... 7 = Minggu) dan emosi
+type WeekdayRow struct {
+	Day     int
+	Emotion string
+	Count   int
+}
+
+// DiaryStats adalah agregasi mentah untuk statistik mood. Sentimen sudah dinormalisasi
+// ke huruf kecil dan periode timeline dihitung di zona waktu yang diminta.
+type DiaryStats struct {
+	Emotions   []CountRow
+	Sentiments []CountRow
+	Daily      []TimelineRow
+	Weekly     []TimelineRow
+	Monthly    []TimelineRow
+	Weekdays   []WeekdayRow
+}
+
+// DiaryRepository mengakses entri diary. Semua operasi yang menerima userID
+// hanya menyentuh entri milik user tersebut. Entri di tempat sampah tidak ikut
+// dibaca atau diubah kecuali lewat DiaryFilter.Trashed, Restore dan PurgeTrashed.
+type DiaryRepository interface {
+	Create(ctx context.Context, entry *models.DiaryEntry) error
+	FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.DiaryEntry, error)
+	// Get mengambil entri tanpa memeriksa pemilik, untuk proses internal seperti worker analisis
+	Get(ctx context.Context, id primitive.ObjectID) (*models.DiaryEntry, error)
+	List(ctx context.Context, filter DiaryFilter, after *DiaryCursor, limit int) ([]models.DiaryEntry, error)
+	Count(ctx context.Context, filter DiaryFilter) (int64, error)
+	Search(ctx context.Context, filter DiaryFilter, query string, offset, limit int) ([]DiarySearchHit, int64, error)
+	Stats(ctx context.Context, filter DiaryFilter, loc *time.Location) (*DiaryStats, error)
+	Update(ctx context.Context, userID, id primitive.ObjectID, update DiaryUpdate) (*models.DiaryEntry, error)
+	// SetAnalysis menulis hasil analisis dan melaporkan apakah entri diperbarui. Emosi atau
+	// sentimen yang diisi user (models.SourceUser) tidak ditimpa.
+	SetAnalysis(ctx context.Context, id primitive.ObjectID, analysis DiaryAnalysis) (bool, error)
+	// Trash memindahkan entri aktif ke tempat sampah dan melaporkan apakah entri ditemukan
+	Trash(ctx context.Context, userID, id primitive.ObjectID, at time.Time) (bool, error)
+	// Restore mengembalikan entri yang masuk tempat sampah pada atau setelah since. Entri
+	// yang lebih lama sudah lewat masa retensi dan menunggu dihapus permanen.
+	Restore(ctx context.Context, userID, id primitive.ObjectID, since time.Time) (*models.DiaryEntry, error)
+	// ExpiredTrash mengembalikan id entri yang masuk tempat sampah sebelum waktu tersebut
+	ExpiredTrash(ctx context.Context, before time.Time) ([]primitive.ObjectID, error)
+	// PurgeTrashed menghapus permanen satu entri jika masih di tempat sampah sejak sebelum
+	// waktu tersebut, dan melaporkan apakah entri dihapus
+	PurgeTrashed(ctx context.Context, id primitive.ObjectID, before time.Time) (bool, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// TagCounts menghitung pemakaian setiap tag di entri aktif milik user, terbanyak lebih dulu
+	TagCounts(ctx context.Context, userID primitive.ObjectID) ([]CountRow, error)
+	// ReplaceTags mengganti tag from dengan into di semua entri user (termasuk tempat sampah)
+	// dan mengembalikan jumlah entri yang berubah; into kosong berarti tag dihapus
+	ReplaceTags(ctx context.Context, userID primitive.ObjectID, from []string, into string) (int64, error)
+	TextStore
+	LabelStore
+}
+
+// RevisionRepository menyimpan versi lama entri diary. Operasi yang menerima userID
+// hanya menyentuh revisi milik user tersebut.
+type RevisionRepository interface {
+	Create(ctx context.Context, revision *models.DiaryRevision) error
+	// ListByEntry mengembalikan revisi satu entri, terbaru lebih dulu
+	ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.DiaryRevision, error)
+	FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.DiaryRevision, error)
+	// Prune hanya menyisakan keep revisi terbaru dari satu entri
+	Prune(ctx context.Context, entryID primitive.ObjectID, keep int) (int64, error)
+	DeleteByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (int64, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	TextStore
+}
+
+// DataKeyRepository menyimpan data key enkripsi at rest milik setiap user dalam bentuk terbungkus
+type DataKeyRepository interface {
+	// Create gagal dengan ErrDuplicate jika user sudah punya data key dengan versi yang sama
+	Create(ctx context.Context, key *models.DataKey) error
+	// Latest mengembalikan data key dengan versi terbesar milik user
+	Latest(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error)
+	Find(ctx context.Context, userID primitive.ObjectID, version int) (*models.DataKey, error)
+	// Scan mengembalikan paling banyak limit data key dengan _id setelah after, urut _id
+	Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]models.DataKey, error)
+	// Rewrap mengganti kunci terbungkus hanya jika kunci masih dibungkus oleh master key prevMasterKeyID
+	Rewrap(ctx context.Context, id primitive.ObjectID, prevMasterKeyID, masterKeyID string, wrapped []byte) (bool, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// AnalysisFeedbackRepository menyimpan koreksi user atas hasil analisis emosi
+type AnalysisFeedbackRepository interface {
+	Create(ctx context.Context, feedback *models.AnalysisFeedback) error
+	// Scan mengembalikan paling banyak limit feedback dengan _id setelah after, urut _id
+	Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]models.AnalysisFeedback, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// AttachmentRepository menyimpan metadata lampiran entri diary. Operasi yang menerima
+// userID hanya menyentuh lampiran milik user tersebut.
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	// ListByEntry mengembalikan lampiran satu entri sesuai urutan upload
+	ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.Attachment, error)
+	CountByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error)
+	FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.Attachment, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// ListByEntries dan ListByUser dipakai untuk membersihkan blob sebelum metadata dihapus
+	ListByEntries(ctx context.Context, entryIDs []primitive.ObjectID) ([]models.Attachment, error)
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Attachment, error)
+	DeleteByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (int64, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// UserUpdate berisi perubahan parsial untuk profil user; field nil tidak diubah
+type UserUpdate struct {
+	Username      *string
+	Email         *string
+	Password      *string // sudah di-hash
+	EmailVerified *bool
+	UpdatedAt     time.Time
+}
+
+// UserRepository mengakses akun user. Email disimpan dan dicari dalam huruf kecil.
+type UserRepository interface {
+	// Create dan Update gagal dengan ErrDuplicate jika email sudah dipakai akun lain
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, id primitive.ObjectID, update UserUpdate) (*models.User, error)
+	// SetVerificationNonce mengganti nonce verifikasi milik user yang belum terverifikasi
+	SetVerificationNonce(ctx context.Context, id primitive.ObjectID, nonce string) error
+	// MarkEmailVerified memverifikasi email jika nonce cocok dan melaporkan apakah berhasil
+	MarkEmailVerified(ctx context.Context, id primitive.ObjectID, nonce string, at time.Time) (bool, error)
+	// SetEncryption mengganti kunci enkripsi end-to-end hanya jika versi kunci saat ini masih
+	// currentVersion (0 berarti belum aktif) dan melaporkan apakah berhasil; key nil menonaktifkan
+	SetEncryption(ctx context.Context, id primitive.ObjectID, key *models.EncryptionKey, currentVersion int) (bool, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+}
+
+// SessionRepository menyimpan sesi login beserta hash refresh token-nya
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	// FindByRefreshHash mencari sesi (aktif atau tidak) pemilik hash refresh token saat ini
+	FindByRefreshHash(ctx context.Context, hash string) (*models.Session, error)
+	// FindByPreviousHash mencari sesi yang pernah memakai hash refresh token yang sudah dirotasi
+	FindByPreviousHash(ctx context.Context, hash string) (*models.Session, error)
+	// Rotate mengganti hash refresh token hanya jika hash lama masih berlaku dan sesi belum dicabut
+	Rotate(ctx context.Context, id primitive.ObjectID, oldHash string, rotation SessionRotation) (bool, error)
+	Touch(ctx context.Context, id primitive.ObjectID, userAgent, ip string, at, staleBefore time.Time) error
+	IsActive(ctx context.Context, id, userID primitive.ObjectID, now time.Time) (bool, error)
+	ListActive(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error)
+	// Revoke mencabut sesi; userID kosong berarti pemilik tidak diperiksa
+	Revoke(ctx context.Context, userID, id primitive.ObjectID, reason string, at time.Time) (bool, error)
+	// RevokeAll mencabut semua sesi user kecuali keepID (boleh kosong)
+	RevokeAll(ctx context.Context, userID, keepID primitive.ObjectID, reason string, at time.Time) (int64, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// SessionRotation berisi nilai baru sesi setelah refresh token dirotasi
+type SessionRotation struct {
+	NewHash   string
+	UserAgent string
+	IP        string
+	At        time.Time
+	ExpiresAt time.Time
+	// KeepPrevious adalah jumlah hash lama yang disimpan untuk deteksi reuse
+	KeepPrevious int
+}
+
+// AnalysisJobRepository menyimpan antrian analisis emosi, satu job per entri diary
+type AnalysisJobRepository interface {
+	// Enqueue membuat job pending untuk entri atau me-reset job yang sudah ada dengan revision baru
+	Enqueue(ctx context.Context, entryID, userID primitive.ObjectID, at time.Time) error
+	// Claim mengunci satu job yang siap dijalankan; nil jika tidak ada
+	Claim(ctx context.Context, now, lockedUntil time.Time) (*models.AnalysisJob, error)
+	// Update memperbarui job hanya jika revision-nya belum berubah sejak diklaim
+	Update(ctx context.Context, id primitive.ObjectID, revision int64, update AnalysisJobUpdate) (bool, error)
+	Delete(ctx context.Context, id primitive.ObjectID, revision int64) error
+	DeleteByEntry(ctx context.Context, entryID primitive.ObjectID) error
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// AnalysisJobUpdate berisi perubahan status job; kunci worker selalu dilepas
+type AnalysisJobUpdate struct {
+	Status    string
+	Attempts  *int
+	LastError *string
+	NextRunAt *time.Time
+	UpdatedAt time.Time
+}
+
+// PasswordResetRepository menyimpan token reset password (dalam bentuk hash)
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	// InvalidateForUser menandai semua token user yang belum dipakai sebagai terpakai
+	InvalidateForUser(ctx context.Context, userID primitive.ObjectID, at time.Time) error
+	// Consume memakai token yang belum dipakai dan belum kedaluwarsa secara atomik
+	Consume(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
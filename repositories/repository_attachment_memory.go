@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryAttachmentRepository menyimpan metadata lampiran di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryAttachmentRepository struct {
+	mu          sync.Mutex
+	attachments map[primitive.ObjectID]models.Attachment
+}
+
+func NewMemoryAttachmentRepository() *MemoryAttachmentRepository {
+	return &MemoryAttachmentRepository{attachments: map[primitive.ObjectID]models.Attachment{}}
+}
+
+func (r *MemoryAttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attachment.ID.IsZero() {
+		attachment.ID = primitive.NewObjectID()
+	}
+	r.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+// where mengembalikan lampiran yang cocok sesuai urutan upload. Pemanggil memegang r.mu.
+func (r *MemoryAttachmentRepository) where(match func(*models.Attachment) bool) []models.Attachment {
+	attachments := []models.Attachment{}
+	for _, attachment := range r.attachments {
+		if match(&attachment) {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].ID.Hex() < attachments[j].ID.Hex() })
+	return attachments
+}
+
+func (r *MemoryAttachmentRepository) ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.where(func(a *models.Attachment) bool { return a.EntryID == entryID && a.UserID == userID }), nil
+}
+
+func (r *MemoryAttachmentRepository) CountByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.where(func(a *models.Attachment) bool { return a.EntryID == entryID }))), nil
+}
+
+func (r *MemoryAttachmentRepository) FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attachment, ok := r.attachments[id]
+	if !ok || attachment.EntryID != entryID || attachment.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &attachment, nil
+}
+
+func (r *MemoryAttachmentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attachments, id)
+	return nil
+}
+
+func (r *MemoryAttachmentRepository) ListByEntries(ctx context.Context, entryIDs []primitive.ObjectID) ([]models.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.where(func(a *models.Attachment) bool { return containsID(entryIDs, a.EntryID) }), nil
+}
+
+func (r *MemoryAttachmentRepository) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.where(func(a *models.Attachment) bool { return a.UserID == userID }), nil
+}
+
+func (r *MemoryAttachmentRepository) DeleteByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (int64, error) {
+	return r.deleteWhere(func(a *models.Attachment) bool { return containsID(entryIDs, a.EntryID) }), nil
+}
+
+func (r *MemoryAttachmentRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.deleteWhere(func(a *models.Attachment) bool { return a.UserID == userID }), nil
+}
+
+func (r *MemoryAttachmentRepository) deleteWhere(match func(*models.Attachment) bool) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, attachment := range r.attachments {
+		if match(&attachment) {
+			delete(r.attachments, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoAttachmentRepository menyimpan metadata lampiran di koleksi 'attachments'
+type MongoAttachmentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAttachmentRepository(collection *mongo.Collection) *MongoAttachmentRepository {
+	return &MongoAttachmentRepository{collection: collection}
+}
+
+func (r *MongoAttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	if attachment.ID.IsZero() {
+		attachment.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, attachment)
+	return err
+}
+
+func (r *MongoAttachmentRepository) find(ctx context.Context, filter bson.M) ([]models.Attachment, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attachments := []models.Attachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *MongoAttachmentRepository) ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.Attachment, error) {
+	return r.find(ctx, bson.M{"entry_id": entryID, "user_id": userID})
+}
+
+func (r *MongoAttachmentRepository) CountByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"entry_id": entryID})
+}
+
+func (r *MongoAttachmentRepository) FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "entry_id": entryID, "user_id": userID}).Decode(&attachment)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *MongoAttachmentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *MongoAttachmentRepository) ListByEntries(ctx context.Context, entryIDs []primitive.ObjectID) ([]models.Attachment, error) {
+	if len(entryIDs) == 0 {
+		return nil, nil
+	}
+	return r.find(ctx, bson.M{"entry_id": bson.M{"$in": entryIDs}})
+}
+
+func (r *MongoAttachmentRepository) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Attachment, error) {
+	return r.find(ctx, bson.M{"user_id": userID})
+}
+
+func (r *MongoAttachmentRepository) DeleteByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (int64, error) {
+	if len(entryIDs) == 0 {
+		return 0, nil
+	}
+	res, err := r.collection.DeleteMany(ctx, bson.M{"entry_id": bson.M{"$in": entryIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *MongoAttachmentRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
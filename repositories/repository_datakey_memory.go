@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryDataKeyRepository menyimpan data key terbungkus di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryDataKeyRepository struct {
+	mu   sync.Mutex
+	keys map[primitive.ObjectID]models.DataKey
+}
+
+func NewMemoryDataKeyRepository() *MemoryDataKeyRepository {
+	return &MemoryDataKeyRepository{keys: map[primitive.ObjectID]models.DataKey{}}
+}
+
+func (r *MemoryDataKeyRepository) Create(ctx context.Context, key *models.DataKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.UserID == key.UserID && existing.Version == key.Version {
+			return ErrDuplicate
+		}
+	}
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+	stored := *key
+	stored.WrappedKey = append([]byte(nil), key.WrappedKey...)
+	r.keys[key.ID] = stored
+	return nil
+}
+
+func (r *MemoryDataKeyRepository) Latest(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *models.DataKey
+	for _, key := range r.keys {
+		if key.UserID == userID && (latest == nil || key.Version > latest.Version) {
+			key := key
+			latest = &key
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *MemoryDataKeyRepository) Find(ctx context.Context, userID primitive.ObjectID, version int) (*models.DataKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.UserID == userID && key.Version == version {
+			return &key, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryDataKeyRepository) Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]models.DataKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := []models.DataKey{}
+	for _, key := range r.keys {
+		if key.ID.Hex() > after.Hex() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID.Hex() < keys[j].ID.Hex() })
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+func (r *MemoryDataKeyRepository) Rewrap(ctx context.Context, id primitive.ObjectID, prevMasterKeyID, masterKeyID string, wrapped []byte) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.MasterKeyID != prevMasterKeyID {
+		return false, nil
+	}
+	key.MasterKeyID = masterKeyID
+	key.WrappedKey = append([]byte(nil), wrapped...)
+	r.keys[id] = key
+	return true, nil
+}
+
+func (r *MemoryDataKeyRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, key := range r.keys {
+		if key.UserID == userID {
+			delete(r.keys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
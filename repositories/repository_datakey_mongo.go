@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoDataKeyRepository menyimpan data key terbungkus di koleksi 'data_keys'
+type MongoDataKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoDataKeyRepository(collection *mongo.Collection) *MongoDataKeyRepository {
+	return &MongoDataKeyRepository{collection: collection}
+}
+
+func (r *MongoDataKeyRepository) Create(ctx context.Context, key *models.DataKey) error {
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, key)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *MongoDataKeyRepository) Latest(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error) {
+	return r.findOne(ctx, bson.M{"user_id": userID}, options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}))
+}
+
+func (r *MongoDataKeyRepository) Find(ctx context.Context, userID primitive.ObjectID, version int) (*models.DataKey, error) {
+	return r.findOne(ctx, bson.M{"user_id": userID, "version": version})
+}
+
+func (r *MongoDataKeyRepository) findOne(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) (*models.DataKey, error) {
+	var key models.DataKey
+	err := r.collection.FindOne(ctx, filter, opts...).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *MongoDataKeyRepository) Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]models.DataKey, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	keys := []models.DataKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *MongoDataKeyRepository) Rewrap(ctx context.Context, id primitive.ObjectID, prevMasterKeyID, masterKeyID string, wrapped []byte) (bool, error) {
+	res, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "master_key_id": prevMasterKeyID},
+		bson.M{"$set": bson.M{"master_key_id": masterKeyID, "wrapped_key": wrapped}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoDataKeyRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// EncryptedDiaryRepository membungkus DiaryRepository lain dan mengenkripsi judul/isi entri
+// sebelum disimpan, lalu mendekripsinya kembali saat dibaca. Operasi yang tidak menyentuh
+// judul/isi diteruskan apa adanya ke repository di dalamnya.
+type EncryptedDiaryRepository struct {
+	DiaryRepository
+	sealer TextSealer
+}
+
+func NewEncryptedDiaryRepository(inner DiaryRepository, sealer TextSealer) *EncryptedDiaryRepository {
+	return &EncryptedDiaryRepository{DiaryRepository: inner, sealer: sealer}
+}
+
+// entryText mengambil judul/isi entri beserta format penyimpanannya
+func entryText(entry *models.DiaryEntry) StoredText {
+	return storedText(entry.ID, entry.UserID, entry.Title, entry.Content, entry.StorageFormat, entry.KeyVersion)
+}
+
+func setEntryText(entry *models.DiaryEntry, text StoredText) {
+	entry.Title = text.Title
+	entry.Content = text.Content
+	entry.StorageFormat = text.Format
+	entry.KeyVersion = text.KeyVersion
+}
+
+func (r *EncryptedDiaryRepository) open(ctx context.Context, entry *models.DiaryEntry) error {
+	text, err := r.sealer.Open(ctx, entryText(entry))
+	if err != nil {
+		return err
+	}
+	setEntryText(entry, text)
+	return nil
+}
+
+func (r *EncryptedDiaryRepository) Create(ctx context.Context, entry *models.DiaryEntry) error {
+	sealed := *entry
+	text, err := r.sealer.Seal(ctx, entryText(entry))
+	if err != nil {
+		return err
+	}
+	setEntryText(&sealed, text)
+	if err := r.DiaryRepository.Create(ctx, &sealed); err != nil {
+		return err
+	}
+	entry.ID = sealed.ID
+	return nil
+}
+
+func (r *EncryptedDiaryRepository) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.DiaryEntry, error) {
+	entry, err := r.DiaryRepository.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return entry, r.open(ctx, entry)
+}
+
+func (r *EncryptedDiaryRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.DiaryEntry, error) {
+	entry, err := r.DiaryRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return entry, r.open(ctx, entry)
+}
+
+func (r *EncryptedDiaryRepository) List(ctx context.Context, f DiaryFilter, after *DiaryCursor, limit int) ([]models.DiaryEntry, error) {
+	entries, err := r.DiaryRepository.List(ctx, f, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if err := r.open(ctx, &entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Search tidak didukung: database hanya menyimpan ciphertext sehingga tidak ada index yang bisa
+// dipakai, dan mendekripsi semua entri user di setiap query biayanya tidak terbatas
+func (r *EncryptedDiaryRepository) Search(ctx context.Context, f DiaryFilter, query string, offset, limit int) ([]DiarySearchHit, int64, error) {
+	return nil, 0, ErrSearchUnavailable
+}
+
+// Update mengenkripsi ulang judul dan isi bersama-sama agar keduanya selalu memakai format
+// dan versi kunci yang sama; field yang tidak diubah diambil dari entri tersimpan
+func (r *EncryptedDiaryRepository) Update(ctx context.Context, userID, id primitive.ObjectID, u DiaryUpdate) (*models.DiaryEntry, error) {
+	if u.Title != nil || u.Content != nil {
+		text := StoredText{ID: id, UserID: userID}
+		if u.Title == nil || u.Content == nil {
+			current, err := r.FindByID(ctx, userID, id)
+			if err != nil {
+				return nil, err
+			}
+			text.Title, text.Content = current.Title, current.Content
+		}
+		if u.Title != nil {
+			text.Title = *u.Title
+		}
+		if u.Content != nil {
+			text.Content = *u.Content
+		}
+
+		sealed, err := r.sealer.Seal(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		u.Title, u.Content, u.Storage = &sealed.Title, &sealed.Content, &sealed.StorageTag
+	}
+
+	entry, err := r.DiaryRepository.Update(ctx, userID, id, u)
+	if err != nil {
+		return nil, err
+	}
+	return entry, r.open(ctx, entry)
+}
+
+// SetAnalysis membandingkan ForContent dengan isi yang sudah didekripsi, lalu meneruskan
+// ciphertext tersimpan supaya repository di dalamnya tetap menolak jika isi berubah sejak itu
+func (r *EncryptedDiaryRepository) SetAnalysis(ctx context.Context, id primitive.ObjectID, a DiaryAnalysis) (bool, error) {
+	if a.ForContent != nil {
+		stored, err := r.DiaryRepository.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		text, err := r.sealer.Open(ctx, entryText(stored))
+		if err != nil {
+			return false, err
+		}
+		if text.Content != *a.ForContent {
+			return false, nil
+		}
+		a.ForContent = &stored.Content
+	}
+	return r.DiaryRepository.SetAnalysis(ctx, id, a)
+}
+
+func (r *EncryptedDiaryRepository) Restore(ctx context.Context, userID, id primitive.ObjectID, since time.Time) (*models.DiaryEntry, error) {
+	entry, err := r.DiaryRepository.Restore(ctx, userID, id, since)
+	if err != nil {
+		return nil, err
+	}
+	return entry, r.open(ctx, entry)
+}
+
+// DeleteByUser juga menghapus data key user, sehingga salinan ciphertext yang mungkin
+// tersisa (mis. di backup) tidak bisa dibuka lagi
+func (r *EncryptedDiaryRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	deleted, err := r.DiaryRepository.DeleteByUser(ctx, userID)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, r.sealer.Forget(ctx, userID)
+}
@@ -0,0 +1,589 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// Bobot field sama dengan text index 'diary_text'
+const (
+	titleSearchWeight   = 3
+	contentSearchWeight = 1
+)
+
+// MemoryDiaryRepository menyimpan entri diary di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryDiaryRepository struct {
+	mu      sync.RWMutex
+	entries map[primitive.ObjectID]models.DiaryEntry
+}
+
+func NewMemoryDiaryRepository() *MemoryDiaryRepository {
+	return &MemoryDiaryRepository{entries: map[primitive.ObjectID]models.DiaryEntry{}}
+}
+
+// matches meniru filter MongoDB dari diaryFilter
+func (f DiaryFilter) matches(entry *models.DiaryEntry) bool {
+	if entry.UserID != f.UserID || (entry.DeletedAt != nil) != f.Trashed {
+		return false
+	}
+	if f.Emotion != "" && entry.Emotion != f.Emotion {
+		return false
+	}
+	if f.Emotion == "" && f.AnalyzedOnly && entry.Emotion == "" {
+		return false
+	}
+	if f.Sentiment != "" && entry.Sentiment != f.Sentiment {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !hasTag(entry.Tags, tag) {
+			return false
+		}
+	}
+	if f.Encrypted != nil && entry.Encrypted != *f.Encrypted {
+		return false
+	}
+	if !f.From.IsZero() && entry.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() {
+		if f.IncludeTo && entry.CreatedAt.After(f.To) || !f.IncludeTo && !entry.CreatedAt.Before(f.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// newerFirst mengurutkan created_at desc, _id desc seperti listing MongoDB
+func newerFirst(a, b *models.DiaryEntry) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID.Hex() > b.ID.Hex()
+}
+
+// filtered mengembalikan salinan entri yang cocok, terbaru lebih dulu. Pemanggil memegang r.mu.
+func (r *MemoryDiaryRepository) filtered(f DiaryFilter) []models.DiaryEntry {
+	entries := []models.DiaryEntry{}
+	for _, entry := range r.entries {
+		if f.matches(&entry) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return newerFirst(&entries[i], &entries[j]) })
+	return entries
+}
+
+func (r *MemoryDiaryRepository) Create(ctx context.Context, entry *models.DiaryEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.entries[entry.ID]; exists {
+		return fmt.Errorf("diary entry %s already exists", entry.ID.Hex())
+	}
+	r.entries[entry.ID] = *entry
+	return nil
+}
+
+func (r *MemoryDiaryRepository) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.DiaryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.UserID != userID || entry.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (r *MemoryDiaryRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.DiaryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (r *MemoryDiaryRepository) List(ctx context.Context, f DiaryFilter, after *DiaryCursor, limit int) ([]models.DiaryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []models.DiaryEntry{}
+	for _, entry := range r.filtered(f) {
+		if after != nil && !newerFirst(&models.DiaryEntry{ID: after.ID, CreatedAt: after.CreatedAt}, &entry) {
+			continue
+		}
+		if len(entries) == limit {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *MemoryDiaryRepository) Count(ctx context.Context, f DiaryFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.filtered(f))), nil
+}
+
+// Search meniru $text dengan bahasa "none": kata dicocokkan utuh tanpa membedakan huruf besar,
+// minimal satu kata harus cocok, semua frasa dalam tanda kutip harus ada dan kata "-kata" tidak boleh ada.
+// Skor adalah jumlah kemunculan kata dikali bobot field.
+func (r *MemoryDiaryRepository) Search(ctx context.Context, f DiaryFilter, query string, offset, limit int) ([]DiarySearchHit, int64, error) {
+	q := parseTextQuery(query)
+
+	r.mu.RLock()
+	hits := []DiarySearchHit{}
+	for _, entry := range r.filtered(f) {
+		if score, ok := q.score(&entry); ok {
+			hits = append(hits, DiarySearchHit{Entry: entry, Score: score})
+		}
+	}
+	r.mu.RUnlock()
+
+	total, page := pageHits(hits, offset, limit)
+	return page, total, nil
+}
+
+// pageHits mengurutkan hasil pencarian berdasarkan skor lalu memotong satu halaman.
+// hits harus sudah terurut terbaru lebih dulu agar skor yang sama tetap urut created_at desc.
+func pageHits(hits []DiarySearchHit, offset, limit int) (int64, []DiarySearchHit) {
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	total := int64(len(hits))
+	if offset >= len(hits) {
+		return total, []DiarySearchHit{}
+	}
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return total, hits
+}
+
+type textQuery struct {
+	terms    []string
+	phrases  []string
+	excluded []string
+}
+
+func parseTextQuery(query string) textQuery {
+	var q textQuery
+	parts := strings.Split(strings.ToLower(query), `"`)
+	for i, part := range parts {
+		// Bagian ganjil berada di dalam tanda kutip
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				q.phrases = append(q.phrases, phrase)
+				q.terms = append(q.terms, textTokens(phrase)...)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			if strings.HasPrefix(field, "-") {
+				q.excluded = append(q.excluded, textTokens(field)...)
+			} else {
+				q.terms = append(q.terms, textTokens(field)...)
+			}
+		}
+	}
+	return q
+}
+
+func (q textQuery) score(entry *models.DiaryEntry) (float64, bool) {
+	title, content := strings.ToLower(entry.Title), strings.ToLower(entry.Content)
+	for _, phrase := range q.phrases {
+		if !strings.Contains(title, phrase) && !strings.Contains(content, phrase) {
+			return 0, false
+		}
+	}
+
+	counts := map[string]float64{}
+	for _, token := range textTokens(title) {
+		counts[token] += titleSearchWeight
+	}
+	for _, token := range textTokens(content) {
+		counts[token] += contentSearchWeight
+	}
+	for _, term := range q.excluded {
+		if counts[term] > 0 {
+			return 0, false
+		}
+	}
+
+	score := 0.0
+	for _, term := range q.terms {
+		score += counts[term]
+	}
+	return score, score > 0
+}
+
+func textTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Stats menghitung agregasi yang sama dengan pipeline $facet MongoDB
+func (r *MemoryDiaryRepository) Stats(ctx context.Context, f DiaryFilter, loc *time.Location) (*DiaryStats, error) {
+	r.mu.RLock()
+	entries := r.filtered(f)
+	r.mu.RUnlock()
+
+	emotions := map[string]int{}
+	sentiments := map[string]int{}
+	daily := map[string]*TimelineRow{}
+	weekly := map[string]*TimelineRow{}
+	monthly := map[string]*TimelineRow{}
+	type weekdayKey struct {
+		day     int
+		emotion string
+	}
+	weekdays := map[weekdayKey]int{}
+
+	// Entri diproses dari yang terlama agar urutan emosi di timeline sama dengan $push
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		sentiment, emotion := strings.ToLower(strings.TrimSpace(string(entry.Sentiment))), string(entry.Emotion)
+		emotions[emotion]++
+		sentiments[sentiment]++
+
+		local := entry.CreatedAt.In(loc)
+		year, week := local.ISOWeek()
+		addToTimeline(daily, local.Format("2006-01-02"), emotion, sentiment)
+		addToTimeline(weekly, fmt.Sprintf("%04d-W%02d", year, week), emotion, sentiment)
+		addToTimeline(monthly, local.Format("2006-01"), emotion, sentiment)
+
+		day := int(local.Weekday())
+		if day == 0 {
+			day = 7
+		}
+		weekdays[weekdayKey{day, emotion}]++
+	}
+
+	stats := &DiaryStats{
+		Emotions:   countRows(emotions),
+		Sentiments: countRows(sentiments),
+		Daily:      timelineRows(daily),
+		Weekly:     timelineRows(weekly),
+		Monthly:    timelineRows(monthly),
+	}
+	for key, count := range weekdays {
+		stats.Weekdays = append(stats.Weekdays, WeekdayRow{Day: key.day, Emotion: key.emotion, Count: count})
+	}
+	return stats, nil
+}
+
+func addToTimeline(rows map[string]*TimelineRow, period, emotion, sentiment string) {
+	row, ok := rows[period]
+	if !ok {
+		row = &TimelineRow{Period: period}
+		rows[period] = row
+	}
+	row.Total++
+	switch sentiment {
+	case "positive":
+		row.Positive++
+	case "negative":
+		row.Negative++
+	case "neutral":
+		row.Neutral++
+	}
+	row.Emotions = append(row.Emotions, emotion)
+}
+
+// countRows mengurutkan count desc lalu key asc, sama seperti facet emotions
+func countRows(counts map[string]int) []CountRow {
+	rows := make([]CountRow, 0, len(counts))
+	for key, count := range counts {
+		rows = append(rows, CountRow{Key: key, Count: count})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows
+}
+
+func timelineRows(rows map[string]*TimelineRow) []TimelineRow {
+	out := make([]TimelineRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Period < out[j].Period })
+	return out
+}
+
+func (r *MemoryDiaryRepository) Update(ctx context.Context, userID, id primitive.ObjectID, u DiaryUpdate) (*models.DiaryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.UserID != userID || entry.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if u.Title != nil {
+		entry.Title = *u.Title
+	}
+	if u.Content != nil {
+		entry.Content = *u.Content
+	}
+	if u.ContentFormat != nil {
+		entry.ContentFormat = *u.ContentFormat
+	}
+	if u.Tags != nil {
+		entry.Tags = append([]string(nil), *u.Tags...)
+	}
+	if u.Encrypted != nil {
+		entry.Encrypted = *u.Encrypted
+	}
+	if u.Storage != nil {
+		entry.StorageFormat = u.Storage.Format
+		entry.KeyVersion = u.Storage.KeyVersion
+	}
+	if u.Analysis != nil {
+		u.Analysis.ApplyTo(&entry)
+	}
+	entry.UpdatedAt = u.UpdatedAt
+	r.entries[id] = entry
+	return &entry, nil
+}
+
+func (r *MemoryDiaryRepository) SetAnalysis(ctx context.Context, id primitive.ObjectID, a DiaryAnalysis) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || a.ForContent != nil && entry.Content != *a.ForContent {
+		return false, nil
+	}
+	if entry.EmotionSource == models.SourceUser {
+		a.Emotion, a.EmotionSource = entry.Emotion, entry.EmotionSource
+	}
+	if entry.SentimentSource == models.SourceUser {
+		a.Sentiment, a.SentimentSource = entry.Sentiment, entry.SentimentSource
+	}
+	a.ApplyTo(&entry)
+	r.entries[id] = entry
+	return true, nil
+}
+
+func (r *MemoryDiaryRepository) Trash(ctx context.Context, userID, id primitive.ObjectID, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.UserID != userID || entry.DeletedAt != nil {
+		return false, nil
+	}
+	entry.DeletedAt = &at
+	r.entries[id] = entry
+	return true, nil
+}
+
+func (r *MemoryDiaryRepository) Restore(ctx context.Context, userID, id primitive.ObjectID, since time.Time) (*models.DiaryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.UserID != userID || entry.DeletedAt == nil || entry.DeletedAt.Before(since) {
+		return nil, ErrNotFound
+	}
+	entry.DeletedAt = nil
+	r.entries[id] = entry
+	return &entry, nil
+}
+
+func (r *MemoryDiaryRepository) ExpiredTrash(ctx context.Context, before time.Time) ([]primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []primitive.ObjectID
+	for id, entry := range r.entries {
+		if entry.DeletedAt != nil && entry.DeletedAt.Before(before) {
+			expired = append(expired, id)
+		}
+	}
+	return expired, nil
+}
+
+func (r *MemoryDiaryRepository) PurgeTrashed(ctx context.Context, id primitive.ObjectID, before time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.DeletedAt == nil || !entry.DeletedAt.Before(before) {
+		return false, nil
+	}
+	delete(r.entries, id)
+	return true, nil
+}
+
+func (r *MemoryDiaryRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, entry := range r.entries {
+		if entry.UserID == userID {
+			delete(r.entries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// storedText mengambil judul/isi tersimpan dari entri
+func storedText(id, userID primitive.ObjectID, title, content string, format, keyVersion int) StoredText {
+	return StoredText{ID: id, UserID: userID, Title: title, Content: content, StorageTag: StorageTag{Format: format, KeyVersion: keyVersion}}
+}
+
+func (r *MemoryDiaryRepository) ScanText(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredText, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	texts := []StoredText{}
+	for _, entry := range r.entries {
+		if entry.ID.Hex() > after.Hex() {
+			texts = append(texts, storedText(entry.ID, entry.UserID, entry.Title, entry.Content, entry.StorageFormat, entry.KeyVersion))
+		}
+	}
+	return firstTexts(texts, limit), nil
+}
+
+// firstTexts mengurutkan berdasarkan _id lalu mengambil limit dokumen pertama
+func firstTexts(texts []StoredText, limit int) []StoredText {
+	sort.Slice(texts, func(i, j int) bool { return texts[i].ID.Hex() < texts[j].ID.Hex() })
+	if len(texts) > limit {
+		texts = texts[:limit]
+	}
+	return texts
+}
+
+func (r *MemoryDiaryRepository) ReplaceText(ctx context.Context, prev, next StoredText) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[prev.ID]
+	if !ok || storedText(entry.ID, entry.UserID, entry.Title, entry.Content, entry.StorageFormat, entry.KeyVersion) != prev {
+		return false, nil
+	}
+	entry.Title = next.Title
+	entry.Content = next.Content
+	entry.StorageFormat = next.Format
+	entry.KeyVersion = next.KeyVersion
+	r.entries[prev.ID] = entry
+	return true, nil
+}
+
+func (r *MemoryDiaryRepository) ScanLabels(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredLabels, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	labels := []StoredLabels{}
+	for _, entry := range r.entries {
+		if entry.ID.Hex() > after.Hex() {
+			labels = append(labels, StoredLabels{ID: entry.ID, Emotion: string(entry.Emotion), Sentiment: string(entry.Sentiment)})
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID.Hex() < labels[j].ID.Hex() })
+	if len(labels) > limit {
+		labels = labels[:limit]
+	}
+	return labels, nil
+}
+
+func (r *MemoryDiaryRepository) ReplaceLabels(ctx context.Context, prev, next StoredLabels) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[prev.ID]
+	if !ok || string(entry.Emotion) != prev.Emotion || string(entry.Sentiment) != prev.Sentiment {
+		return false, nil
+	}
+	entry.Emotion = models.Emotion(next.Emotion)
+	entry.Sentiment = models.Sentiment(next.Sentiment)
+	r.entries[prev.ID] = entry
+	return true, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryDiaryRepository) TagCounts(ctx context.Context, userID primitive.ObjectID) ([]CountRow, error) {
+	r.mu.RLock()
+	counts := map[string]int{}
+	for _, entry := range r.entries {
+		if entry.UserID != userID || entry.DeletedAt != nil {
+			continue
+		}
+		for _, tag := range entry.Tags {
+			counts[tag]++
+		}
+	}
+	r.mu.RUnlock()
+
+	return countRows(counts), nil
+}
+
+func (r *MemoryDiaryRepository) ReplaceTags(ctx context.Context, userID primitive.ObjectID, from []string, into string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var modified int64
+	for id, entry := range r.entries {
+		if entry.UserID != userID {
+			continue
+		}
+		tags := make([]string, 0, len(entry.Tags))
+		replaced := false
+		for _, tag := range entry.Tags {
+			if hasTag(from, tag) {
+				replaced = true
+				continue
+			}
+			if tag != into {
+				tags = append(tags, tag)
+			}
+		}
+		if !replaced {
+			continue
+		}
+		if into != "" {
+			tags = append(tags, into)
+		}
+		if len(tags) == 0 {
+			tags = nil
+		}
+		entry.Tags = tags
+		r.entries[id] = entry
+		modified++
+	}
+	return modified, nil
+}
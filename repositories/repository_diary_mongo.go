@@ -0,0 +1,641 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoDiaryRepository menyimpan entri diary di koleksi 'diary_entries'
+type MongoDiaryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoDiaryRepository(collection *mongo.Collection) *MongoDiaryRepository {
+	return &MongoDiaryRepository{collection: collection}
+}
+
+// diaryFilter menerjemahkan DiaryFilter ke filter MongoDB. user_id selalu
+// dibandingkan sebagai ObjectID, sama seperti saat entri disimpan.
+func diaryFilter(f DiaryFilter) bson.M {
+	filter := bson.M{"user_id": f.UserID, "deleted_at": nil}
+	if f.Trashed {
+		filter["deleted_at"] = bson.M{"$ne": nil}
+	}
+
+	if f.Emotion != "" {
+		filter["emotion"] = f.Emotion
+	} else if f.AnalyzedOnly {
+		filter["emotion"] = bson.M{"$nin": bson.A{"", nil}}
+	}
+	if f.Sentiment != "" {
+		filter["sentiment"] = f.Sentiment
+	}
+	if len(f.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": f.Tags}
+	}
+	if f.Encrypted != nil {
+		if *f.Encrypted {
+			filter["encrypted"] = true
+		} else {
+			filter["encrypted"] = bson.M{"$ne": true}
+		}
+	}
+
+	createdAt := bson.M{}
+	if !f.From.IsZero() {
+		createdAt["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		if f.IncludeTo {
+			createdAt["$lte"] = f.To
+		} else {
+			createdAt["$lt"] = f.To
+		}
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+	return filter
+}
+
+func (r *MongoDiaryRepository) Create(ctx context.Context, entry *models.DiaryEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *MongoDiaryRepository) FindByID(ctx context.Context, userID, id primitive.ObjectID) (*models.DiaryEntry, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": nil})
+}
+
+func (r *MongoDiaryRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.DiaryEntry, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *MongoDiaryRepository) findOne(ctx context.Context, filter bson.M) (*models.DiaryEntry, error) {
+	var entry models.DiaryEntry
+	err := r.collection.FindOne(ctx, filter).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *MongoDiaryRepository) List(ctx context.Context, f DiaryFilter, after *DiaryCursor, limit int) ([]models.DiaryEntry, error) {
+	query := diaryFilter(f)
+	if after != nil {
+		query = bson.M{"$and": []bson.M{query, {"$or": []bson.M{
+			{"created_at": bson.M{"$lt": after.CreatedAt}},
+			{"created_at": after.CreatedAt, "_id": bson.M{"$lt": after.ID}},
+		}}}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.DiaryEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *MongoDiaryRepository) Count(ctx context.Context, f DiaryFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, diaryFilter(f))
+}
+
+// Search memakai text index 'diary_text' dan mengurutkan hasil berdasarkan textScore
+func (r *MongoDiaryRepository) Search(ctx context.Context, f DiaryFilter, query string, offset, limit int) ([]DiarySearchHit, int64, error) {
+	filter := diaryFilter(f)
+	filter["$text"] = bson.M{"$search": query}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		models.DiaryEntry `bson:",inline"`
+		Score             float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]DiarySearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, DiarySearchHit{Entry: row.DiaryEntry, Score: row.Score})
+	}
+	return hits, total, nil
+}
+
+// Stats menghitung seluruh statistik mood dalam satu aggregation $facet
+func (r *MongoDiaryRepository) Stats(ctx context.Context, f DiaryFilter, loc *time.Location) (*DiaryStats, error) {
+	tz := loc.String()
+
+	// Sentimen disimpan dengan kapitalisasi yang tidak konsisten, jadi dinormalisasi dulu
+	sentiment := bson.M{"$toLower": bson.M{"$trim": bson.M{"input": bson.M{"$ifNull": bson.A{"$sentiment", ""}}}}}
+	countIf := func(value string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$sentiment_norm", value}}, 1, 0}}}
+	}
+	timeline := func(format string) bson.A {
+		return bson.A{
+			bson.M{"$group": bson.M{
+				"_id":      bson.M{"$dateToString": bson.M{"format": format, "date": "$created_at", "timezone": tz}},
+				"total":    bson.M{"$sum": 1},
+				"positive": countIf("positive"),
+				"negative": countIf("negative"),
+				"neutral":  countIf("neutral"),
+				"emotions": bson.M{"$push": "$emotion"},
+			}},
+			bson.M{"$sort": bson.M{"_id": 1}},
+		}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": diaryFilter(f)},
+		bson.M{"$addFields": bson.M{"sentiment_norm": sentiment}},
+		bson.M{"$facet": bson.M{
+			"emotions": bson.A{
+				bson.M{"$group": bson.M{"_id": "$emotion", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+			"sentiments": bson.A{
+				bson.M{"$group": bson.M{"_id": "$sentiment_norm", "count": bson.M{"$sum": 1}}},
+			},
+			"daily":   timeline("%Y-%m-%d"),
+			"weekly":  timeline("%G-W%V"),
+			"monthly": timeline("%Y-%m"),
+			"weekdays": bson.A{
+				bson.M{"$group": bson.M{
+					"_id": bson.M{
+						"day":     bson.M{"$isoDayOfWeek": bson.M{"date": "$created_at", "timezone": tz}},
+						"emotion": "$emotion",
+					},
+					"count": bson.M{"$sum": 1},
+				}},
+			},
+		}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Emotions   []CountRow    `bson:"emotions"`
+		Sentiments []CountRow    `bson:"sentiments"`
+		Daily      []TimelineRow `bson:"daily"`
+		Weekly     []TimelineRow `bson:"weekly"`
+		Monthly    []TimelineRow `bson:"monthly"`
+		Weekdays   []struct {
+			Key struct {
+				Day     int    `bson:"day"`
+				Emotion string `bson:"emotion"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		} `bson:"weekdays"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	stats := &DiaryStats{}
+	if len(result) == 0 {
+		return stats, nil
+	}
+	stats.Emotions = result[0].Emotions
+	stats.Sentiments = result[0].Sentiments
+	stats.Daily = result[0].Daily
+	stats.Weekly = result[0].Weekly
+	stats.Monthly = result[0].Monthly
+	for _, row := range result[0].Weekdays {
+		stats.Weekdays = append(stats.Weekdays, WeekdayRow{Day: row.Key.Day, Emotion: row.Key.Emotion, Count: row.Count})
+	}
+	return stats, nil
+}
+
+func (r *MongoDiaryRepository) Update(ctx context.Context, userID, id primitive.ObjectID, u DiaryUpdate) (*models.DiaryEntry, error) {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Title != nil {
+		set["title"] = *u.Title
+	}
+	if u.Content != nil {
+		set["content"] = *u.Content
+	}
+	if u.ContentFormat != nil {
+		set["content_format"] = *u.ContentFormat
+	}
+	unset := bson.M{}
+	if u.Tags != nil {
+		if len(*u.Tags) > 0 {
+			set["tags"] = *u.Tags
+		} else {
+			unset["tags"] = ""
+		}
+	}
+	if u.Encrypted != nil {
+		if *u.Encrypted {
+			set["encrypted"] = true
+		} else {
+			unset["encrypted"] = ""
+		}
+	}
+	if u.Storage != nil {
+		if u.Storage.Format != models.StoragePlaintext {
+			set["storage_format"] = u.Storage.Format
+			set["key_version"] = u.Storage.KeyVersion
+		} else {
+			unset["storage_format"] = ""
+			unset["key_version"] = ""
+		}
+	}
+	if u.Analysis != nil {
+		analysisFields(set, unset, *u.Analysis)
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var entry models.DiaryEntry
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "user_id": userID, "deleted_at": nil},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *MongoDiaryRepository) SetAnalysis(ctx context.Context, id primitive.ObjectID, a DiaryAnalysis) (bool, error) {
+	filter := bson.M{"_id": id}
+	if a.ForContent != nil {
+		filter["content"] = *a.ForContent
+	}
+	// Update berbentuk pipeline agar nilai dari user bisa dipertahankan secara atomik
+	set := bson.M{
+		"analysis_status": bson.M{"$literal": a.Status},
+		"analysis":        literalOrRemove(a.Detail, a.Detail == nil),
+		"analyzed_at":     literalOrRemove(a.AnalyzedAt, a.AnalyzedAt.IsZero()),
+	}
+	for field, value := range map[string][2]string{
+		"emotion":   {string(a.Emotion), a.EmotionSource},
+		"sentiment": {string(a.Sentiment), a.SentimentSource},
+	} {
+		fromUser := bson.M{"$eq": bson.A{"$" + field + "_source", models.SourceUser}}
+		set[field] = bson.M{"$cond": bson.A{fromUser, "$" + field, literalOrRemove(value[0], value[0] == "")}}
+		set[field+"_source"] = bson.M{"$cond": bson.A{fromUser, "$" + field + "_source", literalOrRemove(value[1], value[1] == "")}}
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: set}}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// literalOrRemove dipakai di update pipeline: nilai ditulis apa adanya, atau field dihapus
+func literalOrRemove(value any, remove bool) any {
+	if remove {
+		return "$$REMOVE"
+	}
+	return bson.M{"$literal": value}
+}
+
+// analysisFields mengisi $set/$unset untuk hasil analisis; field kosong dihapus dari dokumen
+func analysisFields(set, unset bson.M, a DiaryAnalysis) {
+	set["analysis_status"] = a.Status
+	for field, value := range map[string]string{
+		"emotion":          string(a.Emotion),
+		"emotion_source":   a.EmotionSource,
+		"sentiment":        string(a.Sentiment),
+		"sentiment_source": a.SentimentSource,
+	} {
+		if value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	if a.Detail != nil {
+		set["analysis"] = a.Detail
+	} else {
+		unset["analysis"] = ""
+	}
+	if !a.AnalyzedAt.IsZero() {
+		set["analyzed_at"] = a.AnalyzedAt
+	} else {
+		unset["analyzed_at"] = ""
+	}
+}
+
+func (r *MongoDiaryRepository) Trash(ctx context.Context, userID, id primitive.ObjectID, at time.Time) (bool, error) {
+	res, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "user_id": userID, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoDiaryRepository) Restore(ctx context.Context, userID, id primitive.ObjectID, since time.Time) (*models.DiaryEntry, error) {
+	var entry models.DiaryEntry
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "user_id": userID, "deleted_at": bson.M{"$gte": since}},
+		bson.M{"$unset": bson.M{"deleted_at": ""}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *MongoDiaryRepository) ExpiredTrash(ctx context.Context, before time.Time) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	return ids, nil
+}
+
+func (r *MongoDiaryRepository) PurgeTrashed(ctx context.Context, id primitive.ObjectID, before time.Time) (bool, error) {
+	// deleted_at diperiksa ulang agar entri yang baru saja dipulihkan tidak ikut terhapus
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *MongoDiaryRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *MongoDiaryRepository) TagCounts(ctx context.Context, userID primitive.ObjectID) ([]CountRow, error) {
+	cursor, err := r.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"user_id": userID, "deleted_at": nil, "tags.0": bson.M{"$exists": true}}},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []CountRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *MongoDiaryRepository) ReplaceTags(ctx context.Context, userID primitive.ObjectID, from []string, into string) (int64, error) {
+	filter := bson.M{"user_id": userID, "tags": bson.M{"$in": from}}
+
+	var update any = bson.M{"$pull": bson.M{"tags": bson.M{"$in": from}}}
+	if into != "" {
+		// Update pipeline agar tag lama dibuang dan tag tujuan ditambahkan tanpa duplikat dalam satu langkah
+		update = bson.A{bson.M{"$set": bson.M{"tags": bson.M{"$concatArrays": bson.A{
+			bson.M{"$filter": bson.M{
+				"input": "$tags",
+				"cond": bson.M{"$and": bson.A{
+					bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this", from}}}},
+					bson.M{"$ne": bson.A{"$$this", into}},
+				}},
+			}},
+			bson.A{into},
+		}}}}}
+	}
+
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	if into == "" {
+		// Entri yang kehilangan tag terakhirnya tidak perlu menyimpan array kosong
+		if _, err := r.collection.UpdateMany(ctx, bson.M{"user_id": userID, "tags": bson.A{}}, bson.M{"$unset": bson.M{"tags": ""}}); err != nil {
+			return 0, err
+		}
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *MongoDiaryRepository) ScanText(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredText, error) {
+	return scanText(ctx, r.collection, after, limit)
+}
+
+func (r *MongoDiaryRepository) ReplaceText(ctx context.Context, prev, next StoredText) (bool, error) {
+	return replaceText(ctx, r.collection, prev, next)
+}
+
+func (r *MongoDiaryRepository) ScanLabels(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredLabels, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	cursor, err := r.collection.Find(
+		ctx,
+		filter,
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(int64(limit)).
+			SetProjection(bson.M{"emotion": 1, "sentiment": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		Emotion   string             `bson:"emotion"`
+		Sentiment string             `bson:"sentiment"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	labels := make([]StoredLabels, 0, len(docs))
+	for _, doc := range docs {
+		labels = append(labels, StoredLabels{ID: doc.ID, Emotion: doc.Emotion, Sentiment: doc.Sentiment})
+	}
+	return labels, nil
+}
+
+// ReplaceLabels menulis label baru hanya jika entri belum berubah sejak dibaca ScanLabels
+func (r *MongoDiaryRepository) ReplaceLabels(ctx context.Context, prev, next StoredLabels) (bool, error) {
+	// Label kosong tidak disimpan (omitempty) sehingga bisa berupa "" atau tidak ada
+	stored := func(value string) any {
+		if value == "" {
+			return bson.M{"$in": bson.A{"", nil}}
+		}
+		return value
+	}
+	filter := bson.M{"_id": prev.ID, "emotion": stored(prev.Emotion), "sentiment": stored(prev.Sentiment)}
+
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range map[string]string{"emotion": next.Emotion, "sentiment": next.Sentiment} {
+		if value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// storedTextDoc adalah proyeksi judul/isi dari entri atau revisi
+type storedTextDoc struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	Title      string             `bson:"title"`
+	Content    string             `bson:"content"`
+	Format     int                `bson:"storage_format"`
+	KeyVersion int                `bson:"key_version"`
+}
+
+// scanText dipakai bersama oleh koleksi entri dan revisi
+func scanText(ctx context.Context, collection *mongo.Collection, after primitive.ObjectID, limit int) ([]StoredText, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	cursor, err := collection.Find(
+		ctx,
+		filter,
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(int64(limit)).
+			SetProjection(bson.M{"user_id": 1, "title": 1, "content": 1, "storage_format": 1, "key_version": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var docs []storedTextDoc
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	texts := make([]StoredText, 0, len(docs))
+	for _, doc := range docs {
+		texts = append(texts, storedText(doc.ID, doc.UserID, doc.Title, doc.Content, doc.Format, doc.KeyVersion))
+	}
+	return texts, nil
+}
+
+// replaceText menulis judul/isi baru hanya jika dokumen belum berubah sejak dibaca ScanText
+func replaceText(ctx context.Context, collection *mongo.Collection, prev, next StoredText) (bool, error) {
+	// Field kosong tidak disimpan (omitempty) sehingga bisa berupa "" atau tidak ada
+	stored := func(value, zero any) any {
+		if value == zero {
+			return bson.M{"$in": bson.A{zero, nil}}
+		}
+		return value
+	}
+	filter := bson.M{
+		"_id":            prev.ID,
+		"title":          stored(prev.Title, ""),
+		"content":        stored(prev.Content, ""),
+		"storage_format": stored(prev.Format, 0),
+		"key_version":    stored(prev.KeyVersion, 0),
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range map[string]any{
+		"title":          next.Title,
+		"content":        next.Content,
+		"storage_format": next.Format,
+		"key_version":    next.KeyVersion,
+	} {
+		if value == "" || value == 0 {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
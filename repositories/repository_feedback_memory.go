@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryAnalysisFeedbackRepository menyimpan koreksi hasil analisis di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryAnalysisFeedbackRepository struct {
+	mu       sync.Mutex
+	feedback map[primitive.ObjectID]models.AnalysisFeedback
+}
+
+func NewMemoryAnalysisFeedbackRepository() *MemoryAnalysisFeedbackRepository {
+	return &MemoryAnalysisFeedbackRepository{feedback: map[primitive.ObjectID]models.AnalysisFeedback{}}
+}
+
+func (r *MemoryAnalysisFeedbackRepository) Create(ctx context.Context, feedback *models.AnalysisFeedback) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if feedback.ID.IsZero() {
+		feedback.ID = primitive.NewObjectID()
+	}
+	r.feedback[feedback.ID] = *feedback
+	return nil
+}
+
+func (r *MemoryAnalysisFeedbackRepository) Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]models.AnalysisFeedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := []models.AnalysisFeedback{}
+	for _, feedback := range r.feedback {
+		if feedback.ID.Hex() > after.Hex() {
+			out = append(out, feedback)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID.Hex() < out[j].ID.Hex() })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *MemoryAnalysisFeedbackRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, feedback := range r.feedback {
+		if feedback.UserID == userID {
+			delete(r.feedback, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
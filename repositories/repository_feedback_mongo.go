@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoAnalysisFeedbackRepository menyimpan koreksi hasil analisis di koleksi 'analysis_feedback'
+type MongoAnalysisFeedbackRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAnalysisFeedbackRepository(collection *mongo.Collection) *MongoAnalysisFeedbackRepository {
+	return &MongoAnalysisFeedbackRepository{collection: collection}
+}
+
+func (r *MongoAnalysisFeedbackRepository) Create(ctx context.Context, feedback *models.AnalysisFeedback) error {
+	if feedback.ID.IsZero() {
+		feedback.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, feedback)
+	return err
+}
+
+func (r *MongoAnalysisFeedbackRepository) Scan(ctx context.Context, after primitive.ObjectID, limit int) ([]models.AnalysisFeedback, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	feedback := []models.AnalysisFeedback{}
+	if err := cursor.All(ctx, &feedback); err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+func (r *MongoAnalysisFeedbackRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
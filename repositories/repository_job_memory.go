@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryAnalysisJobRepository menyimpan antrian analisis di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryAnalysisJobRepository struct {
+	mu   sync.Mutex
+	jobs map[primitive.ObjectID]models.AnalysisJob // key: entry_id
+}
+
+func NewMemoryAnalysisJobRepository() *MemoryAnalysisJobRepository {
+	return &MemoryAnalysisJobRepository{jobs: map[primitive.ObjectID]models.AnalysisJob{}}
+}
+
+func (r *MemoryAnalysisJobRepository) Enqueue(ctx context.Context, entryID, userID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[entryID]
+	if !ok {
+		job = models.AnalysisJob{ID: primitive.NewObjectID(), EntryID: entryID, CreatedAt: at}
+	}
+	job.UserID = userID
+	job.Status = models.AnalysisPending
+	job.Attempts = 0
+	job.NextRunAt = at
+	job.UpdatedAt = at
+	job.LastError = ""
+	job.LockedUntil = time.Time{}
+	job.Revision++
+	r.jobs[entryID] = job
+	return nil
+}
+
+func (r *MemoryAnalysisJobRepository) Claim(ctx context.Context, now, lockedUntil time.Time) (*models.AnalysisJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next *models.AnalysisJob
+	for _, job := range r.jobs {
+		ready := job.Status == models.AnalysisPending && !job.NextRunAt.After(now) ||
+			job.Status == models.AnalysisProcessing && job.LockedUntil.Before(now)
+		if ready && (next == nil || job.NextRunAt.Before(next.NextRunAt)) {
+			job := job
+			next = &job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Status = models.AnalysisProcessing
+	next.LockedUntil = lockedUntil
+	next.UpdatedAt = now
+	r.jobs[next.EntryID] = *next
+	return next, nil
+}
+
+func (r *MemoryAnalysisJobRepository) find(id primitive.ObjectID, revision int64) (models.AnalysisJob, bool) {
+	for _, job := range r.jobs {
+		if job.ID == id && job.Revision == revision {
+			return job, true
+		}
+	}
+	return models.AnalysisJob{}, false
+}
+
+func (r *MemoryAnalysisJobRepository) Update(ctx context.Context, id primitive.ObjectID, revision int64, u AnalysisJobUpdate) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.find(id, revision)
+	if !ok {
+		return false, nil
+	}
+	job.Status = u.Status
+	job.UpdatedAt = u.UpdatedAt
+	job.LockedUntil = time.Time{}
+	if u.Attempts != nil {
+		job.Attempts = *u.Attempts
+	}
+	if u.LastError != nil {
+		job.LastError = *u.LastError
+	}
+	if u.NextRunAt != nil {
+		job.NextRunAt = *u.NextRunAt
+	}
+	r.jobs[job.EntryID] = job
+	return true, nil
+}
+
+func (r *MemoryAnalysisJobRepository) Delete(ctx context.Context, id primitive.ObjectID, revision int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.find(id, revision); ok {
+		delete(r.jobs, job.EntryID)
+	}
+	return nil
+}
+
+func (r *MemoryAnalysisJobRepository) DeleteByEntry(ctx context.Context, entryID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.jobs, entryID)
+	return nil
+}
+
+func (r *MemoryAnalysisJobRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for entryID, job := range r.jobs {
+		if job.UserID == userID {
+			delete(r.jobs, entryID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoAnalysisJobRepository menyimpan antrian analisis di koleksi 'analysis_jobs'
+type MongoAnalysisJobRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAnalysisJobRepository(collection *mongo.Collection) *MongoAnalysisJobRepository {
+	return &MongoAnalysisJobRepository{collection: collection}
+}
+
+func (r *MongoAnalysisJobRepository) Enqueue(ctx context.Context, entryID, userID primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"entry_id": entryID},
+		bson.M{
+			"$set": bson.M{
+				"user_id":     userID,
+				"status":      models.AnalysisPending,
+				"attempts":    0,
+				"next_run_at": at,
+				"updated_at":  at,
+			},
+			"$unset":       bson.M{"last_error": "", "locked_until": ""},
+			"$inc":         bson.M{"revision": 1},
+			"$setOnInsert": bson.M{"created_at": at},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Claim juga mengambil ulang job yang kuncinya sudah lewat (worker-nya dianggap mati)
+func (r *MongoAnalysisJobRepository) Claim(ctx context.Context, now, lockedUntil time.Time) (*models.AnalysisJob, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"status": models.AnalysisPending, "next_run_at": bson.M{"$lte": now}},
+		{"status": models.AnalysisProcessing, "locked_until": bson.M{"$lt": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"status":       models.AnalysisProcessing,
+		"locked_until": lockedUntil,
+		"updated_at":   now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.AnalysisJob
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *MongoAnalysisJobRepository) Update(ctx context.Context, id primitive.ObjectID, revision int64, u AnalysisJobUpdate) (bool, error) {
+	set := bson.M{"status": u.Status, "updated_at": u.UpdatedAt}
+	if u.Attempts != nil {
+		set["attempts"] = *u.Attempts
+	}
+	if u.LastError != nil {
+		set["last_error"] = *u.LastError
+	}
+	if u.NextRunAt != nil {
+		set["next_run_at"] = *u.NextRunAt
+	}
+	res, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revision": revision},
+		bson.M{"$set": set, "$unset": bson.M{"locked_until": ""}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoAnalysisJobRepository) Delete(ctx context.Context, id primitive.ObjectID, revision int64) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "revision": revision})
+	return err
+}
+
+func (r *MongoAnalysisJobRepository) DeleteByEntry(ctx context.Context, entryID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"entry_id": entryID})
+	return err
+}
+
+func (r *MongoAnalysisJobRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
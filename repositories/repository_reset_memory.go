@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryPasswordResetRepository menyimpan token reset di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryPasswordResetRepository struct {
+	mu     sync.Mutex
+	resets map[primitive.ObjectID]models.PasswordReset
+}
+
+func NewMemoryPasswordResetRepository() *MemoryPasswordResetRepository {
+	return &MemoryPasswordResetRepository{resets: map[primitive.ObjectID]models.PasswordReset{}}
+}
+
+func (r *MemoryPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	r.resets[reset.ID] = *reset
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, reset := range r.resets {
+		if reset.UserID == userID && reset.UsedAt == nil {
+			reset.UsedAt = &at
+			r.resets[id] = reset
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) Consume(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, reset := range r.resets {
+		if reset.TokenHash == tokenHash && reset.UsedAt == nil && reset.ExpiresAt.After(at) {
+			reset.UsedAt = &at
+			r.resets[id] = reset
+			return &reset, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPasswordResetRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, reset := range r.resets {
+		if reset.UserID == userID {
+			delete(r.resets, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoPasswordResetRepository menyimpan token reset di koleksi 'password_resets'
+type MongoPasswordResetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoPasswordResetRepository(collection *mongo.Collection) *MongoPasswordResetRepository {
+	return &MongoPasswordResetRepository{collection: collection}
+}
+
+func (r *MongoPasswordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, reset)
+	return err
+}
+
+func (r *MongoPasswordResetRepository) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": at}},
+	)
+	return err
+}
+
+// Consume menandai token terpakai dalam satu FindOneAndUpdate sehingga tidak bisa dipakai dua kali
+func (r *MongoPasswordResetRepository) Consume(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"token_hash": tokenHash, "used_at": nil, "expires_at": bson.M{"$gt": at}},
+		bson.M{"$set": bson.M{"used_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+func (r *MongoPasswordResetRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// EncryptedRevisionRepository membungkus RevisionRepository lain dan mengenkripsi judul/isi
+// revisi dengan cara yang sama seperti EncryptedDiaryRepository
+type EncryptedRevisionRepository struct {
+	RevisionRepository
+	sealer TextSealer
+}
+
+func NewEncryptedRevisionRepository(inner RevisionRepository, sealer TextSealer) *EncryptedRevisionRepository {
+	return &EncryptedRevisionRepository{RevisionRepository: inner, sealer: sealer}
+}
+
+func revisionText(revision *models.DiaryRevision) StoredText {
+	return storedText(revision.ID, revision.UserID, revision.Title, revision.Content, revision.StorageFormat, revision.KeyVersion)
+}
+
+func setRevisionText(revision *models.DiaryRevision, text StoredText) {
+	revision.Title = text.Title
+	revision.Content = text.Content
+	revision.StorageFormat = text.Format
+	revision.KeyVersion = text.KeyVersion
+}
+
+func (r *EncryptedRevisionRepository) open(ctx context.Context, revision *models.DiaryRevision) error {
+	text, err := r.sealer.Open(ctx, revisionText(revision))
+	if err != nil {
+		return err
+	}
+	setRevisionText(revision, text)
+	return nil
+}
+
+func (r *EncryptedRevisionRepository) Create(ctx context.Context, revision *models.DiaryRevision) error {
+	sealed := *revision
+	text, err := r.sealer.Seal(ctx, revisionText(revision))
+	if err != nil {
+		return err
+	}
+	setRevisionText(&sealed, text)
+	if err := r.RevisionRepository.Create(ctx, &sealed); err != nil {
+		return err
+	}
+	revision.ID = sealed.ID
+	return nil
+}
+
+func (r *EncryptedRevisionRepository) ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.DiaryRevision, error) {
+	revisions, err := r.RevisionRepository.ListByEntry(ctx, userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if err := r.open(ctx, &revisions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+func (r *EncryptedRevisionRepository) FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.DiaryRevision, error) {
+	revision, err := r.RevisionRepository.FindByID(ctx, userID, entryID, id)
+	if err != nil {
+		return nil, err
+	}
+	return revision, r.open(ctx, revision)
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryRevisionRepository menyimpan revisi entri diary di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryRevisionRepository struct {
+	mu        sync.Mutex
+	revisions map[primitive.ObjectID]models.DiaryRevision
+}
+
+func NewMemoryRevisionRepository() *MemoryRevisionRepository {
+	return &MemoryRevisionRepository{revisions: map[primitive.ObjectID]models.DiaryRevision{}}
+}
+
+func (r *MemoryRevisionRepository) Create(ctx context.Context, revision *models.DiaryRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	r.revisions[revision.ID] = *revision
+	return nil
+}
+
+// byEntry mengembalikan revisi satu entri, terbaru lebih dulu. Pemanggil memegang r.mu.
+func (r *MemoryRevisionRepository) byEntry(entryID primitive.ObjectID) []models.DiaryRevision {
+	revisions := []models.DiaryRevision{}
+	for _, revision := range r.revisions {
+		if revision.EntryID == entryID {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].ID.Hex() > revisions[j].ID.Hex() })
+	return revisions
+}
+
+func (r *MemoryRevisionRepository) ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.DiaryRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revisions := []models.DiaryRevision{}
+	for _, revision := range r.byEntry(entryID) {
+		if revision.UserID == userID {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+func (r *MemoryRevisionRepository) FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.DiaryRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revision, ok := r.revisions[id]
+	if !ok || revision.EntryID != entryID || revision.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &revision, nil
+}
+
+func (r *MemoryRevisionRepository) Prune(ctx context.Context, entryID primitive.ObjectID, keep int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pruned int64
+	revisions := r.byEntry(entryID)
+	for i := keep; i < len(revisions); i++ {
+		delete(r.revisions, revisions[i].ID)
+		pruned++
+	}
+	return pruned, nil
+}
+
+func (r *MemoryRevisionRepository) DeleteByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remove := map[primitive.ObjectID]bool{}
+	for _, id := range entryIDs {
+		remove[id] = true
+	}
+	var deleted int64
+	for id, revision := range r.revisions {
+		if remove[revision.EntryID] {
+			delete(r.revisions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryRevisionRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, revision := range r.revisions {
+		if revision.UserID == userID {
+			delete(r.revisions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryRevisionRepository) ScanText(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredText, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	texts := []StoredText{}
+	for _, revision := range r.revisions {
+		if revision.ID.Hex() > after.Hex() {
+			texts = append(texts, storedText(revision.ID, revision.UserID, revision.Title, revision.Content, revision.StorageFormat, revision.KeyVersion))
+		}
+	}
+	return firstTexts(texts, limit), nil
+}
+
+func (r *MemoryRevisionRepository) ReplaceText(ctx context.Context, prev, next StoredText) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revision, ok := r.revisions[prev.ID]
+	if !ok || storedText(revision.ID, revision.UserID, revision.Title, revision.Content, revision.StorageFormat, revision.KeyVersion) != prev {
+		return false, nil
+	}
+	revision.Title = next.Title
+	revision.Content = next.Content
+	revision.StorageFormat = next.Format
+	revision.KeyVersion = next.KeyVersion
+	r.revisions[prev.ID] = revision
+	return true, nil
+}
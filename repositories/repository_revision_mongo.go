@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoRevisionRepository menyimpan revisi entri diary di koleksi 'diary_revisions'
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRevisionRepository(collection *mongo.Collection) *MongoRevisionRepository {
+	return &MongoRevisionRepository{collection: collection}
+}
+
+func (r *MongoRevisionRepository) Create(ctx context.Context, revision *models.DiaryRevision) error {
+	if revision.ID.IsZero() {
+		revision.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, revision)
+	return err
+}
+
+// newestRevisionFirst mengurutkan berdasarkan _id karena ObjectID naik sesuai waktu pembuatan
+var newestRevisionFirst = bson.D{{Key: "_id", Value: -1}}
+
+func (r *MongoRevisionRepository) ListByEntry(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.DiaryRevision, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"entry_id": entryID, "user_id": userID},
+		options.Find().SetSort(newestRevisionFirst),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.DiaryRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *MongoRevisionRepository) FindByID(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.DiaryRevision, error) {
+	var revision models.DiaryRevision
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "entry_id": entryID, "user_id": userID}).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (r *MongoRevisionRepository) Prune(ctx context.Context, entryID primitive.ObjectID, keep int) (int64, error) {
+	// Cari revisi terbaru ke-keep; semua yang lebih lama dari itu dihapus
+	var oldestKept struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := r.collection.FindOne(
+		ctx,
+		bson.M{"entry_id": entryID},
+		options.FindOne().SetSort(newestRevisionFirst).SetSkip(int64(keep-1)).SetProjection(bson.M{"_id": 1}),
+	).Decode(&oldestKept)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.collection.DeleteMany(ctx, bson.M{"entry_id": entryID, "_id": bson.M{"$lt": oldestKept.ID}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *MongoRevisionRepository) DeleteByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (int64, error) {
+	if len(entryIDs) == 0 {
+		return 0, nil
+	}
+	res, err := r.collection.DeleteMany(ctx, bson.M{"entry_id": bson.M{"$in": entryIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *MongoRevisionRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *MongoRevisionRepository) ScanText(ctx context.Context, after primitive.ObjectID, limit int) ([]StoredText, error) {
+	return scanText(ctx, r.collection, after, limit)
+}
+
+func (r *MongoRevisionRepository) ReplaceText(ctx context.Context, prev, next StoredText) (bool, error) {
+	return replaceText(ctx, r.collection, prev, next)
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemorySessionRepository menyimpan sesi di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[primitive.ObjectID]models.Session
+}
+
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: map[primitive.ObjectID]models.Session{}}
+}
+
+// copySession menyalin slice hash lama agar pemanggil tidak berbagi memori dengan store
+func copySession(session models.Session) *models.Session {
+	session.PreviousTokenHashes = append([]string(nil), session.PreviousTokenHashes...)
+	return &session
+}
+
+func (r *MemorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	for _, existing := range r.sessions {
+		if existing.ID == session.ID || existing.RefreshTokenHash == session.RefreshTokenHash {
+			return fmt.Errorf("session %s already exists", session.ID.Hex())
+		}
+	}
+	r.sessions[session.ID] = *copySession(*session)
+	return nil
+}
+
+func (r *MemorySessionRepository) FindByRefreshHash(ctx context.Context, hash string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		if session.RefreshTokenHash == hash {
+			return copySession(session), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemorySessionRepository) FindByPreviousHash(ctx context.Context, hash string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, session := range r.sessions {
+		for _, previous := range session.PreviousTokenHashes {
+			if previous == hash {
+				return copySession(session), nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemorySessionRepository) Rotate(ctx context.Context, id primitive.ObjectID, oldHash string, rot SessionRotation) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.RefreshTokenHash != oldHash || session.RevokedAt != nil {
+		return false, nil
+	}
+	previous := append(session.PreviousTokenHashes, oldHash)
+	if len(previous) > rot.KeepPrevious {
+		previous = previous[len(previous)-rot.KeepPrevious:]
+	}
+	session.PreviousTokenHashes = append([]string(nil), previous...)
+	session.RefreshTokenHash = rot.NewHash
+	session.UserAgent = rot.UserAgent
+	session.IP = rot.IP
+	session.LastSeenAt = rot.At
+	session.ExpiresAt = rot.ExpiresAt
+	r.sessions[id] = session
+	return true, nil
+}
+
+func (r *MemorySessionRepository) Touch(ctx context.Context, id primitive.ObjectID, userAgent, ip string, at, staleBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || !session.LastSeenAt.Before(staleBefore) {
+		return nil
+	}
+	session.LastSeenAt = at
+	session.UserAgent = userAgent
+	session.IP = ip
+	r.sessions[id] = session
+	return nil
+}
+
+func (r *MemorySessionRepository) IsActive(ctx context.Context, id, userID primitive.ObjectID, now time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	return ok && session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now), nil
+}
+
+func (r *MemorySessionRepository) ListActive(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, *copySession(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+func (r *MemorySessionRepository) Revoke(ctx context.Context, userID, id primitive.ObjectID, reason string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.RevokedAt != nil || !userID.IsZero() && session.UserID != userID {
+		return false, nil
+	}
+	session.RevokedAt = &at
+	session.RevokedReason = reason
+	r.sessions[id] = session
+	return true, nil
+}
+
+func (r *MemorySessionRepository) RevokeAll(ctx context.Context, userID, keepID primitive.ObjectID, reason string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked int64
+	for id, session := range r.sessions {
+		if session.UserID != userID || session.RevokedAt != nil || id == keepID {
+			continue
+		}
+		session.RevokedAt = &at
+		session.RevokedReason = reason
+		r.sessions[id] = session
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (r *MemorySessionRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
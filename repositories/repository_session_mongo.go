@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoSessionRepository menyimpan sesi di koleksi 'sessions'
+type MongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoSessionRepository(collection *mongo.Collection) *MongoSessionRepository {
+	return &MongoSessionRepository{collection: collection}
+}
+
+func (r *MongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *MongoSessionRepository) FindByRefreshHash(ctx context.Context, hash string) (*models.Session, error) {
+	return r.findOne(ctx, bson.M{"refresh_token_hash": hash})
+}
+
+func (r *MongoSessionRepository) FindByPreviousHash(ctx context.Context, hash string) (*models.Session, error) {
+	return r.findOne(ctx, bson.M{"previous_token_hashes": hash})
+}
+
+func (r *MongoSessionRepository) findOne(ctx context.Context, filter bson.M) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, filter).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate memfilter dengan hash lama agar dua request refresh bersamaan tidak sama-sama berhasil
+func (r *MongoSessionRepository) Rotate(ctx context.Context, id primitive.ObjectID, oldHash string, rot SessionRotation) (bool, error) {
+	res, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "refresh_token_hash": oldHash, "revoked_at": nil},
+		bson.M{
+			"$set": bson.M{
+				"refresh_token_hash": rot.NewHash,
+				"user_agent":         rot.UserAgent,
+				"ip":                 rot.IP,
+				"last_seen_at":       rot.At,
+				"expires_at":         rot.ExpiresAt,
+			},
+			"$push": bson.M{"previous_token_hashes": bson.M{
+				"$each":  bson.A{oldHash},
+				"$slice": -rot.KeepPrevious,
+			}},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoSessionRepository) Touch(ctx context.Context, id primitive.ObjectID, userAgent, ip string, at, staleBefore time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "last_seen_at": bson.M{"$lt": staleBefore}},
+		bson.M{"$set": bson.M{"last_seen_at": at, "user_agent": userAgent, "ip": ip}},
+	)
+	return err
+}
+
+func (r *MongoSessionRepository) IsActive(ctx context.Context, id, userID primitive.ObjectID, now time.Time) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"_id":        id,
+		"user_id":    userID,
+		"revoked_at": nil,
+		"expires_at": bson.M{"$gt": now},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *MongoSessionRepository) ListActive(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"user_id": userID, "revoked_at": nil, "expires_at": bson.M{"$gt": now}},
+		options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *MongoSessionRepository) Revoke(ctx context.Context, userID, id primitive.ObjectID, reason string, at time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "revoked_at": nil}
+	if !userID.IsZero() {
+		filter["user_id"] = userID
+	}
+	res, err := r.collection.UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"revoked_at": at, "revoked_reason": reason}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoSessionRepository) RevokeAll(ctx context.Context, userID, keepID primitive.ObjectID, reason string, at time.Time) (int64, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": nil}
+	if !keepID.IsZero() {
+		filter["_id"] = bson.M{"$ne": keepID}
+	}
+	res, err := r.collection.UpdateMany(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"revoked_at": at, "revoked_reason": reason}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *MongoSessionRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
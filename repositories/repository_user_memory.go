@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+)
+
+// MemoryUserRepository menyimpan akun user di memori proses.
+// Dipakai untuk test dan menjalankan aplikasi tanpa MongoDB.
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[primitive.ObjectID]models.User{}}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("user %s already exists", user.ID.Hex())
+	}
+	user.Email = normalizeEmail(user.Email)
+	if r.emailTaken(user.Email, user.ID) {
+		return ErrDuplicate
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+// emailTaken meniru index unik email di MongoDB
+func (r *MemoryUserRepository) emailTaken(email string, except primitive.ObjectID) bool {
+	for id, user := range r.users {
+		if id != except && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	email = normalizeEmail(email)
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, id primitive.ObjectID, u UserUpdate) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if u.Username != nil {
+		user.Username = *u.Username
+	}
+	if u.Email != nil {
+		user.Email = normalizeEmail(*u.Email)
+		if r.emailTaken(user.Email, id) {
+			return nil, ErrDuplicate
+		}
+	}
+	if u.Password != nil {
+		user.Password = *u.Password
+	}
+	if u.EmailVerified != nil {
+		user.EmailVerified = *u.EmailVerified
+	}
+	user.UpdatedAt = u.UpdatedAt
+	r.users[id] = user
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) SetVerificationNonce(ctx context.Context, id primitive.ObjectID, nonce string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok && !user.EmailVerified {
+		user.VerificationNonce = nonce
+		r.users[id] = user
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) MarkEmailVerified(ctx context.Context, id primitive.ObjectID, nonce string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.VerificationNonce == "" || user.VerificationNonce != nonce {
+		return false, nil
+	}
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &at
+	user.UpdatedAt = at
+	user.VerificationNonce = ""
+	r.users[id] = user
+	return true, nil
+}
+
+func (r *MemoryUserRepository) SetEncryption(ctx context.Context, id primitive.ObjectID, key *models.EncryptionKey, currentVersion int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return false, nil
+	}
+	version := 0
+	if user.Encryption != nil {
+		version = user.Encryption.Version
+	}
+	if version != currentVersion {
+		return false, nil
+	}
+
+	user.Encryption = nil
+	if key != nil {
+		stored := *key
+		user.Encryption = &stored
+	}
+	r.users[id] = user
+	return true, nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return false, nil
+	}
+	delete(r.users, id)
+	return true, nil
+}
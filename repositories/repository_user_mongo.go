@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/models"
+)
+
+// MongoUserRepository menyimpan akun user di koleksi 'users'
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoUserRepository(collection *mongo.Collection) *MongoUserRepository {
+	return &MongoUserRepository{collection: collection}
+}
+
+func (r *MongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	user.Email = normalizeEmail(user.Email)
+	_, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *MongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *MongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": normalizeEmail(email)})
+}
+
+func (r *MongoUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *MongoUserRepository) Update(ctx context.Context, id primitive.ObjectID, u UserUpdate) (*models.User, error) {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Username != nil {
+		set["username"] = *u.Username
+	}
+	if u.Email != nil {
+		set["email"] = normalizeEmail(*u.Email)
+	}
+	if u.Password != nil {
+		set["password"] = *u.Password
+	}
+	if u.EmailVerified != nil {
+		set["email_verified"] = *u.EmailVerified
+	}
+
+	var user models.User
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *MongoUserRepository) SetVerificationNonce(ctx context.Context, id primitive.ObjectID, nonce string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "email_verified": false},
+		bson.M{"$set": bson.M{"verification_nonce": nonce}},
+	)
+	return err
+}
+
+// MarkEmailVerified menghapus nonce setelah dipakai sehingga token yang sama tidak bisa dipakai lagi
+func (r *MongoUserRepository) MarkEmailVerified(ctx context.Context, id primitive.ObjectID, nonce string, at time.Time) (bool, error) {
+	res, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "verification_nonce": nonce},
+		bson.M{
+			"$set":   bson.M{"email_verified": true, "email_verified_at": at, "updated_at": at},
+			"$unset": bson.M{"verification_nonce": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoUserRepository) SetEncryption(ctx context.Context, id primitive.ObjectID, key *models.EncryptionKey, currentVersion int) (bool, error) {
+	filter := bson.M{"_id": id, "encryption.version": currentVersion}
+	if currentVersion == 0 {
+		filter = bson.M{"_id": id, "encryption": nil}
+	}
+	update := bson.M{"$unset": bson.M{"encryption": ""}}
+	if key != nil {
+		update = bson.M{"$set": bson.M{"encryption": key}}
+	}
+
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (r *MongoUserRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,60 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"web-diary-be/models"
+	"web-diary-be/services"
+)
+
+func TestAnalysisDetailIsStored(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "hari ini senang")
+	s.runWorkers(t)
+
+	status, body := s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	detail, _ := body["analysis"].(map[string]any)
+	if detail == nil || body["analyzed_at"] == nil {
+		t.Fatalf("analysis detail was not stored: %v", body)
+	}
+	scores, _ := detail["scores"].(map[string]any)
+	if scores["senang"] != 0.9 || detail["confidence"] != 0.9 || detail["sentiment_score"] != 0.8 || detail["provider"] != "fake" {
+		t.Fatalf("unexpected analysis detail: %v", detail)
+	}
+}
+
+func TestLexiconAnalyzerScores(t *testing.T) {
+	analyzer := services.NewLexiconAnalyzer()
+
+	result, err := analyzer.Analyze(context.Background(), "Hari ini senang dan bangga, tapi sedikit lelah. Senang sekali!")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if result.Emotion != "senang" || result.Sentiment != "positive" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	scores := result.Detail.Scores
+	if len(scores) != len(services.EmotionLabels) || scores["senang"] != 0.5 || scores["percaya_diri"] != 0.25 || scores["mengantuk"] != 0.25 {
+		t.Fatalf("unexpected scores: %v", scores)
+	}
+	if len(result.Detail.Emotions) != 1 || result.Detail.Emotions[0] != "senang" {
+		t.Fatalf("unexpected emotion labels: %v", result.Detail.Emotions)
+	}
+	if result.Detail.SentimentScore != 0.5 || result.Detail.Confidence != 0.4 || result.Detail.Provider != "lexicon" || result.Detail.Model == "" {
+		t.Fatalf("unexpected detail: %+v", result.Detail)
+	}
+
+	// Tanpa kata emosi, hasilnya unknown dengan confidence 0
+	result, err = analyzer.Analyze(context.Background(), "rapat jam sembilan")
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if result.Emotion != models.EmotionUnknown || result.Sentiment != "neutral" || result.Detail.Confidence != 0 || len(result.Detail.Emotions) != 0 {
+		t.Fatalf("unexpected result without emotion words: %+v", result)
+	}
+}
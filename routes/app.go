@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"strings"
+
+	"web-diary-be/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// Ruang untuk boundary dan header multipart di atas ukuran file yang diunggah
+const multipartOverhead = 1 << 20
+
+// NewApp membuat aplikasi Fiber dengan pengaturan server dari cfg; dipakai main dan test
+func NewApp(cfg *config.Config) *fiber.App {
+	app := fiber.New(fiber.Config{
+		// Berlaku untuk semua route, termasuk /api/auth yang bisa diakses tanpa login
+		BodyLimit: cfg.BodyLimit,
+
+		// IP client dari header proxy hanya dipercaya jika koneksi datang dari TRUSTED_PROXIES
+		ProxyHeader:             cfg.Proxy.Header,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.Proxy.TrustedProxies,
+		EnableIPValidation:      true,
+	})
+	// Dipanggil setelah header diterima, sehingga body yang terlalu besar ditolak sebelum dibaca
+	app.Server().HeaderReceived = uploadBodyLimit(cfg)
+	return app
+}
+
+// uploadBodyLimit menaikkan batas body hanya untuk upload lampiran (POST /api/diary/:id/attachments)
+// dan impor diary (POST /api/diary/import); route lain memakai BodyLimit
+func uploadBodyLimit(cfg *config.Config) func(*fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		if !header.IsPost() {
+			return fasthttp.RequestConfig{}
+		}
+		// Routing Fiber tidak membedakan huruf besar/kecil maupun garis miring di akhir
+		path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+		segments := strings.Split(strings.Trim(strings.ToLower(path), "/"), "/")
+		if len(segments) < 3 || segments[0] != "api" || segments[1] != "diary" {
+			return fasthttp.RequestConfig{}
+		}
+
+		switch {
+		case len(segments) == 3 && segments[2] == "import":
+			return fasthttp.RequestConfig{MaxRequestBodySize: cfg.Import.MaxSize + multipartOverhead}
+		case len(segments) == 4 && segments[3] == "attachments":
+			return fasthttp.RequestConfig{MaxRequestBodySize: cfg.Attachments.MaxSize + multipartOverhead}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
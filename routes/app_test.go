@@ -0,0 +1,34 @@
+package routes_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+
+	"web-diary-be/config"
+)
+
+func TestLargeBodiesOnlyAllowedForUploads(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) { cfg.BodyLimit = 4 << 10 })
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	large := strings.Repeat("a", 16<<10)
+
+	// Server menolak body sebelum dibaca; app.Test melaporkannya sebagai error, client menerima 413
+	for _, path := range []string{"/api/auth/login", "/api/diary/"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"content":"`+large+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if _, err := s.app.Test(req, -1); !errors.Is(err, fasthttp.ErrBodyTooLarge) {
+			t.Fatalf("POST %s with a large body: err = %v, want ErrBodyTooLarge", path, err)
+		}
+	}
+
+	// Upload lampiran memakai batas ATTACHMENT_MAX_SIZE
+	id := s.createEntry(t, token, "", "dengan lampiran")
+	status, body, _ := s.upload(t, token, id, "catatan.txt", []byte(large))
+	expect(t, status, http.StatusCreated, body)
+}
@@ -0,0 +1,154 @@
+package routes_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+func masterKey(t *testing.T, id string) string {
+	t.Helper()
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatalf("random: %v", err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(raw)
+}
+
+func withAtRest(keyID string, keys ...string) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.AtRest = config.AtRestConfig{MasterKeyID: keyID, MasterKeys: keys}
+	}
+}
+
+// storedEntry mengambil entri persis seperti tersimpan, tanpa melewati lapisan enkripsi
+func (s *testServer) storedEntry(t *testing.T, id string) *models.DiaryEntry {
+	t.Helper()
+	objID, _ := primitive.ObjectIDFromHex(id)
+	entry, err := s.diaries.Get(context.Background(), objID)
+	if err != nil {
+		t.Fatalf("get stored entry %s: %v", id, err)
+	}
+	return entry
+}
+
+func TestAtRestEncryptionIsTransparent(t *testing.T) {
+	s := newTestServer(t, withAtRest("k1", masterKey(t, "k1")))
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "Pantai", "hari ini senang di pantai")
+	s.runWorkers(t)
+
+	stored := s.storedEntry(t, id)
+	if stored.StorageFormat != models.StorageAESGCM || stored.KeyVersion != 1 {
+		t.Fatalf("stored entry is tagged %d/%d, want AES-GCM with key version 1", stored.StorageFormat, stored.KeyVersion)
+	}
+	if strings.Contains(stored.Title, "Pantai") || strings.Contains(stored.Content, "pantai") {
+		t.Fatalf("stored entry holds plaintext: %+v", stored)
+	}
+
+	status, body := s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["title"] != "Pantai" || body["content"] != "hari ini senang di pantai" || body["emotion"] != "senang" {
+		t.Fatalf("entry is not decrypted or analyzed: %v", body)
+	}
+
+	// Hanya isi yang diubah; judul tetap bisa dibuka karena dienkripsi ulang bersama isi
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "besoknya sedih"})
+	expect(t, status, http.StatusOK, body)
+	if body["title"] != "Pantai" || body["content"] != "besoknya sedih" {
+		t.Fatalf("updated entry is not decrypted: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	revision := body["data"].([]any)[0].(map[string]any)
+	if revision["content"] != "hari ini senang di pantai" {
+		t.Fatalf("revision is not decrypted: %v", revision)
+	}
+
+	// Tidak ada index atas ciphertext, jadi search ditolak alih-alih mendekripsi semua entri
+	status, body = s.do(t, http.MethodGet, "/api/diary/search?q="+url.QueryEscape("sedih"), token, nil)
+	expect(t, status, http.StatusNotImplemented, body)
+}
+
+func TestReencryptMigratesAndRotatesKeys(t *testing.T) {
+	oldKey, newKey := masterKey(t, "k1"), masterKey(t, "k2")
+	s := newTestServer(t, withAtRest("k1", oldKey))
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	ctx := context.Background()
+
+	id := s.createEntry(t, token, "baru", "entri terenkripsi")
+	owner := s.storedEntry(t, id).UserID
+
+	// Entri lama dari sebelum enkripsi at rest diaktifkan
+	legacy := &models.DiaryEntry{UserID: owner, Title: "lama", Content: "entri plaintext", CreatedAt: time.Now().Add(-time.Hour)}
+	if err := s.diaries.Create(ctx, legacy); err != nil {
+		t.Fatalf("create legacy entry: %v", err)
+	}
+
+	report, err := s.atRest.Reencrypt(ctx, false, s.diaries, s.revisions)
+	if err != nil {
+		t.Fatalf("reencrypt: %v", err)
+	}
+	if report.Documents != 1 {
+		t.Fatalf("re-encrypted %d documents, want only the legacy entry", report.Documents)
+	}
+	if stored := s.storedEntry(t, legacy.ID.Hex()); stored.StorageFormat != models.StorageAESGCM || stored.Content == "entri plaintext" {
+		t.Fatalf("legacy entry was not encrypted: %+v", stored)
+	}
+
+	report, err = s.atRest.Reencrypt(ctx, true, s.diaries, s.revisions)
+	if err != nil {
+		t.Fatalf("rotate data keys: %v", err)
+	}
+	if report.RotatedKeys != 1 || report.Documents != 2 {
+		t.Fatalf("rotation report = %+v, want 1 new key and 2 documents", report)
+	}
+	if stored := s.storedEntry(t, id); stored.KeyVersion != 2 {
+		t.Fatalf("entry uses key version %d after rotation, want 2", stored.KeyVersion)
+	}
+
+	// Ganti master key: data key dibungkus ulang lalu master key lama boleh dibuang
+	rotated, err := services.NewAtRestCipher(config.AtRestConfig{MasterKeyID: "k2", MasterKeys: []string{oldKey, newKey}}, s.dataKeys)
+	if err != nil {
+		t.Fatalf("cipher with new master key: %v", err)
+	}
+	report, err = rotated.Reencrypt(ctx, false, s.diaries, s.revisions)
+	if err != nil {
+		t.Fatalf("rewrap data keys: %v", err)
+	}
+	if report.RewrappedKeys != 2 || report.Documents != 0 {
+		t.Fatalf("rewrap report = %+v, want 2 rewrapped keys and no documents", report)
+	}
+
+	fresh, err := services.NewAtRestCipher(config.AtRestConfig{MasterKeyID: "k2", MasterKeys: []string{newKey}}, s.dataKeys)
+	if err != nil {
+		t.Fatalf("cipher without old master key: %v", err)
+	}
+	for _, entryID := range []string{id, legacy.ID.Hex()} {
+		stored := s.storedEntry(t, entryID)
+		text, err := fresh.Open(ctx, repositories.StoredText{
+			ID: stored.ID, UserID: stored.UserID, Title: stored.Title, Content: stored.Content,
+			StorageTag: repositories.StorageTag{Format: stored.StorageFormat, KeyVersion: stored.KeyVersion},
+		})
+		if err != nil {
+			t.Fatalf("open %s with new master key only: %v", entryID, err)
+		}
+		if text.Content != "entri terenkripsi" && text.Content != "entri plaintext" {
+			t.Fatalf("unexpected content %q", text.Content)
+		}
+	}
+}
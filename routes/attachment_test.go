@@ -0,0 +1,260 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upload mengirim file multipart ke entri dan mengembalikan status, body dan id lampiran
+func (s *testServer) upload(t *testing.T, token, entryID, filename string, data []byte) (int, map[string]any, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/diary/"+entryID+"/attachments", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body := map[string]any{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	id := ""
+	if data, ok := body["data"].(map[string]any); ok {
+		id, _ = data["id"].(string)
+	}
+	return resp.StatusCode, body, id
+}
+
+// download mengambil isi lampiran mentah beserta response-nya
+func (s *testServer) download(t *testing.T, token, path string) (*http.Response, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("download %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	return resp, raw
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAttachmentUploadDownloadAndThumbnail(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	entryID := s.createEntry(t, token, "", "liburan senang")
+
+	picture := testPNG(t, 800, 400)
+	status, body, id := s.upload(t, token, entryID, "../../foto liburan.png", picture)
+	expect(t, status, http.StatusCreated, body)
+	data := body["data"].(map[string]any)
+	if data["filename"] != "foto liburan.png" || data["content_type"] != "image/png" {
+		t.Fatalf("unexpected attachment metadata: %v", data)
+	}
+	if data["width"] != float64(800) || data["height"] != float64(400) || data["has_thumbnail"] != true {
+		t.Fatalf("image attachment has no dimensions or thumbnail: %v", data)
+	}
+	if _, leaked := data["blob_key"]; leaked {
+		t.Fatalf("attachment exposes storage key: %v", data)
+	}
+
+	base := "/api/diary/" + entryID + "/attachments/" + id
+	resp, raw := s.download(t, token, base)
+	expect(t, resp.StatusCode, http.StatusOK, nil)
+	if !bytes.Equal(raw, picture) {
+		t.Fatalf("downloaded %d bytes, want the uploaded %d bytes", len(raw), len(picture))
+	}
+	if resp.Header.Get("X-Content-Type-Options") != "nosniff" || !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "inline") {
+		t.Fatalf("unexpected download headers: %v", resp.Header)
+	}
+
+	resp, raw = s.download(t, token, base+"/thumbnail")
+	expect(t, resp.StatusCode, http.StatusOK, nil)
+	thumb, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || format != "jpeg" || thumb.Width != 320 || thumb.Height != 160 {
+		t.Fatalf("thumbnail = %s %dx%d (err %v), want jpeg 320x160", format, thumb.Width, thumb.Height, err)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+entryID+"/attachments", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if list := body["data"].([]any); len(list) != 1 {
+		t.Fatalf("listed %d attachments, want 1", len(list))
+	}
+
+	// Lampiran lain (bukan gambar) selalu diunduh dan tidak punya thumbnail
+	status, body, textID := s.upload(t, token, entryID, "catatan.txt", []byte("catatan perjalanan"))
+	expect(t, status, http.StatusCreated, body)
+	resp, _ = s.download(t, token, "/api/diary/"+entryID+"/attachments/"+textID)
+	if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Fatalf("text attachment is served inline: %v", resp.Header)
+	}
+	resp, _ = s.download(t, token, "/api/diary/"+entryID+"/attachments/"+textID+"/thumbnail")
+	expect(t, resp.StatusCode, http.StatusNotFound, nil)
+
+	status, body = s.do(t, http.MethodDelete, base, token, nil)
+	expect(t, status, http.StatusOK, body)
+	resp, _ = s.download(t, token, base)
+	expect(t, resp.StatusCode, http.StatusNotFound, nil)
+	if n := s.blobs.Len(); n != 1 {
+		t.Fatalf("%d blobs left after deleting the image, want 1", n)
+	}
+}
+
+func TestAttachmentUploadIsValidated(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	entryID := s.createEntry(t, token, "", "hari biasa")
+
+	// Tipe ditentukan dari isi file, bukan dari nama atau header client
+	status, body, _ := s.upload(t, token, entryID, "foto.png", []byte("<html><script>alert(1)</script></html>"))
+	expect(t, status, http.StatusUnsupportedMediaType, body)
+
+	tooLarge := bytes.Repeat([]byte("a"), s.handler.Config.Attachments.MaxSize+1)
+	status, body, _ = s.upload(t, token, entryID, "besar.txt", tooLarge)
+	expect(t, status, http.StatusRequestEntityTooLarge, body)
+
+	// Entri milik user lain tidak bisa dilampiri maupun dibaca
+	other := s.signUp(t, "sari", "sari@example.com", "secret123")
+	status, body, _ = s.upload(t, other, entryID, "catatan.txt", []byte("bukan milikku"))
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body, id := s.upload(t, token, entryID, "catatan.txt", []byte("milikku"))
+	expect(t, status, http.StatusCreated, body)
+	resp, _ := s.download(t, other, "/api/diary/"+entryID+"/attachments/"+id)
+	expect(t, resp.StatusCode, http.StatusNotFound, nil)
+
+	for i := 1; i < s.handler.Config.Attachments.MaxPerEntry; i++ {
+		status, body, _ = s.upload(t, token, entryID, "catatan.txt", []byte("lagi"))
+		expect(t, status, http.StatusCreated, body)
+	}
+	status, body, _ = s.upload(t, token, entryID, "catatan.txt", []byte("kebanyakan"))
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestAttachmentsRemovedWithEntryAndAccount(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	purged := s.createEntry(t, token, "", "dihapus")
+	status, body, _ := s.upload(t, token, purged, "foto.png", testPNG(t, 10, 10))
+	expect(t, status, http.StatusCreated, body)
+	kept := s.createEntry(t, token, "", "disimpan")
+	status, body, _ = s.upload(t, token, kept, "catatan.txt", []byte("tetap ada"))
+	expect(t, status, http.StatusCreated, body)
+	if n := s.blobs.Len(); n != 3 {
+		t.Fatalf("%d blobs stored, want 3 (file, thumbnail, file)", n)
+	}
+
+	// Lampiran tetap ada selama entri masih di tempat sampah
+	status, body = s.do(t, http.MethodDelete, "/api/diary/"+purged, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if n := s.blobs.Len(); n != 3 {
+		t.Fatalf("%d blobs after trashing, want 3", n)
+	}
+	if _, err := s.purger.PurgeExpired(context.Background(), time.Now().Add(31*24*time.Hour)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if n := s.blobs.Len(); n != 1 {
+		t.Fatalf("%d blobs after purge, want 1", n)
+	}
+
+	status, body = s.do(t, http.MethodDelete, "/api/profile/me", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if n := s.blobs.Len(); n != 0 {
+		t.Fatalf("%d blobs after deleting the account, want 0", n)
+	}
+}
+
+func TestTrashPurgerRetriesFailedBlobDeletes(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "dihapus")
+	status, body, _ := s.upload(t, token, id, "foto.png", testPNG(t, 10, 10))
+	expect(t, status, http.StatusCreated, body)
+	status, body = s.do(t, http.MethodDelete, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	// Blob store gagal: entri dan lampirannya tetap ada agar dicoba lagi, bukan jadi file yatim
+	later := time.Now().Add(31 * 24 * time.Hour)
+	s.blobsDown.Store(true)
+	if n, err := s.purger.PurgeExpired(context.Background(), later); err == nil || n != 0 {
+		t.Fatalf("purged %d entries while the blob store was down (err %v)", n, err)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/trash", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 1 {
+		t.Fatalf("entry left the trash after a failed purge: %v", body)
+	}
+
+	s.blobsDown.Store(false)
+	if n, err := s.purger.PurgeExpired(context.Background(), later); err != nil || n != 1 {
+		t.Fatalf("purged %d entries on retry, want 1 (err %v)", n, err)
+	}
+	if n := s.blobs.Len(); n != 0 {
+		t.Fatalf("%d blobs left after retry, want 0", n)
+	}
+}
+
+func TestEncryptedEntryAttachmentsAreStoredOpaque(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	s.enableEncryption(t, token)
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{"content": ciphertext(t, 64), "encrypted": true})
+	expect(t, status, http.StatusCreated, body)
+	entryID := body["id"].(string)
+
+	// Isi lampiran sudah dienkripsi client, jadi tidak diperiksa tipenya dan tidak dibuatkan thumbnail
+	status, body, id := s.upload(t, token, entryID, "lampiran.bin", testPNG(t, 20, 20))
+	expect(t, status, http.StatusCreated, body)
+	data := body["data"].(map[string]any)
+	if data["content_type"] != "application/octet-stream" || data["has_thumbnail"] != false {
+		t.Fatalf("encrypted attachment was inspected: %v", data)
+	}
+
+	resp, _ := s.download(t, token, "/api/diary/"+entryID+"/attachments/"+id)
+	expect(t, resp.StatusCode, http.StatusOK, nil)
+	if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Fatalf("encrypted attachment is served inline: %v", resp.Header)
+	}
+}
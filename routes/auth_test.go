@@ -0,0 +1,197 @@
+package routes_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+func TestRegisterValidation(t *testing.T) {
+	s := newTestServer(t)
+
+	cases := []struct {
+		name string
+		body map[string]string
+	}{
+		{"invalid email", map[string]string{"username": "a", "email": "not-an-email", "password": "secret123"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := s.do(t, http.MethodPost, "/api/auth/register", "", tc.body)
+			expect(t, status, http.StatusBadRequest, body)
+		})
+	}
+}
+
+func TestRegisterDuplicateEmailIsCaseInsensitive(t *testing.T) {
+	s := newTestServer(t)
+
+	status, body := s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"username": "budi", "email": "budi@example.com", "password": "secret123",
+	})
+	expect(t, status, http.StatusOK, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"username": "budi2", "email": "BUDI@example.com", "password": "secret123",
+	})
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestMixedCaseEmailLogsInWithAnyCasing(t *testing.T) {
+	s := newTestServer(t)
+
+	// Akun lama yang tersimpan dengan huruf besar; repository menormalisasinya seperti migrasi users
+	hashed, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	legacy := &models.User{Username: "budi", Email: " Budi@Example.COM", Password: string(hashed), EmailVerified: true}
+	if err := s.users.Create(context.Background(), legacy); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	if legacy.Email != "budi@example.com" {
+		t.Fatalf("stored email = %q, want lowercase", legacy.Email)
+	}
+
+	for _, email := range []string{"Budi@Example.COM", "budi@example.com", "BUDI@EXAMPLE.COM"} {
+		s.login(t, email, "secret123")
+	}
+
+	status, body := s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"username": "budi2", "email": "budi@EXAMPLE.com", "password": "secret123",
+	})
+	expect(t, status, http.StatusBadRequest, body)
+
+	// Index unik email juga berlaku untuk penulisan yang melewati pengecekan di handler
+	err = s.users.Create(context.Background(), &models.User{Username: "budi3", Email: "BUDI@example.com"})
+	if !errors.Is(err, repositories.ErrDuplicate) {
+		t.Fatalf("duplicate email create err = %v, want ErrDuplicate", err)
+	}
+}
+
+func TestLoginAndJWT(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	t.Run("wrong password", func(t *testing.T) {
+		status, body := s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "budi@example.com", "password": "wrong-password",
+		})
+		expect(t, status, http.StatusBadRequest, body)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		status, body := s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email": "nobody@example.com", "password": "secret123",
+		})
+		expect(t, status, http.StatusBadRequest, body)
+	})
+
+	t.Run("me with token", func(t *testing.T) {
+		status, body := s.do(t, http.MethodGet, "/api/profile/me", token, nil)
+		expect(t, status, http.StatusOK, body)
+		if body["email"] != "budi@example.com" || body["email_verified"] != true {
+			t.Fatalf("unexpected profile: %v", body)
+		}
+		if _, leaked := body["password"]; leaked {
+			t.Fatalf("profile leaks password: %v", body)
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		status, body := s.do(t, http.MethodGet, "/api/profile/me", "", nil)
+		expect(t, status, http.StatusUnauthorized, body)
+	})
+
+	t.Run("tampered token", func(t *testing.T) {
+		status, body := s.do(t, http.MethodGet, "/api/diary/", token+"x", nil)
+		expect(t, status, http.StatusUnauthorized, body)
+	})
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/auth/logout", token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/profile/me", token, nil)
+	expect(t, status, http.StatusUnauthorized, body)
+}
+
+func TestPasswordResetKeepsTokenOnRejectedPassword(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	if err := s.handler.Resets.RequestPasswordReset(context.Background(), "budi@example.com", "127.0.0.1"); err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	reset := s.mailer.lastToken(t, "budi@example.com")
+
+	// Tanpa password, atau password yang ditolak bcrypt, token tidak boleh hangus
+	status, body := s.do(t, http.MethodPost, "/api/auth/reset-password", "", map[string]string{"token": reset})
+	expect(t, status, http.StatusBadRequest, body)
+	status, body = s.do(t, http.MethodPost, "/api/auth/reset-password", "", map[string]string{
+		"token": reset, "password": strings.Repeat("x", 73),
+	})
+	expect(t, status, http.StatusInternalServerError, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/auth/reset-password", "", map[string]string{
+		"token": reset, "password": "newsecret",
+	})
+	expect(t, status, http.StatusOK, body)
+	s.login(t, "budi@example.com", "newsecret")
+
+	status, body = s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": "budi@example.com", "password": "",
+	})
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, login := s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": "budi@example.com", "password": "secret123",
+	})
+	expect(t, status, http.StatusOK, login)
+	refresh := login["refresh_token"]
+
+	status, rotated := s.do(t, http.MethodPost, "/api/auth/refresh", "", map[string]any{"refresh_token": refresh})
+	expect(t, status, http.StatusOK, rotated)
+	if rotated["refresh_token"] == refresh {
+		t.Fatalf("refresh token was not rotated")
+	}
+
+	// Refresh token lama yang dipakai ulang mencabut seluruh sesi
+	status, body := s.do(t, http.MethodPost, "/api/auth/refresh", "", map[string]any{"refresh_token": refresh})
+	expect(t, status, http.StatusUnauthorized, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/profile/me", rotated["access_token"].(string), nil)
+	expect(t, status, http.StatusUnauthorized, body)
+}
+
+func TestUnverifiedUserIsReadOnly(t *testing.T) {
+	s := newTestServer(t)
+
+	status, body := s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"username": "budi", "email": "budi@example.com", "password": "secret123",
+	})
+	expect(t, status, http.StatusOK, body)
+	token := s.login(t, "budi@example.com", "secret123")
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/", token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{"content": "hari ini senang"})
+	expect(t, status, http.StatusForbidden, body)
+}
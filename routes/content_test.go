@@ -0,0 +1,121 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHTMLContentIsSanitized(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"content":        `<p onclick="steal()">Hari <b>senang</b></p><script>alert(1)</script><a href="javascript:alert(1)">x</a>`,
+		"content_format": "html",
+	})
+	expect(t, status, http.StatusCreated, body)
+	content, _ := body["content"].(string)
+	if body["content_format"] != "html" || strings.Contains(content, "script") || strings.Contains(content, "onclick") || strings.Contains(content, "javascript:") {
+		t.Fatalf("HTML content was not sanitized: %v", body)
+	}
+	if !strings.Contains(content, "<b>senang</b>") {
+		t.Fatalf("sanitizer removed safe formatting: %q", content)
+	}
+
+	// Isi yang hanya berisi markup berbahaya menjadi kosong setelah sanitasi
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"content": "<script>alert(1)</script>", "content_format": "html",
+	})
+	expect(t, status, http.StatusBadRequest, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"content": "isi", "content_format": "rtf",
+	})
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestRenderMarkdownEntry(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"title":          "Catatan",
+		"content":        "# Pagi\n\nHari ini **senang** <img src=x onerror=alert(1)>\n\n- satu\n- dua",
+		"content_format": "markdown",
+	})
+	expect(t, status, http.StatusCreated, body)
+	id := body["id"].(string)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/html", token, nil)
+	expect(t, status, http.StatusOK, body)
+	rendered, _ := body["html"].(string)
+	for _, want := range []string{"<h1", "<strong>senang</strong>", "<li>satu</li>"} {
+		if !strings.Contains(rendered, want) {
+			t.Fatalf("rendered HTML %q does not contain %q", rendered, want)
+		}
+	}
+	if strings.Contains(rendered, "onerror") || body["content_format"] != "markdown" {
+		t.Fatalf("unexpected rendered entry: %v", body)
+	}
+
+	// Teks biasa di-escape dan baris barunya dipertahankan
+	id = s.createEntry(t, token, "", "a < b\nbaris dua")
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/html", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["html"] != "<p>a &lt; b<br>\nbaris dua</p>\n" || body["content_format"] != "plain" {
+		t.Fatalf("unexpected plain rendering: %v", body)
+	}
+}
+
+func TestAnalysisReceivesPlainText(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"content": "## Hari ini\n\nAku **sedih** sekali", "content_format": "markdown",
+	})
+	expect(t, status, http.StatusCreated, body)
+	id := body["id"].(string)
+	s.runWorkers(t)
+
+	if got := s.analyzer.calls[len(s.analyzer.calls)-1]; got != "Hari ini\n\nAku sedih sekali" {
+		t.Fatalf("analyzer received %q", got)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "sedih" {
+		t.Fatalf("unexpected analysis: %v", body)
+	}
+}
+
+func TestChangeContentFormat(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "<i>senang</i> <script>x</script>")
+	s.runWorkers(t)
+
+	// Isi lama disanitasi ulang saat format diubah menjadi html
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content_format": "html"})
+	expect(t, status, http.StatusOK, body)
+	if body["content_format"] != "html" || body["content"] != "<i>senang</i>" || body["analysis_status"] != "pending" {
+		t.Fatalf("unexpected entry after format change: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	if len(data) != 1 || data[0].(map[string]any)["content_format"] != "plain" {
+		t.Fatalf("previous format was not kept as a revision: %v", body)
+	}
+
+	// Format yang sama tidak dihitung sebagai perubahan
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content_format": "HTML"})
+	expect(t, status, http.StatusOK, body)
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 1 {
+		t.Fatalf("unchanged format recorded a revision: %v", body)
+	}
+}
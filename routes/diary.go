@@ -7,31 +7,94 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func AuthRoutes(app *fiber.App) {
+func AuthRoutes(app *fiber.App, h *handlers.Handler) {
 	api := app.Group("/api/auth")
+	// Dibatasi per IP karena belum ada akun yang terautentikasi
+	api.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Store:  h.Limits,
+		Name:   "auth",
+		Max:    h.Config.RateLimit.AuthMax,
+		Window: h.Config.RateLimit.AuthWindow,
+	}))
 
-	api.Post("/register", handlers.Register)
-	api.Post("/login", handlers.Login)
-	api.Get("/logout", handlers.Logout) // logout bisa di-handle client-side
+	api.Post("/register", h.Register)
+	api.Post("/login", h.Login)
+	api.Get("/verify", h.VerifyEmail) // tautan dari email
+	api.Post("/verify", h.VerifyEmail)
+	api.Post("/resend-verification", h.ResendVerification)
+	api.Post("/forgot-password", h.ForgotPassword)
+	api.Post("/reset-password", h.ResetPassword)
+	api.Post("/refresh", h.Refresh)
+	api.Post("/logout", h.Logout)
+	api.Get("/logout", h.Logout) // kompatibilitas client lama, memakai access token di header
 }
 
-func DiaryRoutes(app *fiber.App) {
+func DiaryRoutes(app *fiber.App, h *handlers.Handler) {
 	diary := app.Group("/api/diary")
-	diary.Use(middleware.JWTProtected()) // Wajibkan token
+	diary.Use(h.Auth.JWTProtected()) // Wajibkan token
+	diary.Use(accountRateLimit(h, "diary"))
+	diary.Use(h.Auth.RequireVerifiedEmail())
 
-	diary.Post("/", handlers.CreateDiaryEntry)
-	diary.Get("/", handlers.GetDiaryEntries)
-	diary.Get("/:id", handlers.GetDiaryEntryByID)	
-	diary.Put("/:id", handlers.UpdateDiaryEntry)
-	diary.Delete("/:id", handlers.DeleteDiaryEntry)
+	diary.Post("/", h.CreateDiaryEntry)
+	diary.Get("/", h.GetDiaryEntries)
+	diary.Get("/search", h.SearchDiaryEntries) // harus sebelum /:id
+	diary.Get("/stats", h.GetDiaryStats)
+	diary.Get("/trash", h.GetTrashedEntries)
+	diary.Post("/import", h.ImportDiaryEntries)
+	diary.Post("/trash/:id/restore", h.RestoreDiaryEntry)
+	diary.Get("/:id/html", h.RenderDiaryEntry)
+	diary.Get("/:id/revisions", h.ListDiaryRevisions)
+	diary.Get("/:id/revisions/diff", h.DiffDiaryRevisions) // harus sebelum /:rid
+	diary.Get("/:id/revisions/:rid", h.GetDiaryRevision)
+	diary.Post("/:id/revisions/:rid/restore", h.RestoreDiaryRevision)
+	diary.Get("/:id/attachments", h.ListAttachments)
+	diary.Post("/:id/attachments", h.UploadAttachment)
+	diary.Get("/:id/attachments/:aid", h.DownloadAttachment)
+	diary.Get("/:id/attachments/:aid/thumbnail", h.DownloadAttachmentThumbnail)
+	diary.Delete("/:id/attachments/:aid", h.DeleteAttachment)
+	diary.Get("/:id", h.GetDiaryEntryByID)	
+	diary.Put("/:id", h.UpdateDiaryEntry)
+	diary.Delete("/:id", h.DeleteDiaryEntry)
 }
 
-func ProfileRoutes(app *fiber.App) {
+func TagRoutes(app *fiber.App, h *handlers.Handler) {
+	tags := app.Group("/api/tags")
+	tags.Use(h.Auth.JWTProtected())
+	tags.Use(accountRateLimit(h, "tags"))
+	tags.Use(h.Auth.RequireVerifiedEmail())
+
+	tags.Get("/", h.ListTags)
+	tags.Post("/merge", h.MergeTags)
+	tags.Put("/:tag", h.RenameTag)
+	tags.Delete("/:tag", h.DeleteTag)
+}
+
+func ProfileRoutes(app *fiber.App, h *handlers.Handler) {
 	profile := app.Group("/api/profile")
 
-	profile.Use(middleware.JWTProtected())
+	profile.Use(h.Auth.JWTProtected())
+	profile.Use(accountRateLimit(h, "profile"))
 
-	profile.Get("/me", handlers.Me)
-	profile.Put("/:id", handlers.UpdateProfile)
-	profile.Delete("/:id", handlers.DeleteProfile)
-}
\ No newline at end of file
+	profile.Get("/me", h.Me)
+	profile.Get("/sessions", h.ListSessions)
+	profile.Delete("/sessions", h.RevokeOtherSessions) // harus sebelum /:id
+	profile.Delete("/sessions/:sid", h.RevokeSession)
+	profile.Get("/encryption", h.GetEncryption) // harus sebelum /:id
+	profile.Post("/encryption", h.EnableEncryption)
+	profile.Put("/encryption", h.RewrapEncryptionKey)
+	profile.Delete("/encryption", h.DisableEncryption)
+	profile.Get("/export", h.ExportAccount)
+	profile.Put("/:id", h.UpdateProfile)
+	profile.Delete("/:id", h.DeleteProfile)
+}
+
+// accountRateLimit membatasi request per akun untuk group yang sudah melewati JWTProtected
+func accountRateLimit(h *handlers.Handler, name string) fiber.Handler {
+	return middleware.RateLimit(middleware.RateLimitConfig{
+		Store:   h.Limits,
+		Name:    name,
+		Max:     h.Config.RateLimit.APIMax,
+		Window:  h.Config.RateLimit.APIWindow,
+		KeyFunc: middleware.ByAccount,
+	})
+}
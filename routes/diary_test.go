@@ -0,0 +1,277 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiaryCRUD(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{"title": "kosong"})
+	expect(t, status, http.StatusBadRequest, body)
+
+	id := s.createEntry(t, token, "Senin", "hari ini aku senang sekali")
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["analysis_status"] != "pending" {
+		t.Fatalf("new entry analysis_status = %v, want pending", body["analysis_status"])
+	}
+
+	if n := s.runWorkers(t); n != 1 {
+		t.Fatalf("processed %d jobs, want 1", n)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "senang" || body["sentiment"] != "positive" || body["analysis_status"] != "done" {
+		t.Fatalf("entry not analyzed: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if data := body["data"].([]any); len(data) != 1 || body["total"] != float64(1) {
+		t.Fatalf("unexpected listing: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodDelete, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/not-an-id", token, nil)
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestDiaryListPagination(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	for _, content := range []string{"satu", "dua", "tiga"} {
+		s.createEntry(t, token, content, content)
+	}
+
+	status, first := s.do(t, http.MethodGet, "/api/diary/?limit=2", token, nil)
+	expect(t, status, http.StatusOK, first)
+	cursor, _ := first["next_cursor"].(string)
+	if len(first["data"].([]any)) != 2 || cursor == "" {
+		t.Fatalf("unexpected first page: %v", first)
+	}
+
+	status, second := s.do(t, http.MethodGet, "/api/diary/?limit=2&cursor="+cursor, token, nil)
+	expect(t, status, http.StatusOK, second)
+	data := second["data"].([]any)
+	if len(data) != 1 || second["next_cursor"] != nil {
+		t.Fatalf("unexpected second page: %v", second)
+	}
+	if data[0].(map[string]any)["content"] != "satu" {
+		t.Fatalf("last page should hold the oldest entry: %v", data)
+	}
+}
+
+func TestDiaryOwnershipIsolation(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice", "alice@example.com", "secret123")
+	bob := s.signUp(t, "bob", "bob@example.com", "secret123")
+
+	id := s.createEntry(t, alice, "rahasia", "catatan pribadi alice")
+
+	status, body := s.do(t, http.MethodGet, "/api/diary/"+id, bob, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, bob, map[string]string{"content": "diubah bob"})
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodDelete, "/api/diary/"+id, bob, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/", bob, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 0 {
+		t.Fatalf("bob can list alice's entries: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, alice, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["content"] != "catatan pribadi alice" {
+		t.Fatalf("alice's entry was modified: %v", body)
+	}
+}
+
+func TestDiaryPartialUpdate(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "judul lama", "hari ini senang")
+	s.runWorkers(t)
+
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"title": "judul baru"})
+	expect(t, status, http.StatusOK, body)
+	if body["title"] != "judul baru" || body["content"] != "hari ini senang" {
+		t.Fatalf("partial update changed other fields: %v", body)
+	}
+	// Mengganti judul saja tidak memicu analisis ulang
+	if body["emotion"] != "senang" || body["analysis_status"] != "done" {
+		t.Fatalf("title update reset the analysis: %v", body)
+	}
+	if n := s.runWorkers(t); n != 0 {
+		t.Fatalf("title update enqueued %d analysis jobs", n)
+	}
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{})
+	expect(t, status, http.StatusBadRequest, body)
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": ""})
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestDiaryReanalysisOnContentChange(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "hari ini senang")
+	s.runWorkers(t)
+
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "ternyata sedih"})
+	expect(t, status, http.StatusOK, body)
+	if body["analysis_status"] != "pending" || body["emotion"] != nil || body["sentiment"] != nil || body["analysis"] != nil || body["analyzed_at"] != nil {
+		t.Fatalf("content change did not reset the analysis: %v", body)
+	}
+
+	if n := s.runWorkers(t); n != 1 {
+		t.Fatalf("processed %d jobs after content change, want 1", n)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "sedih" || body["sentiment"] != "negative" || body["analysis_status"] != "done" {
+		t.Fatalf("entry was not re-analyzed: %v", body)
+	}
+
+	// Konten yang sama tidak perlu dianalisis ulang
+	calls := s.analyzer.callCount()
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "ternyata sedih"})
+	expect(t, status, http.StatusOK, body)
+	s.runWorkers(t)
+	if s.analyzer.callCount() != calls {
+		t.Fatalf("unchanged content was analyzed again")
+	}
+}
+
+func TestDiaryTrashAndRestore(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "hari ini senang")
+
+	status, body := s.do(t, http.MethodDelete, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	// Entri di tempat sampah tidak muncul di listing, detail maupun bisa diubah
+	status, body = s.do(t, http.MethodGet, "/api/diary/", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 0 {
+		t.Fatalf("trashed entry is still listed: %v", body)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusNotFound, body)
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"title": "baru"})
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/trash", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	if len(data) != 1 || data[0].(map[string]any)["deleted_at"] == nil {
+		t.Fatalf("unexpected trash listing: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/trash", other, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 0 {
+		t.Fatalf("trash leaks entries of other users: %v", body)
+	}
+	status, body = s.do(t, http.MethodPost, "/api/diary/trash/"+id+"/restore", other, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/trash/"+id+"/restore", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["deleted_at"] != nil {
+		t.Fatalf("restored entry still has deleted_at: %v", body)
+	}
+
+	// Job analisis yang dibuang saat dihapus dijadwalkan ulang setelah dipulihkan
+	if n := s.runWorkers(t); n != 1 {
+		t.Fatalf("processed %d jobs after restore, want 1", n)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "senang" {
+		t.Fatalf("restored entry was not analyzed: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/trash/"+id+"/restore", token, nil)
+	expect(t, status, http.StatusNotFound, body)
+}
+
+func TestCreateIgnoresServerOwnedFields(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	past := "2020-01-01T00:00:00Z"
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{
+		"content":         "langsung ke tempat sampah",
+		"deleted_at":      past,
+		"updated_at":      past,
+		"created_at":      past,
+		"analysis_status": "done",
+	})
+	expect(t, status, http.StatusCreated, body)
+	if body["deleted_at"] != nil || body["updated_at"] == past || body["created_at"] == past || body["analysis_status"] != "pending" {
+		t.Fatalf("client set server-owned fields: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/trash", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 0 {
+		t.Fatalf("new entry was created in the trash: %v", body)
+	}
+}
+
+func TestTrashPurgerRemovesExpiredEntries(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "dihapus")
+	status, body := s.do(t, http.MethodDelete, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	ctx := context.Background()
+	if n, err := s.purger.PurgeExpired(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("purged %d entries before retention expired (err %v)", n, err)
+	}
+	if n, err := s.purger.PurgeExpired(ctx, time.Now().Add(31*24*time.Hour)); err != nil || n != 1 {
+		t.Fatalf("purged %d entries after retention expired, want 1 (err %v)", n, err)
+	}
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/trash/"+id+"/restore", token, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	// Entri yang sudah lewat masa retensi tidak bisa dipulihkan walaupun purger belum berjalan,
+	// karena purger menghapus lampirannya lebih dulu
+	expired := s.createEntry(t, token, "", "kedaluwarsa")
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+expired, token, nil)
+	expect(t, status, http.StatusOK, body)
+	userID, _ := primitive.ObjectIDFromHex(body["user_id"].(string))
+	entryID, _ := primitive.ObjectIDFromHex(expired)
+	if ok, err := s.diaries.Trash(ctx, userID, entryID, time.Now().Add(-31*24*time.Hour)); err != nil || !ok {
+		t.Fatalf("trash entry: %v, %v", ok, err)
+	}
+	status, body = s.do(t, http.MethodPost, "/api/diary/trash/"+expired+"/restore", token, nil)
+	expect(t, status, http.StatusNotFound, body)
+}
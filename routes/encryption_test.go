@@ -0,0 +1,206 @@
+package routes_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// ciphertext meniru keluaran enkripsi client: base64 dari nonce, ciphertext dan tag
+func ciphertext(t *testing.T, n int) string {
+	t.Helper()
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatalf("random: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func encryptionKey(t *testing.T, version int) map[string]any {
+	return map[string]any{
+		"cipher": "aes-256-gcm",
+		"kdf": map[string]any{
+			"algorithm":   "argon2id",
+			"salt":        ciphertext(t, 16),
+			"iterations":  3,
+			"memory_kib":  65536,
+			"parallelism": 1,
+		},
+		"wrapped_key": ciphertext(t, 60),
+		"version":     version,
+	}
+}
+
+func (s *testServer) enableEncryption(t *testing.T, token string) {
+	t.Helper()
+	status, body := s.do(t, http.MethodPost, "/api/profile/encryption", token, encryptionKey(t, 0))
+	expect(t, status, http.StatusCreated, body)
+}
+
+func TestEncryptionKeyLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodGet, "/api/profile/encryption", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["enabled"] != false {
+		t.Fatalf("encryption enabled by default: %v", body)
+	}
+
+	weak := encryptionKey(t, 0)
+	weak["kdf"] = map[string]any{"algorithm": "pbkdf2-sha256", "salt": ciphertext(t, 16), "iterations": 1000}
+	status, body = s.do(t, http.MethodPost, "/api/profile/encryption", token, weak)
+	expect(t, status, http.StatusBadRequest, body)
+
+	s.enableEncryption(t, token)
+	status, body = s.do(t, http.MethodPost, "/api/profile/encryption", token, encryptionKey(t, 0))
+	expect(t, status, http.StatusConflict, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/profile/me", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["encryption_enabled"] != true {
+		t.Fatalf("profile does not report encryption: %v", body)
+	}
+
+	// Passphrase diganti: data key dibungkus ulang dengan versi kunci yang terakhir dibaca
+	rewrapped := encryptionKey(t, 1)
+	status, body = s.do(t, http.MethodPut, "/api/profile/encryption", token, rewrapped)
+	expect(t, status, http.StatusOK, body)
+	key := body["key"].(map[string]any)
+	if key["version"] != float64(2) || key["wrapped_key"] != rewrapped["wrapped_key"] {
+		t.Fatalf("key was not rewrapped: %v", key)
+	}
+
+	status, body = s.do(t, http.MethodPut, "/api/profile/encryption", token, encryptionKey(t, 1))
+	expect(t, status, http.StatusConflict, body)
+
+	status, body = s.do(t, http.MethodDelete, "/api/profile/encryption", token, nil)
+	expect(t, status, http.StatusOK, body)
+	status, body = s.do(t, http.MethodGet, "/api/profile/encryption", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["enabled"] != false {
+		t.Fatalf("encryption still enabled: %v", body)
+	}
+}
+
+func TestEncryptedEntriesAreNotAnalyzedByServer(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{"content": ciphertext(t, 64), "encrypted": true})
+	expect(t, status, http.StatusBadRequest, body)
+
+	s.enableEncryption(t, token)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{"content": "hari ini senang"})
+	expect(t, status, http.StatusBadRequest, body)
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{"content": "hari ini senang", "encrypted": true})
+	expect(t, status, http.StatusBadRequest, body)
+
+	content := ciphertext(t, 64)
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{
+		"title": ciphertext(t, 32), "content": content, "encrypted": true,
+		"emotion": "Senang", "sentiment": "positive",
+	})
+	expect(t, status, http.StatusCreated, body)
+	if body["encrypted"] != true || body["content"] != content || body["emotion"] != "senang" || body["analysis_status"] != "done" {
+		t.Fatalf("unexpected encrypted entry: %v", body)
+	}
+	id := body["id"].(string)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{"content": ciphertext(t, 64), "encrypted": true})
+	expect(t, status, http.StatusCreated, body)
+	if body["analysis_status"] != "skipped" || body["emotion"] != nil {
+		t.Fatalf("entry without client analysis: %v", body)
+	}
+
+	if n := s.runWorkers(t); n != 0 || s.analyzer.callCount() != 0 {
+		t.Fatalf("server analyzed encrypted entries (%d jobs)", n)
+	}
+
+	// Search tidak pernah mencocokkan ciphertext
+	status, body = s.do(t, http.MethodGet, "/api/diary/search?q="+url.QueryEscape(content[:8]), token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["total"] != float64(0) {
+		t.Fatalf("search matched encrypted entries: %v", body)
+	}
+
+	// Isi baru tanpa hasil analisis membuang emosi lama yang tidak lagi sesuai
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": ciphertext(t, 64)})
+	expect(t, status, http.StatusOK, body)
+	if body["analysis_status"] != "skipped" || body["emotion"] != nil {
+		t.Fatalf("stale analysis kept after update: %v", body)
+	}
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "sedih", "sentiment": "negative"})
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "sedih" || body["analysis_status"] != "done" {
+		t.Fatalf("client analysis not saved: %v", body)
+	}
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "bingung", "sentiment": "negative"})
+	expect(t, status, http.StatusBadRequest, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	revisions := body["data"].([]any)
+	if len(revisions) != 1 || revisions[0].(map[string]any)["encrypted"] != true {
+		t.Fatalf("unexpected revisions: %v", body)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions/diff?from="+revisions[0].(map[string]any)["id"].(string), token, nil)
+	expect(t, status, http.StatusConflict, body)
+}
+
+func TestConvertingEntriesBetweenPlaintextAndEncrypted(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "judul", "hari ini senang")
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "hari ini senang sekali"})
+	expect(t, status, http.StatusOK, body)
+	s.runWorkers(t)
+
+	s.enableEncryption(t, token)
+
+	// Entri lama tetap bisa dibaca, tetapi tulisan baru harus terenkripsi
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "masih plaintext"})
+	expect(t, status, http.StatusBadRequest, body)
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]any{"encrypted": true, "content": ciphertext(t, 64)})
+	expect(t, status, http.StatusBadRequest, body)
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]any{
+		"encrypted": true, "title": ciphertext(t, 32), "content": ciphertext(t, 64),
+	})
+	expect(t, status, http.StatusOK, body)
+	if body["encrypted"] != true || body["analysis_status"] != "skipped" || body["emotion"] != nil {
+		t.Fatalf("entry was not converted: %v", body)
+	}
+
+	// Versi plaintext tidak boleh tersisa di riwayat
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["total"] != float64(0) {
+		t.Fatalf("plaintext revisions kept after encrypting: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodDelete, "/api/profile/encryption", token, nil)
+	expect(t, status, http.StatusConflict, body)
+	if body["encrypted_entries"] != float64(1) {
+		t.Fatalf("unexpected disable response: %v", body)
+	}
+
+	// Client mendekripsi entri kembali ke plaintext sebelum mematikan enkripsi
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]any{
+		"encrypted": false, "title": "judul", "content": "hari ini sedih",
+	})
+	expect(t, status, http.StatusOK, body)
+	if body["encrypted"] != nil || body["analysis_status"] != "pending" {
+		t.Fatalf("entry was not decrypted: %v", body)
+	}
+	if n := s.runWorkers(t); n != 1 {
+		t.Fatalf("processed %d jobs after decrypting, want 1", n)
+	}
+
+	status, body = s.do(t, http.MethodDelete, "/api/profile/encryption", token, nil)
+	expect(t, status, http.StatusOK, body)
+}
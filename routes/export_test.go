@@ -0,0 +1,102 @@
+package routes_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAccountExportZip(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	kept := s.createEntry(t, token, "Pantai", "hari ini senang")
+	trashed := s.createEntry(t, token, "", "dibuang")
+	s.createEntry(t, other, "rahasia", "bukan milik budi")
+	s.runWorkers(t)
+	status, body := s.do(t, http.MethodDelete, "/api/diary/"+trashed, token, nil)
+	expect(t, status, http.StatusOK, body)
+	status, body, _ = s.upload(t, token, kept, "foto.png", testPNG(t, 4, 4))
+	expect(t, status, http.StatusCreated, body)
+
+	resp, raw := s.download(t, token, "/api/profile/export")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("export status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	if strings.Contains(files["profile.json"], "password") || !strings.Contains(files["profile.json"], "budi@example.com") {
+		t.Fatalf("unexpected profile.json: %s", files["profile.json"])
+	}
+
+	var entries []map[string]any
+	if err := json.Unmarshal([]byte(files["entries.json"]), &entries); err != nil {
+		t.Fatalf("decode entries.json: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("exported %d entries, want 2 (active and trashed): %v", len(entries), entries)
+	}
+	attachments, _ := entries[0]["attachments"].([]any)
+	if len(attachments) != 1 {
+		t.Fatalf("entry attachments = %v, want 1", entries[0]["attachments"])
+	}
+	path := attachments[0].(map[string]any)["path"].(string)
+	if _, ok := files[path]; !ok {
+		t.Fatalf("attachment file %s missing from archive", path)
+	}
+
+	var markdown, trash string
+	for name, content := range files {
+		switch {
+		case strings.HasPrefix(name, "entries/") && strings.HasSuffix(name, kept+".md"):
+			markdown = content
+		case strings.HasPrefix(name, "trash/") && strings.HasSuffix(name, trashed+".md"):
+			trash = content
+		case strings.Contains(content, "bukan milik budi"):
+			t.Fatalf("export contains another user's entry in %s", name)
+		}
+	}
+	for _, want := range []string{"---\n", `title: "Pantai"`, `emotion: "senang"`, `sentiment: "positive"`, "# Pantai", "hari ini senang"} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("markdown entry lacks %q:\n%s", want, markdown)
+		}
+	}
+	if !strings.Contains(trash, "deleted_at:") {
+		t.Fatalf("trashed entry lacks deleted_at:\n%s", trash)
+	}
+}
+
+func TestAccountExportJSON(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	s.createEntry(t, token, "satu", "isi pertama")
+
+	status, body := s.do(t, http.MethodGet, "/api/profile/export?format=json", token, nil)
+	expect(t, status, http.StatusOK, body)
+	profile, _ := body["profile"].(map[string]any)
+	entries, _ := body["entries"].([]any)
+	if profile["username"] != "budi" || len(entries) != 1 {
+		t.Fatalf("unexpected JSON export: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/profile/export?format=pdf", token, nil)
+	expect(t, status, http.StatusBadRequest, body)
+}
@@ -0,0 +1,113 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"web-diary-be/models"
+	"web-diary-be/services"
+)
+
+func TestUserEmotionOverrideSurvivesReanalysis(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "hari ini senang")
+	s.runWorkers(t)
+
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "Sedih"})
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "sedih" || body["emotion_source"] != "user" || body["sentiment"] != "positive" || body["sentiment_source"] != "model" {
+		t.Fatalf("override was not applied: %v", body)
+	}
+	if n := s.runWorkers(t); n != 0 {
+		t.Fatalf("override enqueued %d analysis jobs", n)
+	}
+
+	// Analisis ulang karena isi berubah tidak menimpa koreksi user
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "hari ini senang sekali"})
+	expect(t, status, http.StatusOK, body)
+	if body["analysis_status"] != "pending" || body["emotion"] != "sedih" || body["sentiment"] != nil {
+		t.Fatalf("content change dropped the override: %v", body)
+	}
+	s.runWorkers(t)
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "sedih" || body["emotion_source"] != "user" || body["sentiment"] != "positive" || body["analysis_status"] != "done" {
+		t.Fatalf("re-analysis overwrote the override: %v", body)
+	}
+
+	// String kosong mengembalikan emosi ke analyzer
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": ""})
+	expect(t, status, http.StatusOK, body)
+	if body["analysis_status"] != "pending" || body["emotion"] != nil {
+		t.Fatalf("releasing the override did not reset the emotion: %v", body)
+	}
+	s.runWorkers(t)
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "senang" || body["emotion_source"] != "model" {
+		t.Fatalf("emotion was not re-analyzed: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "bahagia"})
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestEmotionSetOnCreateIsKept(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"content": "hari ini senang", "sentiment": "negative", "emotion_source": "model",
+	})
+	expect(t, status, http.StatusCreated, body)
+	if body["sentiment"] != "negative" || body["sentiment_source"] != "user" || body["emotion_source"] != nil || body["analysis_status"] != "pending" {
+		t.Fatalf("unexpected new entry: %v", body)
+	}
+	id := body["id"].(string)
+
+	s.runWorkers(t)
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "senang" || body["sentiment"] != "negative" || body["sentiment_source"] != "user" {
+		t.Fatalf("worker overwrote the sentiment from the user: %v", body)
+	}
+}
+
+func TestAnalysisFeedbackIsRecordedAndExported(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "hari ini senang")
+	s.runWorkers(t)
+
+	// Sentimen sama dengan hasil analyzer, hanya emosi yang dicatat sebagai koreksi
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "cinta", "sentiment": "positive"})
+	expect(t, status, http.StatusOK, body)
+	// Mengoreksi nilai dari user tidak dicatat lagi
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "sedih"})
+	expect(t, status, http.StatusOK, body)
+
+	var out bytes.Buffer
+	n, err := services.ExportAnalysisFeedback(context.Background(), &out, s.feedback)
+	if err != nil {
+		t.Fatalf("export feedback: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("exported %d feedback records, want 1: %s", n, out.String())
+	}
+	var feedback models.AnalysisFeedback
+	if err := json.Unmarshal(out.Bytes(), &feedback); err != nil {
+		t.Fatalf("decode feedback: %v", err)
+	}
+	if feedback.EntryID.Hex() != id || feedback.Field != "emotion" || feedback.ModelValue != "senang" || feedback.UserValue != "cinta" {
+		t.Fatalf("unexpected feedback: %+v", feedback)
+	}
+	if feedback.Analysis == nil || feedback.Analysis.Provider != "fake" {
+		t.Fatalf("feedback does not record the analyzer: %+v", feedback)
+	}
+}
@@ -0,0 +1,185 @@
+package routes_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// importFile mengunggah file ke endpoint impor dengan query tambahan
+func (s *testServer) importFile(t *testing.T, token, query, filename string, data []byte) (int, map[string]any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/diary/import"+query, &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body := map[string]any{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func expectCounts(t *testing.T, body map[string]any, format string, fresh, duplicates, skipped int) {
+	t.Helper()
+	if body["format"] != format || body["new"] != float64(fresh) || body["duplicates"] != float64(duplicates) || body["skipped"] != float64(skipped) {
+		t.Fatalf("import result = %v, want format %s with %d new, %d duplicates, %d skipped", body, format, fresh, duplicates, skipped)
+	}
+}
+
+const dayOneJournal = `{
+  "metadata": {"version": "1.0"},
+  "entries": [
+    {"uuid": "A1", "creationDate": "2019-03-01T08:00:00Z", "text": "# Liburan\n\nHari ini senang sekali\\!", "tags": ["Liburan", "keluarga"]},
+    {"uuid": "A2", "creationDate": "2019-03-02T08:00:00Z", "text": "Catatan tanpa judul"},
+    {"uuid": "A3", "creationDate": "2019-03-03T08:00:00Z", "text": "Catatan tanpa judul"}
+  ]
+}`
+
+func TestImportDayOneWithDryRunAndDeduplication(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.importFile(t, token, "?dry_run=true", "Journal.json", []byte(dayOneJournal))
+	expect(t, status, http.StatusOK, body)
+	expectCounts(t, body, "dayone", 2, 1, 0)
+
+	// Dry run tidak menyimpan apa pun
+	status, body = s.do(t, http.MethodGet, "/api/diary/", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if data := body["data"].([]any); len(data) != 0 {
+		t.Fatalf("dry run created %d entries", len(data))
+	}
+
+	status, body = s.importFile(t, token, "", "Journal.json", []byte(dayOneJournal))
+	expect(t, status, http.StatusCreated, body)
+	expectCounts(t, body, "dayone", 2, 1, 0)
+	first := body["entries"].([]any)[0].(map[string]any)
+
+	status, entry := s.do(t, http.MethodGet, "/api/diary/"+first["id"].(string), token, nil)
+	expect(t, status, http.StatusOK, entry)
+	if entry["title"] != "Liburan" || entry["content"] != "Hari ini senang sekali!" || entry["analysis_status"] != "skipped" {
+		t.Fatalf("unexpected imported entry: %v", entry)
+	}
+	if created, _ := time.Parse(time.RFC3339, entry["created_at"].(string)); !created.Equal(time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("created_at = %v, want original Day One date", entry["created_at"])
+	}
+	if tags := entry["tags"].([]any); len(tags) != 2 || tags[0] != "liburan" {
+		t.Fatalf("tags = %v", tags)
+	}
+
+	// Impor ulang hanya menemukan duplikat
+	status, body = s.importFile(t, token, "", "Journal.json", []byte(dayOneJournal))
+	expect(t, status, http.StatusCreated, body)
+	expectCounts(t, body, "dayone", 0, 3, 0)
+}
+
+func TestImportMarkdownZipWithAnalysis(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	archive := zipFiles(t, map[string]string{
+		"jurnal/2020-05-01-pagi.md":  "Bangun pagi, senang rasanya.",
+		"jurnal/malam.md":            "---\ntitle: \"Malam\"\ndate: 2020-05-02\ntags: [rumah]\n---\n\nHari yang sedih.",
+		"jurnal/__MACOSX/._malam.md": "binary",
+		"jurnal/foto.jpg":            "not imported",
+	})
+	status, body := s.importFile(t, token, "?analyze=true", "jurnal.zip", archive)
+	expect(t, status, http.StatusCreated, body)
+	expectCounts(t, body, "markdown", 2, 0, 0)
+	if n := s.runWorkers(t); n != 2 {
+		t.Fatalf("analyzed %d imported entries, want 2", n)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	newest, oldest := data[0].(map[string]any), data[1].(map[string]any)
+	if newest["title"] != "Malam" || newest["emotion"] != "sedih" || newest["tags"].([]any)[0] != "rumah" {
+		t.Fatalf("unexpected front-matter entry: %v", newest)
+	}
+	if oldest["title"] != "pagi" || oldest["emotion"] != "senang" || oldest["created_at"] != "2020-05-01T00:00:00Z" {
+		t.Fatalf("unexpected file-name dated entry: %v", oldest)
+	}
+}
+
+func TestImportJourneyEntries(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	archive := zipFiles(t, map[string]string{
+		"1583020800000-abc.json": `{"id": "abc", "text": "<p>Pergi ke pasar &amp; taman</p><p>Lelah</p>", "date_journal": 1583020800000, "tags": ["jalan"]}`,
+	})
+	status, body := s.importFile(t, token, "", "journey.zip", archive)
+	expect(t, status, http.StatusCreated, body)
+	expectCounts(t, body, "journey", 1, 0, 0)
+
+	id := body["entries"].([]any)[0].(map[string]any)["id"].(string)
+	status, entry := s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, entry)
+	if entry["content"] != "<p>Pergi ke pasar &amp; taman</p><p>Lelah</p>" || entry["content_format"] != "html" || entry["created_at"] != "2020-03-01T00:00:00Z" {
+		t.Fatalf("unexpected Journey entry: %v", entry)
+	}
+}
+
+func TestImportOwnExport(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	s.createEntry(t, token, "satu", "hari ini senang")
+	trashed := s.createEntry(t, token, "dua", "dibuang")
+	s.runWorkers(t)
+	status, body := s.do(t, http.MethodDelete, "/api/diary/"+trashed, token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	_, archive := s.download(t, token, "/api/profile/export")
+	status, body = s.importFile(t, other, "", "web-diary-export.zip", archive)
+	expect(t, status, http.StatusCreated, body)
+	expectCounts(t, body, "web-diary", 1, 0, 1)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/", other, nil)
+	expect(t, status, http.StatusOK, body)
+	entry := body["data"].([]any)[0].(map[string]any)
+	if entry["content"] != "hari ini senang" || entry["emotion"] != "senang" || entry["analysis_status"] != "done" {
+		t.Fatalf("exported analysis was not kept: %v", entry)
+	}
+
+	status, body = s.importFile(t, other, "", "notes.bin", []byte("\x00\x01"))
+	expect(t, status, http.StatusBadRequest, body)
+}
@@ -0,0 +1,134 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/services"
+)
+
+func TestModelLabelsAreNormalized(t *testing.T) {
+	for label, want := range map[string]models.Emotion{
+		"senang": models.EmotionSenang, " Senang ": models.EmotionSenang, "happy": models.EmotionSenang,
+		"Sadness": models.EmotionSedih, "Percaya Diri": models.EmotionPercayaDiri, "percaya-diri": models.EmotionPercayaDiri,
+		"confident": models.EmotionPercayaDiri,
+	} {
+		if got, ok := models.MatchEmotion(label); !ok || got != want {
+			t.Fatalf("MatchEmotion(%q) = %q, %v; want %q", label, got, ok, want)
+		}
+	}
+	for _, label := range []string{"", "nostalgia", "unknown"} {
+		if got, ok := models.MatchEmotion(label); ok {
+			t.Fatalf("MatchEmotion(%q) accepted %q", label, got)
+		}
+	}
+	if got, ok := models.MatchSentiment("positive "); !ok || got != models.SentimentPositive {
+		t.Fatalf("MatchSentiment did not trim the label: %q", got)
+	}
+	// Sinonim hanya diterima dari model, bukan dari user
+	if _, ok := models.ParseEmotion("happy"); ok {
+		t.Fatal("ParseEmotion accepted an alias")
+	}
+
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	id := s.createEntry(t, token, "", "hari ini senang")
+	s.runWorkers(t)
+
+	// Filter menerima penulisan label yang longgar
+	status, body := s.do(t, http.MethodGet, "/api/diary/?emotion=Senang&sentiment=POSITIVE", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	if len(data) != 1 || data[0].(map[string]any)["id"] != id {
+		t.Fatalf("filter did not match the canonical label: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"emotion": "happy"})
+	expect(t, status, http.StatusBadRequest, body)
+}
+
+func TestInvalidModelLabelsAreRejected(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	// Dengan MaxAttempts = 1, label yang ditolak langsung membuat analisis gagal
+	id := s.createEntry(t, token, "", "hari ini aneh")
+	s.runWorkers(t)
+
+	status, body := s.do(t, http.MethodGet, "/api/diary/"+id, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["emotion"] != "unknown" || body["sentiment"] != "neutral" || body["analysis_status"] != "failed" {
+		t.Fatalf("invalid labels were stored: %v", body)
+	}
+}
+
+func TestNormalizeStoredLabels(t *testing.T) {
+	repo := repositories.NewMemoryDiaryRepository()
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	stored := map[primitive.ObjectID][2]string{}
+	for _, labels := range [][2]string{
+		{"Senang", "positive "},
+		{"happy", "Positif"},
+		{"nostalgia", "mixed"},
+		{"Unknown", "Neutral"},
+		{"sedih", "negative"},
+		{"", ""},
+	} {
+		entry := &models.DiaryEntry{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			Content:   "isi",
+			Emotion:   models.Emotion(labels[0]),
+			Sentiment: models.Sentiment(labels[1]),
+		}
+		if err := repo.Create(ctx, entry); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		stored[entry.ID] = labels
+	}
+
+	report, err := services.NormalizeLabels(ctx, repo, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.Scanned != 6 || report.Normalized != 3 || report.Invalid != 1 {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+	for id, labels := range stored {
+		entry, _ := repo.Get(ctx, id)
+		if string(entry.Emotion) != labels[0] {
+			t.Fatalf("dry run changed %q to %q", labels[0], entry.Emotion)
+		}
+	}
+
+	if _, err := services.NormalizeLabels(ctx, repo, false); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	want := map[string][2]string{
+		"Senang":    {"senang", "positive"},
+		"happy":     {"senang", "positive"},
+		"nostalgia": {"unknown", "neutral"},
+		"Unknown":   {"unknown", "neutral"},
+		"sedih":     {"sedih", "negative"},
+		"":          {"", ""},
+	}
+	for id, labels := range stored {
+		entry, _ := repo.Get(ctx, id)
+		if got := [2]string{string(entry.Emotion), string(entry.Sentiment)}; got != want[labels[0]] {
+			t.Fatalf("%q was normalized to %v, want %v", labels[0], got, want[labels[0]])
+		}
+	}
+
+	// Menjalankan ulang tidak mengubah apa-apa
+	report, err = services.NormalizeLabels(ctx, repo, false)
+	if err != nil || report.Normalized != 0 || report.Invalid != 0 {
+		t.Fatalf("second run changed entries: %+v, %v", report, err)
+	}
+}
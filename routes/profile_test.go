@@ -0,0 +1,74 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/repositories"
+)
+
+func TestUpdateProfileEmailRequiresVerification(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPut, "/api/profile/me", token, map[string]string{"email": "siti@example.com"})
+	expect(t, status, http.StatusBadRequest, body)
+
+	status, body = s.do(t, http.MethodPut, "/api/profile/me", token, map[string]string{"email": "Budi.Baru@example.com"})
+	expect(t, status, http.StatusOK, body)
+	if body["email"] != "budi.baru@example.com" || body["email_verified"] != false {
+		t.Fatalf("unexpected profile after email change: %v", body)
+	}
+	if s.mailer.lastToken(t, "budi.baru@example.com") == "" {
+		t.Fatalf("no verification email sent to the new address")
+	}
+}
+
+func TestDeleteProfile(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	s.createEntry(t, token, "", "hari ini senang")
+	s.createEntry(t, token, "", "hari ini sedih")
+	kept := s.createEntry(t, other, "", "punya siti")
+
+	status, me := s.do(t, http.MethodGet, "/api/profile/me", token, nil)
+	expect(t, status, http.StatusOK, me)
+	userID, err := primitive.ObjectIDFromHex(me["id"].(string))
+	if err != nil {
+		t.Fatalf("invalid user id %v", me["id"])
+	}
+
+	status, body := s.do(t, http.MethodDelete, "/api/profile/me", token, nil)
+	expect(t, status, http.StatusOK, body)
+
+	// Token user yang sudah dihapus tidak berlaku lagi
+	status, body = s.do(t, http.MethodGet, "/api/diary/", token, nil)
+	expect(t, status, http.StatusUnauthorized, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": "budi@example.com", "password": "secret123",
+	})
+	expect(t, status, http.StatusBadRequest, body)
+
+	ctx := context.Background()
+	if n, _ := s.diaries.Count(ctx, repositories.DiaryFilter{UserID: userID}); n != 0 {
+		t.Fatalf("%d diary entries left after profile deletion", n)
+	}
+	if sessions, _ := s.sessions.ListActive(ctx, userID, time.Now()); len(sessions) != 0 {
+		t.Fatalf("%d sessions left after profile deletion", len(sessions))
+	}
+	// Pending analysis job milik user juga ikut dibuang
+	if n := s.runWorkers(t); n != 1 {
+		t.Fatalf("processed %d jobs, want only the other user's job", n)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+kept, other, nil)
+	expect(t, status, http.StatusOK, body)
+}
@@ -0,0 +1,52 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"web-diary-be/config"
+)
+
+func TestAuthRateLimitUsesClientIPBehindProxy(t *testing.T) {
+	behindProxy := func(trusted string) func(*config.Config) {
+		return func(cfg *config.Config) {
+			cfg.RateLimit.AuthMax = 2
+			cfg.Proxy = config.ProxyConfig{Header: "X-Real-IP", TrustedProxies: []string{trusted}}
+		}
+	}
+	// Koneksi dari app.Test selalu datang dari 0.0.0.0
+	login := func(t *testing.T, s *testServer, clientIP string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.Header.Set("X-Real-IP", clientIP)
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	s := newTestServer(t, behindProxy("0.0.0.0"))
+	for i := 0; i < 2; i++ {
+		if status := login(t, s, "203.0.113.1"); status == http.StatusTooManyRequests {
+			t.Fatalf("request %d was limited", i+1)
+		}
+	}
+	if status := login(t, s, "203.0.113.1"); status != http.StatusTooManyRequests {
+		t.Fatalf("third request status = %d, want 429", status)
+	}
+	// Client lain di belakang proxy yang sama punya kuota sendiri
+	if status := login(t, s, "203.0.113.2"); status == http.StatusTooManyRequests {
+		t.Fatal("other client shares the limit of the first one")
+	}
+
+	// Header dari proxy yang tidak dipercaya diabaikan, jadi tidak bisa dipakai menghindari limit
+	s = newTestServer(t, behindProxy("10.0.0.1"))
+	login(t, s, "203.0.113.1")
+	login(t, s, "203.0.113.2")
+	if status := login(t, s, "203.0.113.3"); status != http.StatusTooManyRequests {
+		t.Fatalf("spoofed header status = %d, want 429", status)
+	}
+}
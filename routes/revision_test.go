@@ -0,0 +1,127 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDiaryRevisionsRecordPreviousVersions(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "v1", "hari ini senang")
+	s.runWorkers(t)
+
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": "hari ini sedih"})
+	expect(t, status, http.StatusOK, body)
+
+	// Update tanpa perubahan tidak membuat revisi baru
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"title": "v1"})
+	expect(t, status, http.StatusOK, body)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	if len(data) != 1 {
+		t.Fatalf("got %d revisions, want 1: %v", len(data), body)
+	}
+	revision := data[0].(map[string]any)
+	if revision["content"] != "hari ini senang" || revision["emotion"] != "senang" || revision["written_at"] == nil {
+		t.Fatalf("revision does not hold the previous version: %v", revision)
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions/"+revision["id"].(string), token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["title"] != "v1" {
+		t.Fatalf("unexpected revision: %v", body)
+	}
+}
+
+func TestDiaryRevisionsAreCapped(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	id := s.createEntry(t, token, "", "versi 0")
+	for _, content := range []string{"versi 1", "versi 2", "versi 3", "versi 4", "versi 5"} {
+		status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{"content": content})
+		expect(t, status, http.StatusOK, body)
+	}
+
+	// newTestServer membatasi 3 revisi per entri
+	status, body := s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	if len(data) != 3 {
+		t.Fatalf("got %d revisions, want 3", len(data))
+	}
+	for i, want := range []string{"versi 4", "versi 3", "versi 2"} {
+		if got := data[i].(map[string]any)["content"]; got != want {
+			t.Fatalf("revision %d content = %v, want %q", i, got, want)
+		}
+	}
+}
+
+func TestDiaryRevisionDiffAndRestore(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	id := s.createEntry(t, token, "lama", "pagi\nsiang senang\nmalam")
+	s.runWorkers(t)
+	status, body := s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]string{
+		"title": "baru", "content": "pagi\nsiang sedih\nmalam",
+	})
+	expect(t, status, http.StatusOK, body)
+	s.runWorkers(t)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	rid := body["data"].([]any)[0].(map[string]any)["id"].(string)
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions/diff?from="+rid, token, nil)
+	expect(t, status, http.StatusOK, body)
+	title := body["title"].(map[string]any)
+	if title["from"] != "lama" || title["to"] != "baru" || title["changed"] != true {
+		t.Fatalf("unexpected title diff: %v", title)
+	}
+	var ops []string
+	for _, op := range body["content"].([]any) {
+		line := op.(map[string]any)
+		ops = append(ops, line["op"].(string)+":"+line["text"].(string))
+	}
+	want := []string{"equal:pagi", "delete:siang senang", "insert:siang sedih", "equal:malam"}
+	if len(ops) != len(want) {
+		t.Fatalf("content diff = %v, want %v", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("content diff = %v, want %v", ops, want)
+		}
+	}
+
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions/diff", token, nil)
+	expect(t, status, http.StatusBadRequest, body)
+
+	// Revisi milik entri user lain tidak bisa diakses maupun dipulihkan
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", other, nil)
+	expect(t, status, http.StatusNotFound, body)
+	status, body = s.do(t, http.MethodPost, "/api/diary/"+id+"/revisions/"+rid+"/restore", other, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/"+id+"/revisions/"+rid+"/restore", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if body["title"] != "lama" || body["content"] != "pagi\nsiang senang\nmalam" || body["analysis_status"] != "pending" {
+		t.Fatalf("revision was not restored: %v", body)
+	}
+	if n := s.runWorkers(t); n != 1 {
+		t.Fatalf("processed %d jobs after restore, want 1", n)
+	}
+
+	// Versi sebelum restore ikut tersimpan sebagai revisi
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	data := body["data"].([]any)
+	if len(data) != 2 || data[0].(map[string]any)["title"] != "baru" {
+		t.Fatalf("restore did not record the replaced version: %v", body)
+	}
+}
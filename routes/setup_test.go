@@ -0,0 +1,301 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"web-diary-be/config"
+	"web-diary-be/handlers"
+	"web-diary-be/middleware"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+	"web-diary-be/routes"
+	"web-diary-be/services"
+)
+
+// fakeAnalyzer memberi hasil tetap berdasarkan kata kunci agar test deterministik. Labelnya
+// sengaja ditulis seperti keluaran model ("Joy", "Positive") agar normalisasi ikut diuji.
+type fakeAnalyzer struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (a *fakeAnalyzer) Analyze(ctx context.Context, text string) (*services.AnalysisResult, error) {
+	a.mu.Lock()
+	a.calls = append(a.calls, text)
+	a.mu.Unlock()
+
+	result := &services.AnalysisResult{Detail: models.AnalysisDetail{Scores: map[string]float64{}, Provider: "fake"}}
+	switch {
+	case strings.Contains(text, "senang"):
+		result.Emotion, result.Sentiment = "Joy", "Positive"
+		result.Detail.Scores["senang"], result.Detail.SentimentScore, result.Detail.Confidence = 0.9, 0.8, 0.9
+	case strings.Contains(text, "sedih"):
+		result.Emotion, result.Sentiment = "Sadness", "Negative"
+		result.Detail.Scores["sedih"], result.Detail.SentimentScore, result.Detail.Confidence = 0.9, -0.8, 0.9
+	case strings.Contains(text, "aneh"):
+		result.Emotion, result.Sentiment = "nostalgia", "mixed"
+	default:
+		result.Emotion, result.Sentiment = models.EmotionUnknown, models.SentimentNeutral
+	}
+	return result, nil
+}
+
+func (a *fakeAnalyzer) callCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.calls)
+}
+
+// captureMailer menyimpan email yang dikirim supaya test bisa mengambil token di dalamnya
+type captureMailer struct {
+	mu       sync.Mutex
+	messages []services.Message
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg services.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+var tokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+// lastToken mengembalikan token dari email terakhir yang dikirim ke alamat tersebut
+func (m *captureMailer) lastToken(t *testing.T, to string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != to {
+			continue
+		}
+		match := tokenPattern.FindStringSubmatch(m.messages[i].Body)
+		if match == nil {
+			t.Fatalf("email to %s has no token: %q", to, m.messages[i].Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatalf("unescape token: %v", err)
+		}
+		return token
+	}
+	t.Fatalf("no email sent to %s", to)
+	return ""
+}
+
+// failingBlobStore meneruskan ke MemoryBlobStore, tetapi Delete gagal selama fail diset
+type failingBlobStore struct {
+	*services.MemoryBlobStore
+	fail *atomic.Bool
+}
+
+func (s failingBlobStore) Delete(ctx context.Context, key string) error {
+	if s.fail.Load() {
+		return errors.New("blob store unavailable")
+	}
+	return s.MemoryBlobStore.Delete(ctx, key)
+}
+
+type testServer struct {
+	app       *fiber.App
+	handler   *handlers.Handler
+	diaries   *repositories.MemoryDiaryRepository
+	revisions *repositories.MemoryRevisionRepository
+	blobs     *services.MemoryBlobStore
+	blobsDown atomic.Bool // membuat penghapusan blob gagal
+	users     *repositories.MemoryUserRepository
+	sessions  *repositories.MemorySessionRepository
+	dataKeys  *repositories.MemoryDataKeyRepository
+	feedback  *repositories.MemoryAnalysisFeedbackRepository
+	atRest    *services.AtRestCipher // nil kecuali enkripsi at rest diaktifkan lewat configure
+	workers   *services.AnalysisWorkerPool
+	purger    *services.TrashPurger
+	analyzer  *fakeAnalyzer
+	mailer    *captureMailer
+}
+
+// newTestServer merakit aplikasi yang sama dengan main, tetapi seluruhnya di memori.
+// configure dijalankan setelah konfigurasi bawaan test diterapkan.
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.JWTSecret = "test-secret"
+	cfg.Analyzer.MaxAttempts = 1
+
+	cfg.Revisions.MaxPerEntry = 3
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	s := &testServer{
+		diaries:   repositories.NewMemoryDiaryRepository(),
+		revisions: repositories.NewMemoryRevisionRepository(),
+		blobs:     services.NewMemoryBlobStore(),
+		users:     repositories.NewMemoryUserRepository(),
+		sessions:  repositories.NewMemorySessionRepository(),
+		dataKeys:  repositories.NewMemoryDataKeyRepository(),
+		feedback:  repositories.NewMemoryAnalysisFeedbackRepository(),
+		analyzer:  &fakeAnalyzer{},
+		mailer:    &captureMailer{},
+	}
+	jobs := repositories.NewMemoryAnalysisJobRepository()
+	limits := services.NewMemoryCounterStore()
+	sessions := services.NewSessionService(s.sessions, cfg.Auth)
+	blobs := failingBlobStore{MemoryBlobStore: s.blobs, fail: &s.blobsDown}
+	attachments := services.NewAttachmentService(repositories.NewMemoryAttachmentRepository(), blobs, cfg.Attachments)
+
+	// s.diaries dan s.revisions tetap menunjuk ke data mentah agar test bisa memeriksa ciphertext
+	var diaries repositories.DiaryRepository = s.diaries
+	var revisions repositories.RevisionRepository = s.revisions
+	if cfg.AtRest.Enabled() {
+		var err error
+		if s.atRest, err = services.NewAtRestCipher(cfg.AtRest, s.dataKeys); err != nil {
+			t.Fatalf("at-rest cipher: %v", err)
+		}
+		diaries = repositories.NewEncryptedDiaryRepository(diaries, s.atRest)
+		revisions = repositories.NewEncryptedRevisionRepository(revisions, s.atRest)
+	}
+
+	s.handler = &handlers.Handler{
+		Config:      cfg,
+		Diaries:     diaries,
+		Revisions:   revisions,
+		Attachments: attachments,
+		Exports:     services.NewAccountExporter(diaries, attachments),
+		Users:       s.users,
+		Auth:        middleware.NewAuth(cfg, s.users, sessions),
+		Sessions:    sessions,
+		Verifier:    services.NewEmailVerifier(cfg, s.users, s.mailer),
+		Resets:      services.NewPasswordResetService(cfg.Auth, repositories.NewMemoryPasswordResetRepository(), s.users, sessions, s.mailer),
+		Analysis:    services.NewAnalysisQueue(jobs),
+		Feedback:    s.feedback,
+		Lockout:     services.NewLoginLockout(limits, cfg.RateLimit),
+		Limits:      limits,
+	}
+	s.workers = services.NewAnalysisWorkerPool(jobs, diaries, s.analyzer, cfg.Analyzer)
+	s.purger = services.NewTrashPurger(diaries, revisions, attachments, cfg.Trash)
+
+	s.app = routes.NewApp(cfg)
+	routes.AuthRoutes(s.app, s.handler)
+	routes.DiaryRoutes(s.app, s.handler)
+	routes.TagRoutes(s.app, s.handler)
+	routes.ProfileRoutes(s.app, s.handler)
+	return s
+}
+
+// do mengirim request JSON dan mengembalikan status beserta body yang sudah di-decode
+func (s *testServer) do(t *testing.T, method, path, token string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// bcrypt cost 14 bisa lebih lama dari timeout default app.Test
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	out := map[string]any{}
+	raw, _ := io.ReadAll(resp.Body)
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, raw, err)
+		}
+	}
+	return resp.StatusCode, out
+}
+
+// expect memastikan status response sesuai
+func expect(t *testing.T, got int, want int, body map[string]any) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("status = %d, want %d (body %v)", got, want, body)
+	}
+}
+
+// signUp mendaftarkan user, memverifikasi emailnya lewat email yang dikirim,
+// lalu login dan mengembalikan access token
+func (s *testServer) signUp(t *testing.T, username, email, password string) string {
+	t.Helper()
+
+	status, body := s.do(t, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"username": username, "email": email, "password": password,
+	})
+	expect(t, status, http.StatusOK, body)
+
+	status, body = s.do(t, http.MethodPost, "/api/auth/verify", "", map[string]string{
+		"token": s.mailer.lastToken(t, email),
+	})
+	expect(t, status, http.StatusOK, body)
+
+	return s.login(t, email, password)
+}
+
+func (s *testServer) login(t *testing.T, email, password string) string {
+	t.Helper()
+
+	status, body := s.do(t, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email": email, "password": password,
+	})
+	expect(t, status, http.StatusOK, body)
+	token, _ := body["access_token"].(string)
+	if token == "" {
+		t.Fatalf("login response has no access_token: %v", body)
+	}
+	return token
+}
+
+// createEntry membuat entri diary dan mengembalikan id-nya
+func (s *testServer) createEntry(t *testing.T, token, title, content string) string {
+	t.Helper()
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]string{
+		"title": title, "content": content,
+	})
+	expect(t, status, http.StatusCreated, body)
+	id, _ := body["id"].(string)
+	if id == "" {
+		t.Fatalf("created entry has no id: %v", body)
+	}
+	return id
+}
+
+// runWorkers mengerjakan semua job analisis yang tertunda
+func (s *testServer) runWorkers(t *testing.T) int {
+	t.Helper()
+	n, err := s.workers.ProcessPending(context.Background())
+	if err != nil {
+		t.Fatalf("process analysis jobs: %v", err)
+	}
+	return n
+}
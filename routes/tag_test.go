@@ -0,0 +1,141 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// createTaggedEntry membuat entri dengan tag dan mengembalikan id-nya
+func (s *testServer) createTaggedEntry(t *testing.T, token, content string, tags ...string) string {
+	t.Helper()
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{
+		"content": content, "tags": tags,
+	})
+	expect(t, status, http.StatusCreated, body)
+	return body["id"].(string)
+}
+
+// tagCounts mengembalikan hitungan tag user dalam bentuk map
+func (s *testServer) tagCounts(t *testing.T, token string) map[string]float64 {
+	t.Helper()
+
+	status, body := s.do(t, http.MethodGet, "/api/tags/", token, nil)
+	expect(t, status, http.StatusOK, body)
+	counts := map[string]float64{}
+	for _, row := range body["data"].([]any) {
+		tag := row.(map[string]any)
+		counts[tag["tag"].(string)] = tag["count"].(float64)
+	}
+	return counts
+}
+
+func TestDiaryTagsOnCreateAndUpdate(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+
+	status, body := s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{
+		"content": "rapat", "tags": []string{" Kerja ", "kerja", "Kantor  Baru"},
+	})
+	expect(t, status, http.StatusCreated, body)
+	tags := body["tags"].([]any)
+	if len(tags) != 2 || tags[0] != "kerja" || tags[1] != "kantor baru" {
+		t.Fatalf("tags were not normalized: %v", body["tags"])
+	}
+	id := body["id"].(string)
+
+	status, body = s.do(t, http.MethodPost, "/api/diary/", token, map[string]any{
+		"content": "x", "tags": []string{"  "},
+	})
+	expect(t, status, http.StatusBadRequest, body)
+
+	// Update tag saja tidak mengubah isi maupun membuat revisi
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]any{"tags": []string{"keluarga"}})
+	expect(t, status, http.StatusOK, body)
+	if tags := body["tags"].([]any); len(tags) != 1 || tags[0] != "keluarga" || body["content"] != "rapat" {
+		t.Fatalf("unexpected entry after tag update: %v", body)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+id+"/revisions", token, nil)
+	expect(t, status, http.StatusOK, body)
+	if len(body["data"].([]any)) != 0 {
+		t.Fatalf("tag update created a revision: %v", body)
+	}
+
+	status, body = s.do(t, http.MethodPut, "/api/diary/"+id, token, map[string]any{"tags": []string{}})
+	expect(t, status, http.StatusOK, body)
+	if _, ok := body["tags"]; ok {
+		t.Fatalf("tags were not cleared: %v", body)
+	}
+}
+
+func TestDiaryListFiltersByTags(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	s.createTaggedEntry(t, token, "satu", "kerja")
+	s.createTaggedEntry(t, token, "dua", "kerja", "lembur")
+	s.createTaggedEntry(t, token, "tiga", "liburan")
+	s.createTaggedEntry(t, other, "punya siti", "kerja")
+
+	cases := map[string]int{
+		"kerja":        2,
+		"Kerja,lembur": 1,
+		"liburan":      1,
+		"lembur,libur": 0,
+	}
+	for query, want := range cases {
+		status, body := s.do(t, http.MethodGet, "/api/diary/?tags="+query, token, nil)
+		expect(t, status, http.StatusOK, body)
+		if got := len(body["data"].([]any)); got != want || body["total"] != float64(want) {
+			t.Fatalf("tags=%s returned %d entries (total %v), want %d", query, got, body["total"], want)
+		}
+	}
+
+	counts := s.tagCounts(t, token)
+	if len(counts) != 3 || counts["kerja"] != 2 || counts["lembur"] != 1 || counts["liburan"] != 1 {
+		t.Fatalf("unexpected tag counts: %v", counts)
+	}
+}
+
+func TestTagManagement(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp(t, "budi", "budi@example.com", "secret123")
+	other := s.signUp(t, "siti", "siti@example.com", "secret123")
+
+	first := s.createTaggedEntry(t, token, "satu", "kerja", "kantor")
+	s.createTaggedEntry(t, token, "dua", "pekerjaan")
+	s.createTaggedEntry(t, token, "tiga", "liburan")
+	s.createTaggedEntry(t, other, "punya siti", "kerja")
+
+	status, body := s.do(t, http.MethodPut, "/api/tags/kerja", token, map[string]string{"name": "Pekerjaan"})
+	expect(t, status, http.StatusOK, body)
+	counts := s.tagCounts(t, token)
+	if _, ok := counts["kerja"]; ok || counts["pekerjaan"] != 2 {
+		t.Fatalf("rename did not merge into the existing tag: %v", counts)
+	}
+
+	status, body = s.do(t, http.MethodPost, "/api/tags/merge", token, map[string]any{
+		"tags": []string{"kantor", "pekerjaan"}, "into": "karier",
+	})
+	expect(t, status, http.StatusOK, body)
+	counts = s.tagCounts(t, token)
+	if len(counts) != 2 || counts["karier"] != 2 || counts["liburan"] != 1 {
+		t.Fatalf("unexpected counts after merge: %v", counts)
+	}
+	status, body = s.do(t, http.MethodGet, "/api/diary/"+first, token, nil)
+	expect(t, status, http.StatusOK, body)
+	if tags := body["tags"].([]any); len(tags) != 1 || tags[0] != "karier" {
+		t.Fatalf("merge left duplicate tags on an entry: %v", body["tags"])
+	}
+
+	status, body = s.do(t, http.MethodDelete, "/api/tags/liburan", token, nil)
+	expect(t, status, http.StatusOK, body)
+	status, body = s.do(t, http.MethodDelete, "/api/tags/liburan", token, nil)
+	expect(t, status, http.StatusNotFound, body)
+
+	// Tag user lain tidak ikut berubah
+	if counts := s.tagCounts(t, other); counts["kerja"] != 1 {
+		t.Fatalf("tag management leaked to another user: %v", counts)
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+const (
+	analysisPollInterval = 2 * time.Second
+	analysisTimeout      = 30 * time.Second
+	// Job yang terkunci lebih lama dari ini dianggap worker-nya mati dan diambil ulang
+	analysisLockDuration = 2 * time.Minute
+	analysisBaseBackoff  = 5 * time.Second
+	analysisMaxBackoff   = 10 * time.Minute
+)
+
+// AnalysisQueue menjadwalkan analisis emosi untuk entri diary
+type AnalysisQueue struct {
+	jobs repositories.AnalysisJobRepository
+}
+
+func NewAnalysisQueue(jobs repositories.AnalysisJobRepository) *AnalysisQueue {
+	return &AnalysisQueue{jobs: jobs}
+}
+
+// Enqueue menjadwalkan analisis emosi untuk satu entri diary.
+// Jika entri sudah punya job, job tersebut di-reset ke pending dengan revision baru.
+func (q *AnalysisQueue) Enqueue(ctx context.Context, entryID, userID primitive.ObjectID) error {
+	return q.jobs.Enqueue(ctx, entryID, userID, time.Now())
+}
+
+// Cancel membuang job analisis milik entri yang dihapus
+func (q *AnalysisQueue) Cancel(ctx context.Context, entryID primitive.ObjectID) error {
+	return q.jobs.DeleteByEntry(ctx, entryID)
+}
+
+// CancelUser membuang semua job analisis milik user
+func (q *AnalysisQueue) CancelUser(ctx context.Context, userID primitive.ObjectID) error {
+	return q.jobs.DeleteByUser(ctx, userID)
+}
+
+// AnalysisWorkerPool menjalankan sejumlah worker yang mengambil job pending dari
+// antrian dan menulis hasil emosi/sentimen kembali ke DiaryEntry.
+type AnalysisWorkerPool struct {
+	jobs        repositories.AnalysisJobRepository
+	diaries     repositories.DiaryRepository
+	analyzer    EmotionAnalyzer
+	workers     int
+	maxAttempts int
+	wg          sync.WaitGroup
+}
+
+// NewAnalysisWorkerPool membuat pool worker sesuai konfigurasi analyzer
+func NewAnalysisWorkerPool(jobs repositories.AnalysisJobRepository, diaries repositories.DiaryRepository, analyzer EmotionAnalyzer, cfg config.AnalyzerConfig) *AnalysisWorkerPool {
+	return &AnalysisWorkerPool{
+		jobs:        jobs,
+		diaries:     diaries,
+		analyzer:    analyzer,
+		workers:     cfg.Workers,
+		maxAttempts: cfg.MaxAttempts,
+	}
+}
+
+// Start menjalankan worker di background sampai ctx dibatalkan
+func (p *AnalysisWorkerPool) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.run(ctx)
+		}()
+	}
+	log.Printf("Started %d emotion analysis workers", p.workers)
+}
+
+// Wait menunggu semua worker berhenti setelah ctx dibatalkan
+func (p *AnalysisWorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+// ProcessPending mengerjakan semua job yang siap dijalankan di goroutine pemanggil
+// dan mengembalikan jumlah job yang dikerjakan. Job yang dijadwalkan ulang
+// (backoff) tidak ikut dikerjakan.
+func (p *AnalysisWorkerPool) ProcessPending(ctx context.Context) (int, error) {
+	processed := 0
+	for {
+		job, err := p.claim(ctx)
+		if err != nil || job == nil {
+			return processed, err
+		}
+		p.process(ctx, job)
+		processed++
+	}
+}
+
+func (p *AnalysisWorkerPool) run(ctx context.Context) {
+	for {
+		job, err := p.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim analysis job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(analysisPollInterval):
+			}
+			continue
+		}
+
+		p.process(ctx, job)
+	}
+}
+
+// claim mengambil satu job yang siap dijalankan dan menguncinya untuk worker ini
+func (p *AnalysisWorkerPool) claim(ctx context.Context) (*models.AnalysisJob, error) {
+	now := time.Now()
+	return p.jobs.Claim(ctx, now, now.Add(analysisLockDuration))
+}
+
+func (p *AnalysisWorkerPool) process(ctx context.Context, job *models.AnalysisJob) {
+	entry, err := p.diaries.Get(ctx, job.EntryID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			// Entri sudah dihapus, job tidak perlu dikerjakan lagi
+			_ = p.jobs.Delete(ctx, job.ID, job.Revision)
+			return
+		}
+		p.retry(ctx, job, err)
+		return
+	}
+	if entry.Encrypted {
+		// Entri dienkripsi setelah di-enqueue; isinya tidak bisa dianalisis server
+		_ = p.jobs.Delete(ctx, job.ID, job.Revision)
+		return
+	}
+
+	// Analyzer hanya menerima teks, tanpa tag HTML atau sintaks Markdown
+	analyzeCtx, cancel := context.WithTimeout(ctx, analysisTimeout)
+	result, err := p.analyzer.Analyze(analyzeCtx, PlainText(entry.ContentFormat, entry.Content))
+	cancel()
+	if err == nil {
+		err = result.Normalize()
+	}
+	if err != nil {
+		p.retry(ctx, job, err)
+		return
+	}
+
+	// Hanya tulis hasil jika konten belum berubah sejak dianalisis;
+	// perubahan konten sudah meng-enqueue revision baru. Koreksi user tidak ditimpa.
+	_, err = p.diaries.SetAnalysis(ctx, entry.ID, repositories.DiaryAnalysis{
+		Emotion:         result.Emotion,
+		EmotionSource:   models.SourceModel,
+		Sentiment:       result.Sentiment,
+		SentimentSource: models.SourceModel,
+		Status:          models.AnalysisDone,
+		Detail:          &result.Detail,
+		AnalyzedAt:      time.Now(),
+		ForContent:      &entry.Content,
+	})
+	if err != nil {
+		p.retry(ctx, job, err)
+		return
+	}
+
+	p.finish(ctx, job, repositories.AnalysisJobUpdate{Status: models.AnalysisDone})
+}
+
+// retry menjadwalkan ulang job dengan exponential backoff, atau menandainya
+// gagal jika jumlah percobaan sudah habis
+func (p *AnalysisWorkerPool) retry(ctx context.Context, job *models.AnalysisJob, cause error) {
+	attempts := job.Attempts + 1
+	lastError := cause.Error()
+	log.Printf("Emotion analysis for entry %s failed (attempt %d/%d): %v", job.EntryID.Hex(), attempts, p.maxAttempts, cause)
+
+	if attempts >= p.maxAttempts {
+		updated := p.finish(ctx, job, repositories.AnalysisJobUpdate{
+			Status:    models.AnalysisFailed,
+			Attempts:  &attempts,
+			LastError: &lastError,
+		})
+		if !updated {
+			// Entri sudah di-enqueue ulang, biarkan revision baru yang menentukan statusnya
+			return
+		}
+
+		_, err := p.diaries.SetAnalysis(ctx, job.EntryID, repositories.DiaryAnalysis{
+			Emotion:         models.EmotionUnknown,
+			EmotionSource:   models.SourceModel,
+			Sentiment:       models.SentimentNeutral,
+			SentimentSource: models.SourceModel,
+			Status:          models.AnalysisFailed,
+		})
+		if err != nil {
+			log.Printf("Failed to mark diary entry %s as failed: %v", job.EntryID.Hex(), err)
+		}
+		return
+	}
+
+	nextRunAt := time.Now().Add(backoff(attempts))
+	p.finish(ctx, job, repositories.AnalysisJobUpdate{
+		Status:    models.AnalysisPending,
+		Attempts:  &attempts,
+		LastError: &lastError,
+		NextRunAt: &nextRunAt,
+	})
+}
+
+// finish memperbarui job hanya jika belum di-enqueue ulang sejak diklaim,
+// dan melaporkan apakah job tersebut benar-benar diperbarui
+func (p *AnalysisWorkerPool) finish(ctx context.Context, job *models.AnalysisJob, update repositories.AnalysisJobUpdate) bool {
+	update.UpdatedAt = time.Now()
+	updated, err := p.jobs.Update(ctx, job.ID, job.Revision, update)
+	if err != nil {
+		log.Printf("Failed to update analysis job %s: %v", job.ID.Hex(), err)
+		return false
+	}
+	return updated
+}
+
+// backoff menghitung jeda sebelum percobaan berikutnya: base * 2^(attempt-1) + jitter
+func backoff(attempt int) time.Duration {
+	d := analysisBaseBackoff << (attempt - 1)
+	if d <= 0 || d > analysisMaxBackoff {
+		d = analysisMaxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d/4)+1))
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+)
+
+// EmotionAnalyzer adalah backend yang menganalisis emosi dan sentimen dari teks diary.
+// Respons yang tidak bisa dibaca dikembalikan sebagai error agar job dicoba ulang.
+type EmotionAnalyzer interface {
+	Analyze(ctx context.Context, text string) (*AnalysisResult, error)
+}
+
+// AnalysisResult adalah hasil analisis satu teks: emosi dominan, label sentimen, dan rinciannya
+type AnalysisResult struct {
+	Emotion   models.Emotion
+	Sentiment models.Sentiment
+	Detail    models.AnalysisDetail
+}
+
+const (
+	// Emosi dengan skor minimal ini ikut dicantumkan di Detail.Emotions
+	emotionLabelThreshold = 0.3
+	// Skor sentimen di antara -neutralSentiment dan neutralSentiment dianggap netral
+	neutralSentiment = 0.2
+)
+
+// newAnalysisResult merangkum skor mentah dari analyzer: skor di-clamp ke rentang yang
+// valid, emosi dominan dipilih dari skor tertinggi (seri diputus sesuai urutan EmotionLabels),
+// dan sentimen kosong diturunkan dari skornya. Tanpa skor emosi sama sekali, emosinya
+// models.EmotionUnknown. Kunci scores harus sudah berupa label kanonik.
+func newAnalysisResult(scores map[string]float64, sentiment models.Sentiment, sentimentScore, confidence float64) *AnalysisResult {
+	result := &AnalysisResult{
+		Emotion:   models.EmotionUnknown,
+		Sentiment: sentiment,
+		Detail: models.AnalysisDetail{
+			Scores:         map[string]float64{},
+			SentimentScore: clamp(sentimentScore, -1, 1),
+			Confidence:     clamp(confidence, 0, 1),
+		},
+	}
+
+	best := 0.0
+	for _, label := range EmotionLabels {
+		score := clamp(scores[label], 0, 1)
+		result.Detail.Scores[label] = score
+		if score > best {
+			result.Emotion, best = models.Emotion(label), score
+		}
+		if score >= emotionLabelThreshold {
+			result.Detail.Emotions = append(result.Detail.Emotions, label)
+		}
+	}
+	sort.SliceStable(result.Detail.Emotions, func(i, j int) bool {
+		return result.Detail.Scores[result.Detail.Emotions[i]] > result.Detail.Scores[result.Detail.Emotions[j]]
+	})
+
+	if result.Sentiment == "" {
+		switch score := result.Detail.SentimentScore; {
+		case score >= neutralSentiment:
+			result.Sentiment = models.SentimentPositive
+		case score <= -neutralSentiment:
+			result.Sentiment = models.SentimentNegative
+		default:
+			result.Sentiment = models.SentimentNeutral
+		}
+	}
+	return result
+}
+
+// Normalize menyeragamkan label hasil analyzer ke nilai kanonik, termasuk sinonim yang sering
+// dikembalikan model ("Happy", "sadness", "positive "). Label yang tidak dikenal dikembalikan
+// sebagai error agar job dicoba ulang, bukan disimpan apa adanya.
+func (r *AnalysisResult) Normalize() error {
+	emotion, ok := models.MatchEmotion(string(r.Emotion))
+	if !ok && strings.EqualFold(strings.TrimSpace(string(r.Emotion)), string(models.EmotionUnknown)) {
+		// Tidak ada emosi yang terdeteksi
+		emotion, ok = models.EmotionUnknown, true
+	}
+	if !ok {
+		return fmt.Errorf("analyzer returned invalid emotion %q", r.Emotion)
+	}
+	r.Emotion = emotion
+
+	sentiment, ok := models.MatchSentiment(string(r.Sentiment))
+	if !ok {
+		return fmt.Errorf("analyzer returned invalid sentiment %q", r.Sentiment)
+	}
+	r.Sentiment = sentiment
+	return nil
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// NewEmotionAnalyzer membuat analyzer sesuai backend yang dipilih di konfigurasi
+func NewEmotionAnalyzer(cfg config.AnalyzerConfig) (EmotionAnalyzer, error) {
+	switch cfg.Backend {
+	case config.AnalyzerGemini:
+		return NewGeminiAnalyzer(cfg.GeminiAPIKey)
+	case config.AnalyzerLexicon:
+		return NewLexiconAnalyzer(), nil
+	default:
+		return nil, fmt.Errorf("unknown emotion analyzer %q", cfg.Backend)
+	}
+}
+
+// Label yang bisa dihasilkan analyzer; dipakai juga untuk memvalidasi hasil analisis dari client
+var (
+	EmotionLabels   = labelStrings(models.Emotions)
+	SentimentLabels = labelStrings(models.Sentiments)
+)
+
+func labelStrings[T ~string](labels []T) []string {
+	out := make([]string, len(labels))
+	for i, label := range labels {
+		out[i] = string(label)
+	}
+	return out
+}
+
+// NormalizeClientAnalysis memvalidasi emosi dan sentimen yang dihitung client untuk entri
+// terenkripsi, karena server tidak bisa membaca isinya untuk dianalisis sendiri
+func NormalizeClientAnalysis(emotion, sentiment string) (models.Emotion, models.Sentiment, error) {
+	e, err := NormalizeEmotion(emotion)
+	if err != nil {
+		return "", "", err
+	}
+	s, err := NormalizeSentiment(sentiment)
+	if err != nil {
+		return "", "", err
+	}
+	return e, s, nil
+}
+
+// NormalizeEmotion memvalidasi label emosi dari client atau koreksi user. Berbeda dengan hasil
+// model, sinonim tidak diterima di sini.
+func NormalizeEmotion(emotion string) (models.Emotion, error) {
+	e, ok := models.ParseEmotion(emotion)
+	if !ok {
+		return "", fmt.Errorf("emotion must be one of %s", strings.Join(EmotionLabels, ", "))
+	}
+	return e, nil
+}
+
+// NormalizeSentiment memvalidasi label sentimen dari client atau koreksi user
+func NormalizeSentiment(sentiment string) (models.Sentiment, error) {
+	s, ok := models.ParseSentiment(sentiment)
+	if !ok {
+		return "", fmt.Errorf("sentiment must be one of %s", strings.Join(SentimentLabels, ", "))
+	}
+	return s, nil
+}
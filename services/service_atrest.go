@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+// Ukuran data key AES-256
+const dataKeyBytes = 32
+
+// AtRestCipher mengenkripsi judul/isi diary dengan AES-256-GCM memakai data key per user.
+// Data key dibuat saat pertama kali dibutuhkan dan disimpan terbungkus oleh master key
+// dari konfigurasi; data key yang sudah dibuka disimpan di memori proses.
+type AtRestCipher struct {
+	keys        repositories.DataKeyRepository
+	masterKeys  map[string][]byte
+	masterKeyID string
+
+	mu     sync.Mutex
+	opened map[dataKeyRef]cipher.AEAD
+}
+
+type dataKeyRef struct {
+	userID  primitive.ObjectID
+	version int
+}
+
+func NewAtRestCipher(cfg config.AtRestConfig, keys repositories.DataKeyRepository) (*AtRestCipher, error) {
+	masterKeys, err := cfg.Keyring()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := masterKeys[cfg.MasterKeyID]; !ok {
+		return nil, fmt.Errorf("master key %q not found", cfg.MasterKeyID)
+	}
+	return &AtRestCipher{
+		keys:        keys,
+		masterKeys:  masterKeys,
+		masterKeyID: cfg.MasterKeyID,
+		opened:      map[dataKeyRef]cipher.AEAD{},
+	}, nil
+}
+
+// Seal mengenkripsi judul dan isi dengan data key terbaru milik user. Judul/isi kosong tetap
+// kosong agar tidak disimpan, sama seperti entri plaintext.
+func (c *AtRestCipher) Seal(ctx context.Context, text repositories.StoredText) (repositories.StoredText, error) {
+	if text.Format != models.StoragePlaintext {
+		return text, fmt.Errorf("text is already sealed with format %d", text.Format)
+	}
+	key, err := c.latestKey(ctx, text.UserID)
+	if err != nil {
+		return text, err
+	}
+	aead, err := c.dataKey(key)
+	if err != nil {
+		return text, err
+	}
+
+	if text.Title, err = seal(aead, text.Title, textAAD(text.UserID, "title")); err != nil {
+		return text, err
+	}
+	if text.Content, err = seal(aead, text.Content, textAAD(text.UserID, "content")); err != nil {
+		return text, err
+	}
+	text.StorageTag = repositories.StorageTag{Format: models.StorageAESGCM, KeyVersion: key.Version}
+	return text, nil
+}
+
+func (c *AtRestCipher) Open(ctx context.Context, text repositories.StoredText) (repositories.StoredText, error) {
+	switch text.Format {
+	case models.StoragePlaintext:
+		return text, nil
+	case models.StorageAESGCM:
+	default:
+		return text, fmt.Errorf("document %s has unknown storage format %d", text.ID.Hex(), text.Format)
+	}
+
+	aead, err := c.dataKeyVersion(ctx, text.UserID, text.KeyVersion)
+	if err != nil {
+		return text, err
+	}
+	title, err := open(aead, text.Title, textAAD(text.UserID, "title"))
+	if err != nil {
+		return text, fmt.Errorf("decrypt title of %s: %w", text.ID.Hex(), err)
+	}
+	content, err := open(aead, text.Content, textAAD(text.UserID, "content"))
+	if err != nil {
+		return text, fmt.Errorf("decrypt content of %s: %w", text.ID.Hex(), err)
+	}
+	text.Title, text.Content = title, content
+	text.StorageTag = repositories.StorageTag{}
+	return text, nil
+}
+
+func (c *AtRestCipher) Forget(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := c.keys.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ref := range c.opened {
+		if ref.userID == userID {
+			delete(c.opened, ref)
+		}
+	}
+	return nil
+}
+
+// RotateDataKey membuat data key versi baru untuk user. Entri lama tetap bisa dibuka dengan
+// versi sebelumnya sampai dienkripsi ulang oleh Reencrypt.
+func (c *AtRestCipher) RotateDataKey(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	latest, err := c.keys.Latest(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	key, err := c.createKey(ctx, userID, latest.Version+1)
+	if err != nil {
+		return 0, err
+	}
+	return key.Version, nil
+}
+
+// latestKey mengembalikan data key terbaru milik user, membuat versi pertama jika belum ada
+func (c *AtRestCipher) latestKey(ctx context.Context, userID primitive.ObjectID) (*models.DataKey, error) {
+	key, err := c.keys.Latest(ctx, userID)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return key, err
+	}
+	key, err = c.createKey(ctx, userID, 1)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		// Request lain baru saja membuat kunci pertama user ini
+		return c.keys.Latest(ctx, userID)
+	}
+	return key, err
+}
+
+func (c *AtRestCipher) createKey(ctx context.Context, userID primitive.ObjectID, version int) (*models.DataKey, error) {
+	raw := make([]byte, dataKeyBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	key := &models.DataKey{
+		UserID:      userID,
+		Version:     version,
+		MasterKeyID: c.masterKeyID,
+		CreatedAt:   time.Now(),
+	}
+	wrapped, err := c.wrap(key, raw)
+	if err != nil {
+		return nil, err
+	}
+	key.WrappedKey = wrapped
+	if err := c.keys.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (c *AtRestCipher) dataKeyVersion(ctx context.Context, userID primitive.ObjectID, version int) (cipher.AEAD, error) {
+	c.mu.Lock()
+	aead, ok := c.opened[dataKeyRef{userID, version}]
+	c.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	key, err := c.keys.Find(ctx, userID, version)
+	if err != nil {
+		return nil, fmt.Errorf("data key %d of user %s: %w", version, userID.Hex(), err)
+	}
+	return c.dataKey(key)
+}
+
+// dataKey membuka data key terbungkus dan menyimpannya di cache
+func (c *AtRestCipher) dataKey(key *models.DataKey) (cipher.AEAD, error) {
+	ref := dataKeyRef{key.UserID, key.Version}
+	c.mu.Lock()
+	aead, ok := c.opened[ref]
+	c.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	raw, err := c.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+	if aead, err = newGCM(raw); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.opened[ref] = aead
+	c.mu.Unlock()
+	return aead, nil
+}
+
+// Rewrap membungkus ulang data key dengan master key aktif jika masih memakai master key lain
+func (c *AtRestCipher) Rewrap(ctx context.Context, key *models.DataKey) (bool, error) {
+	if key.MasterKeyID == c.masterKeyID {
+		return false, nil
+	}
+	raw, err := c.unwrap(key)
+	if err != nil {
+		return false, err
+	}
+	wrapped, err := c.wrap(&models.DataKey{UserID: key.UserID, Version: key.Version, MasterKeyID: c.masterKeyID}, raw)
+	if err != nil {
+		return false, err
+	}
+	return c.keys.Rewrap(ctx, key.ID, key.MasterKeyID, c.masterKeyID, wrapped)
+}
+
+func (c *AtRestCipher) wrap(key *models.DataKey, raw []byte) ([]byte, error) {
+	master, err := newGCM(c.masterKeys[key.MasterKeyID])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, master.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return master.Seal(nonce, nonce, raw, keyAAD(key)), nil
+}
+
+func (c *AtRestCipher) unwrap(key *models.DataKey) ([]byte, error) {
+	secret, ok := c.masterKeys[key.MasterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("data key %d of user %s is wrapped by unknown master key %q", key.Version, key.UserID.Hex(), key.MasterKeyID)
+	}
+	master, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(key.WrappedKey) < master.NonceSize() {
+		return nil, errors.New("wrapped data key is too short")
+	}
+	nonce, sealed := key.WrappedKey[:master.NonceSize()], key.WrappedKey[master.NonceSize():]
+	raw, err := master.Open(nil, nonce, sealed, keyAAD(key))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key %d of user %s: %w", key.Version, key.UserID.Hex(), err)
+	}
+	return raw, nil
+}
+
+// keyAAD mengikat data key terbungkus ke pemilik dan versinya
+func keyAAD(key *models.DataKey) []byte {
+	return []byte(fmt.Sprintf("data-key:%s:%d", key.UserID.Hex(), key.Version))
+}
+
+// textAAD mengikat ciphertext ke pemilik dan field-nya, sehingga tidak bisa dipindah ke user
+// lain atau ditukar antara judul dan isi
+func textAAD(userID primitive.ObjectID, field string) []byte {
+	return []byte(userID.Hex() + ":" + field)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal menghasilkan base64 dari nonce diikuti ciphertext dan tag
+func seal(aead cipher.AEAD, plaintext string, aad []byte) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plaintext), aad)), nil
+}
+
+func open(aead cipher.AEAD, sealed string, aad []byte) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	plaintext, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+var (
+	ErrAttachmentTooLarge  = errors.New("attachment is too large")
+	ErrAttachmentType      = errors.New("attachment type is not allowed")
+	ErrTooManyAttachments  = errors.New("entry has too many attachments")
+	ErrAttachmentNoPreview = errors.New("attachment has no thumbnail")
+)
+
+const (
+	maxAttachmentFilename = 255
+	encryptedContentType  = "application/octet-stream"
+)
+
+// AttachmentService mengelola lampiran entri diary: metadata di repository dan isi file di blob store
+type AttachmentService struct {
+	attachments repositories.AttachmentRepository
+	blobs       BlobStore
+	cfg         config.AttachmentConfig
+}
+
+func NewAttachmentService(attachments repositories.AttachmentRepository, blobs BlobStore, cfg config.AttachmentConfig) *AttachmentService {
+	return &AttachmentService{attachments: attachments, blobs: blobs, cfg: cfg}
+}
+
+// MaxSize adalah batas ukuran satu file dalam byte
+func (s *AttachmentService) MaxSize() int {
+	return s.cfg.MaxSize
+}
+
+// Upload menyimpan file sebagai lampiran entri. Tipe file ditentukan dari isinya;
+// gambar yang bisa di-decode juga dibuatkan thumbnail. Lampiran entri terenkripsi sudah
+// dienkripsi client sehingga disimpan apa adanya sebagai application/octet-stream.
+func (s *AttachmentService) Upload(ctx context.Context, entry *models.DiaryEntry, filename string, data io.Reader) (*models.Attachment, error) {
+	count, err := s.attachments.CountByEntry(ctx, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.cfg.MaxPerEntry) {
+		return nil, fmt.Errorf("%w (max %d)", ErrTooManyAttachments, s.cfg.MaxPerEntry)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(data, int64(s.cfg.MaxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > s.cfg.MaxSize {
+		return nil, fmt.Errorf("%w (max %d bytes)", ErrAttachmentTooLarge, s.cfg.MaxSize)
+	}
+
+	contentType := encryptedContentType
+	if !entry.Encrypted {
+		contentType = detectContentType(raw)
+		if !s.allowed(contentType) {
+			return nil, fmt.Errorf("%w: %s", ErrAttachmentType, contentType)
+		}
+	}
+
+	attachment := &models.Attachment{
+		ID:          primitive.NewObjectID(),
+		EntryID:     entry.ID,
+		UserID:      entry.UserID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        int64(len(raw)),
+		CreatedAt:   time.Now(),
+	}
+	attachment.BlobKey = fmt.Sprintf("%s/%s/%s", entry.UserID.Hex(), entry.ID.Hex(), attachment.ID.Hex())
+
+	if err := s.blobs.Put(ctx, attachment.BlobKey, bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("store attachment: %w", err)
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if thumb, width, height, ok := imageThumbnail(raw, s.cfg.ThumbnailSize); ok {
+			attachment.Width = width
+			attachment.Height = height
+			thumbKey := attachment.BlobKey + "-thumb"
+			if err := s.blobs.Put(ctx, thumbKey, bytes.NewReader(thumb)); err != nil {
+				// Lampiran tetap bisa dipakai tanpa thumbnail
+				log.Printf("Failed to store attachment thumbnail: %v", err)
+			} else {
+				attachment.ThumbnailKey = thumbKey
+				attachment.HasThumbnail = true
+			}
+		}
+	}
+
+	if err := s.attachments.Create(ctx, attachment); err != nil {
+		s.deleteBlobs(ctx, []models.Attachment{*attachment})
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (s *AttachmentService) allowed(contentType string) bool {
+	for _, t := range s.cfg.AllowedTypes {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContentType menentukan tipe file dari isinya tanpa parameter seperti charset
+func detectContentType(raw []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(raw))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// cleanFilename membuang path dan karakter kontrol dari nama file kiriman client
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	for len(name) > maxAttachmentFilename {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func (s *AttachmentService) List(ctx context.Context, userID, entryID primitive.ObjectID) ([]models.Attachment, error) {
+	return s.attachments.ListByEntry(ctx, userID, entryID)
+}
+
+func (s *AttachmentService) Find(ctx context.Context, userID, entryID, id primitive.ObjectID) (*models.Attachment, error) {
+	return s.attachments.FindByID(ctx, userID, entryID, id)
+}
+
+// Open membuka isi lampiran atau thumbnail-nya
+func (s *AttachmentService) Open(ctx context.Context, attachment *models.Attachment, thumbnail bool) (io.ReadCloser, error) {
+	if !thumbnail {
+		return s.blobs.Open(ctx, attachment.BlobKey)
+	}
+	if !attachment.HasThumbnail {
+		return nil, ErrAttachmentNoPreview
+	}
+	return s.blobs.Open(ctx, attachment.ThumbnailKey)
+}
+
+// Delete menghapus satu lampiran beserta file-nya
+func (s *AttachmentService) Delete(ctx context.Context, attachment *models.Attachment) error {
+	if err := s.attachments.Delete(ctx, attachment.ID); err != nil {
+		return err
+	}
+	s.deleteBlobs(ctx, []models.Attachment{*attachment})
+	return nil
+}
+
+// DeleteForEntries menghapus semua lampiran milik entri yang akan dihapus permanen. Metadata
+// lampiran yang file-nya gagal dihapus dibiarkan, sehingga bisa dicoba lagi tanpa meninggalkan
+// file yatim.
+func (s *AttachmentService) DeleteForEntries(ctx context.Context, entryIDs []primitive.ObjectID) error {
+	attachments, err := s.attachments.ListByEntries(ctx, entryIDs)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, attachment := range attachments {
+		if err := s.deleteFiles(ctx, attachment); err != nil {
+			errs = append(errs, fmt.Errorf("delete attachment %s: %w", attachment.ID.Hex(), err))
+			continue
+		}
+		if err := s.attachments.Delete(ctx, attachment.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeleteForUser menghapus semua lampiran milik user yang menghapus akunnya
+func (s *AttachmentService) DeleteForUser(ctx context.Context, userID primitive.ObjectID) error {
+	attachments, err := s.attachments.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	s.deleteBlobs(ctx, attachments)
+	_, err = s.attachments.DeleteByUser(ctx, userID)
+	return err
+}
+
+// deleteBlobs menghapus file lampiran; kegagalan hanya dicatat karena metadata tetap dihapus
+func (s *AttachmentService) deleteBlobs(ctx context.Context, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		if err := s.deleteFiles(ctx, attachment); err != nil {
+			log.Printf("Failed to delete attachment blob: %v", err)
+		}
+	}
+}
+
+// deleteFiles menghapus file lampiran beserta thumbnail-nya
+func (s *AttachmentService) deleteFiles(ctx context.Context, attachment models.Attachment) error {
+	for _, key := range []string{attachment.BlobKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			return fmt.Errorf("delete blob %s: %w", key, err)
+		}
+	}
+	return nil
+}
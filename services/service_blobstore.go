@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/config"
+)
+
+// ErrBlobNotFound dikembalikan Open jika blob dengan kunci tersebut tidak ada
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore menyimpan isi file lampiran berdasarkan kunci. Kunci hanya berisi
+// huruf kecil, angka, '-' dan '/' sehingga aman dipakai sebagai path.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete menghapus blob; blob yang sudah tidak ada tidak dianggap error
+	Delete(ctx context.Context, key string) error
+}
+
+var blobKeyPattern = regexp.MustCompile(`^[a-z0-9-]+(/[a-z0-9-]+)*$`)
+
+func checkBlobKey(key string) error {
+	if !blobKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
+
+// NewBlobStore memilih penyimpanan lampiran sesuai ATTACHMENT_STORE
+func NewBlobStore(cfg config.AttachmentConfig, db *config.DB) (BlobStore, error) {
+	switch cfg.Store {
+	case config.BlobStoreLocal:
+		return NewLocalBlobStore(cfg.Dir)
+	case config.BlobStoreGridFS:
+		return NewGridFSBlobStore(db.Database)
+	default:
+		return nil, fmt.Errorf("unknown attachment store %q", cfg.Store)
+	}
+}
+
+// LocalBlobStore menyimpan blob sebagai file biasa di bawah satu folder
+type LocalBlobStore struct {
+	dir string
+}
+
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create attachment dir: %w", err)
+	}
+	return &LocalBlobStore{dir: dir}, nil
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, data io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Tulis ke file sementara dulu agar pembaca tidak pernah melihat file setengah jadi
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// GridFSBlobStore menyimpan blob di GridFS bucket 'attachment_blobs'; kunci dipakai sebagai _id file
+type GridFSBlobStore struct {
+	bucket *gridfs.Bucket
+}
+
+func NewGridFSBlobStore(db *mongo.Database) (*GridFSBlobStore, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("attachment_blobs"))
+	if err != nil {
+		return nil, fmt.Errorf("open gridfs bucket: %w", err)
+	}
+	return &GridFSBlobStore{bucket: bucket}, nil
+}
+
+func (s *GridFSBlobStore) Put(ctx context.Context, key string, data io.Reader) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	// Upload GridFS belum menerima context, jadi deadline ctx diterapkan lewat bucket
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := s.bucket.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	return s.bucket.UploadFromStreamWithID(key, key, data)
+}
+
+func (s *GridFSBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	stream, err := s.bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *GridFSBlobStore) Delete(ctx context.Context, key string) error {
+	if err := s.bucket.DeleteContext(ctx, key); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return nil
+}
+
+// MemoryBlobStore menyimpan blob di memori proses, untuk test dan pengembangan lokal
+type MemoryBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: map[string][]byte{}}
+}
+
+func (s *MemoryBlobStore) Put(ctx context.Context, key string, data io.Reader) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = raw
+	return nil
+}
+
+func (s *MemoryBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(raw)), nil
+}
+
+func (s *MemoryBlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+// Len mengembalikan jumlah blob yang tersimpan
+func (s *MemoryBlobStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.blobs)
+}
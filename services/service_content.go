@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
+	"web-diary-be/models"
+)
+
+// ContentFormats adalah nilai content_format yang diterima
+var ContentFormats = []string{models.ContentPlain, models.ContentMarkdown, models.ContentHTML}
+
+var (
+	// htmlPolicy mengizinkan format teks umum (heading, list, tautan, gambar, tabel) tanpa
+	// script, style, event handler atau URL javascript:
+	htmlPolicy = bluemonday.UGCPolicy()
+	// textPolicy membuang semua tag, dipakai untuk mengambil teks biasa
+	textPolicy = bluemonday.StrictPolicy()
+	// markdown tidak merender HTML mentah di dalam Markdown; hasilnya tetap disanitasi
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	blockEnd  = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|blockquote|pre|tr)>`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// NormalizeContentFormat menyeragamkan content_format; kosong berarti plain
+func NormalizeContentFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		return models.ContentPlain, nil
+	}
+	if !containsFold(ContentFormats, format) {
+		return "", fmt.Errorf("content_format must be one of %s", strings.Join(ContentFormats, ", "))
+	}
+	return format, nil
+}
+
+// PrepareContent memvalidasi format dan menyanitasi isi HTML sebelum disimpan.
+// Markdown disimpan apa adanya karena baru disanitasi saat dirender.
+func PrepareContent(format, content string) (string, string, error) {
+	format, err := NormalizeContentFormat(format)
+	if err != nil {
+		return "", "", err
+	}
+	if format == models.ContentHTML {
+		content = strings.TrimSpace(htmlPolicy.Sanitize(content))
+	}
+	return format, content, nil
+}
+
+// RenderContent mengubah isi entri menjadi HTML yang aman ditampilkan
+func RenderContent(format, content string) (string, error) {
+	switch format {
+	case "", models.ContentPlain:
+		paragraphs := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n")
+		var b strings.Builder
+		for _, p := range paragraphs {
+			if p = strings.TrimSpace(p); p != "" {
+				fmt.Fprintf(&b, "<p>%s</p>\n", strings.ReplaceAll(html.EscapeString(p), "\n", "<br>\n"))
+			}
+		}
+		return b.String(), nil
+	case models.ContentMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			return "", err
+		}
+		return htmlPolicy.Sanitize(buf.String()), nil
+	case models.ContentHTML:
+		return htmlPolicy.Sanitize(content), nil
+	}
+	return "", fmt.Errorf("unknown content format %q", format)
+}
+
+// PlainText mengambil teks tanpa markup dari isi entri, dipakai sebagai input analisis emosi
+// agar tag HTML dan sintaks Markdown tidak ikut dinilai
+func PlainText(format, content string) string {
+	if format == "" || format == models.ContentPlain {
+		return content
+	}
+	rendered, err := RenderContent(format, content)
+	if err != nil {
+		return content
+	}
+	text := textPolicy.Sanitize(blockEnd.ReplaceAllString(rendered, "$0\n"))
+	text = html.UnescapeString(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
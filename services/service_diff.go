@@ -0,0 +1,104 @@
+package services
+
+import "strings"
+
+// Jenis operasi di hasil diff
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// Di atas ukuran tabel ini diff tidak dihitung baris per baris, bagian yang berubah
+// cukup ditampilkan sebagai dihapus lalu ditambahkan
+const maxDiffCells = 1_000_000
+
+// DiffOp adalah satu baris hasil diff
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines membandingkan dua teks per baris memakai longest common subsequence
+func DiffLines(from, to string) []DiffOp {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// Baris awal dan akhir yang sama tidak perlu masuk tabel LCS
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]DiffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, DiffOp{Op: DiffEqual, Text: line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, DiffOp{Op: DiffEqual, Text: line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []DiffOp {
+	var ops []DiffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, DiffOp{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			ops = append(ops, DiffOp{Op: DiffInsert, Text: line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] adalah panjang LCS dari a[i:] dan b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffOp{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffOp{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffOp{Op: DiffInsert, Text: b[j]})
+	}
+	return ops
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"web-diary-be/models"
+)
+
+// Cipher dan KDF yang boleh dipakai client untuk mode enkripsi end-to-end
+var (
+	EncryptionCiphers = []string{"aes-256-gcm", "xchacha20-poly1305"}
+	EncryptionKDFs    = []string{"argon2id", "pbkdf2-sha256"}
+)
+
+const (
+	minKDFSaltBytes     = 16
+	minPBKDF2Iterations = 100_000
+	minArgon2MemoryKiB  = 19 * 1024
+	// Data key 32 byte yang dibungkus AES-KW (40 byte) sampai AEAD dengan nonce 24 byte (72 byte)
+	minWrappedKeyBytes = 40
+	maxWrappedKeyBytes = 128
+	// Ciphertext paling tidak berisi nonce 12 byte dan tag autentikasi 16 byte
+	minCiphertextBytes = 28
+)
+
+var ErrInvalidCiphertext = errors.New("value must be base64 ciphertext produced by the client")
+
+// ValidateEncryptionKey memeriksa metadata kunci yang dikirim client. Server tidak bisa
+// memeriksa kebenaran kuncinya, hanya bahwa parameternya masuk akal dan cukup kuat.
+func ValidateEncryptionKey(key *models.EncryptionKey) error {
+	var errs []error
+	if !containsFold(EncryptionCiphers, key.Cipher) {
+		errs = append(errs, fmt.Errorf("cipher must be one of %s", strings.Join(EncryptionCiphers, ", ")))
+	}
+
+	kdf := key.KDF
+	switch strings.ToLower(kdf.Algorithm) {
+	case "pbkdf2-sha256":
+		if kdf.Iterations < minPBKDF2Iterations {
+			errs = append(errs, fmt.Errorf("kdf.iterations must be at least %d for pbkdf2-sha256", minPBKDF2Iterations))
+		}
+	case "argon2id":
+		if kdf.Iterations < 1 || kdf.Parallelism < 1 {
+			errs = append(errs, errors.New("kdf.iterations and kdf.parallelism must be positive for argon2id"))
+		}
+		if kdf.MemoryKiB < minArgon2MemoryKiB {
+			errs = append(errs, fmt.Errorf("kdf.memory_kib must be at least %d for argon2id", minArgon2MemoryKiB))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("kdf.algorithm must be one of %s", strings.Join(EncryptionKDFs, ", ")))
+	}
+
+	if salt, err := base64.StdEncoding.DecodeString(kdf.Salt); err != nil || len(salt) < minKDFSaltBytes {
+		errs = append(errs, fmt.Errorf("kdf.salt must be base64 with at least %d bytes", minKDFSaltBytes))
+	}
+	if wrapped, err := base64.StdEncoding.DecodeString(key.WrappedKey); err != nil || len(wrapped) < minWrappedKeyBytes || len(wrapped) > maxWrappedKeyBytes {
+		errs = append(errs, fmt.Errorf("wrapped_key must be base64 with %d to %d bytes", minWrappedKeyBytes, maxWrappedKeyBytes))
+	}
+	return errors.Join(errs...)
+}
+
+// NormalizeEncryptionKey menyeragamkan huruf nama cipher dan KDF
+func NormalizeEncryptionKey(key *models.EncryptionKey) {
+	key.Cipher = strings.ToLower(key.Cipher)
+	key.KDF.Algorithm = strings.ToLower(key.KDF.Algorithm)
+	if key.KDF.Algorithm != "argon2id" {
+		key.KDF.MemoryKiB = 0
+		key.KDF.Parallelism = 0
+	}
+}
+
+// ValidateCiphertext memastikan nilai judul/isi entri terenkripsi berbentuk ciphertext.
+// Judul kosong diperbolehkan.
+func ValidateCiphertext(value string, allowEmpty bool) error {
+	if value == "" && allowEmpty {
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) < minCiphertextBytes {
+		return ErrInvalidCiphertext
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+// Jumlah entri yang dibaca per batch saat membuat ekspor
+const exportBatch = 100
+
+// ExportProfile adalah profil user di dalam ekspor, tanpa password dan token internal.
+// Metadata kunci end-to-end ikut diekspor karena dibutuhkan untuk membuka entri terenkripsi.
+type ExportProfile struct {
+	ID            primitive.ObjectID    `json:"id"`
+	Username      string                `json:"username"`
+	Email         string                `json:"email"`
+	EmailVerified bool                  `json:"email_verified"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at,omitempty"`
+	Encryption    *models.EncryptionKey `json:"encryption,omitempty"`
+}
+
+// ExportEntry adalah satu entri diary di dalam ekspor beserta daftar lampirannya
+type ExportEntry struct {
+	models.DiaryEntry
+	Attachments []ExportAttachment `json:"attachments,omitempty"`
+}
+
+type ExportAttachment struct {
+	models.Attachment
+	// Path adalah lokasi file di dalam ZIP; kosong pada ekspor JSON
+	Path string `json:"path,omitempty"`
+}
+
+// AccountExporter menulis seluruh data akun user (profil, entri diary termasuk yang ada di
+// tempat sampah, dan lampiran) sebagai JSON atau ZIP. Entri dibaca per batch dan langsung
+// ditulis ke writer, sehingga ekspor besar tidak dimuat sekaligus ke memori.
+type AccountExporter struct {
+	diaries     repositories.DiaryRepository
+	attachments *AttachmentService
+}
+
+func NewAccountExporter(diaries repositories.DiaryRepository, attachments *AttachmentService) *AccountExporter {
+	return &AccountExporter{diaries: diaries, attachments: attachments}
+}
+
+func exportProfile(user *models.User) ExportProfile {
+	return ExportProfile{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Encryption:    user.Encryption,
+	}
+}
+
+// eachEntry memanggil fn untuk setiap entri user, entri aktif lebih dulu lalu isi tempat sampah
+func (e *AccountExporter) eachEntry(ctx context.Context, userID primitive.ObjectID, fn func(*models.DiaryEntry) error) error {
+	for _, trashed := range []bool{false, true} {
+		filter := repositories.DiaryFilter{UserID: userID, Trashed: trashed}
+		var after *repositories.DiaryCursor
+		for {
+			entries, err := e.diaries.List(ctx, filter, after, exportBatch)
+			if err != nil {
+				return err
+			}
+			for i := range entries {
+				if err := fn(&entries[i]); err != nil {
+					return err
+				}
+			}
+			if len(entries) < exportBatch {
+				break
+			}
+			last := entries[len(entries)-1]
+			after = &repositories.DiaryCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+	return nil
+}
+
+func (e *AccountExporter) exportEntry(ctx context.Context, entry *models.DiaryEntry) (*ExportEntry, error) {
+	attachments, err := e.attachments.List(ctx, entry.UserID, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := &ExportEntry{DiaryEntry: *entry}
+	for _, attachment := range attachments {
+		out.Attachments = append(out.Attachments, ExportAttachment{Attachment: attachment})
+	}
+	return out, nil
+}
+
+// WriteJSON menulis satu dokumen {"profile": ..., "entries": [...]} tanpa isi file lampiran
+func (e *AccountExporter) WriteJSON(ctx context.Context, w io.Writer, user *models.User) error {
+	profile, err := json.Marshal(exportProfile(user))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"exported_at":%q,"profile":%s,"entries":`, time.Now().UTC().Format(time.RFC3339), profile); err != nil {
+		return err
+	}
+	if err := e.writeEntriesJSON(ctx, w, user.ID, nil); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// writeEntriesJSON menulis array JSON entri; visit dipanggil untuk setiap entri sebelum ditulis
+func (e *AccountExporter) writeEntriesJSON(ctx context.Context, w io.Writer, userID primitive.ObjectID, visit func(*ExportEntry) error) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := e.eachEntry(ctx, userID, func(entry *models.DiaryEntry) error {
+		out, err := e.exportEntry(ctx, entry)
+		if err != nil {
+			return err
+		}
+		if visit != nil {
+			if err := visit(out); err != nil {
+				return err
+			}
+		}
+		raw, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(raw)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+// WriteZip menulis arsip ZIP berisi:
+//   - profile.json
+//   - entries.json, semua entri dalam format yang sama dengan WriteJSON
+//   - entries/<tanggal>-<id>.md, satu file Markdown per entri dengan front-matter YAML
+//     (entri di tempat sampah ada di trash/)
+//   - attachments/<id entri>/<id>-<nama file>, isi file lampiran
+func (e *AccountExporter) WriteZip(ctx context.Context, w io.Writer, user *models.User) error {
+	zw := zip.NewWriter(w)
+
+	profile, err := json.MarshalIndent(exportProfile(user), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "profile.json", user.UpdatedAt, profile); err != nil {
+		return err
+	}
+
+	// entries.json ditulis lebih dulu karena ZIP hanya bisa menulis satu file dalam satu waktu
+	entriesFile, err := zw.CreateHeader(&zip.FileHeader{Name: "entries.json", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	err = e.writeEntriesJSON(ctx, entriesFile, user.ID, func(entry *ExportEntry) error {
+		for i := range entry.Attachments {
+			entry.Attachments[i].Path = attachmentPath(&entry.Attachments[i].Attachment)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Markdown dan lampiran dibaca ulang per batch agar memori tetap kecil
+	err = e.eachEntry(ctx, user.ID, func(entry *models.DiaryEntry) error {
+		out, err := e.exportEntry(ctx, entry)
+		if err != nil {
+			return err
+		}
+		dir := "entries"
+		if entry.DeletedAt != nil {
+			dir = "trash"
+		}
+		name := path.Join(dir, entry.CreatedAt.UTC().Format("2006-01-02")+"-"+entry.ID.Hex()+".md")
+		if err := writeZipFile(zw, name, entryModified(entry), entryMarkdown(entry)); err != nil {
+			return err
+		}
+		for _, attachment := range out.Attachments {
+			if err := e.writeAttachment(ctx, zw, attachmentPath(&attachment.Attachment), &attachment.Attachment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (e *AccountExporter) writeAttachment(ctx context.Context, zw *zip.Writer, name string, attachment *models.Attachment) error {
+	body, err := e.attachments.Open(ctx, attachment, false)
+	if errors.Is(err, ErrBlobNotFound) {
+		log.Printf("Skipping missing attachment blob %s in export", attachment.BlobKey)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Gambar dan dokumen umumnya sudah terkompresi
+	file, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: attachment.CreatedAt})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, body)
+	return err
+}
+
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	file, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
+
+func attachmentPath(attachment *models.Attachment) string {
+	return path.Join("attachments", attachment.EntryID.Hex(), attachment.ID.Hex()+"-"+zipName(attachment.Filename))
+}
+
+// zipName membuang karakter yang bermasalah di nama file dalam arsip
+func zipName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+func entryModified(entry *models.DiaryEntry) time.Time {
+	if !entry.UpdatedAt.IsZero() {
+		return entry.UpdatedAt
+	}
+	return entry.CreatedAt
+}
+
+// entryMarkdown menulis entri sebagai Markdown dengan front-matter YAML. String di
+// front-matter ditulis sebagai string JSON, yang juga string YAML yang valid.
+func entryMarkdown(entry *models.DiaryEntry) []byte {
+	var b strings.Builder
+	field := func(key string, value any) {
+		raw, _ := json.Marshal(value)
+		fmt.Fprintf(&b, "%s: %s\n", key, raw)
+	}
+
+	b.WriteString("---\n")
+	field("id", entry.ID.Hex())
+	field("title", entry.Title)
+	format := entry.ContentFormat
+	if format == "" {
+		format = models.ContentPlain
+	}
+	field("content_format", format)
+	field("created_at", entry.CreatedAt.UTC().Format(time.RFC3339))
+	if !entry.UpdatedAt.IsZero() {
+		field("updated_at", entry.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+	if entry.DeletedAt != nil {
+		field("deleted_at", entry.DeletedAt.UTC().Format(time.RFC3339))
+	}
+	if entry.Emotion != "" {
+		field("emotion", entry.Emotion)
+	}
+	if entry.Sentiment != "" {
+		field("sentiment", entry.Sentiment)
+	}
+	if len(entry.Tags) > 0 {
+		field("tags", entry.Tags)
+	}
+	if entry.Encrypted {
+		// Judul dan isi adalah ciphertext client; kuncinya ada di profile.json
+		field("encrypted", true)
+	}
+	b.WriteString("---\n\n")
+
+	if entry.Title != "" && !entry.Encrypted {
+		fmt.Fprintf(&b, "# %s\n\n", entry.Title)
+	}
+	b.WriteString(entry.Content)
+	if !strings.HasSuffix(entry.Content, "\n") {
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
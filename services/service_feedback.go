@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/repositories"
+)
+
+// Jumlah feedback yang dibaca per batch saat ekspor
+const feedbackBatch = 500
+
+// ExportAnalysisFeedback menulis semua koreksi user atas hasil analisis sebagai JSON Lines
+// (satu objek models.AnalysisFeedback per baris) dan mengembalikan jumlah baris yang ditulis
+func ExportAnalysisFeedback(ctx context.Context, w io.Writer, feedback repositories.AnalysisFeedbackRepository) (int, error) {
+	enc := json.NewEncoder(w)
+	written := 0
+	var after primitive.ObjectID
+	for {
+		batch, err := feedback.Scan(ctx, after, feedbackBatch)
+		if err != nil {
+			return written, err
+		}
+		for i := range batch {
+			if err := enc.Encode(&batch[i]); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(batch) < feedbackBatch {
+			return written, nil
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+)
+
+const (
+	geminiModel = "gemini-2.5-flash-lite"
+	// geminiPromptVersion dinaikkan setiap kali prompt diubah, dan dicatat di hasil analisis
+	geminiPromptVersion = "emotion-scores-v2"
+)
+
+// GeminiAnalyzer menganalisis emosi memakai Gemini Flash.
+// Client dibuat sekali dan dipakai ulang untuk semua request.
+type GeminiAnalyzer struct {
+	client *genai.Client
+	model  *genai.GenerativeModel
+}
+
+// NewGeminiAnalyzer membuat client Gemini dengan API key yang diberikan
+func NewGeminiAnalyzer(apiKey string) (*GeminiAnalyzer, error) {
+	if apiKey == "" {
+		return nil, errors.New("gemini flash api key is empty")
+	}
+
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey(apiKey))
+	if err != nil {
+		log.Printf("Failed to create Gemini Flash client: %v", err)
+		return nil, err
+	}
+
+	model := client.GenerativeModel(geminiModel)
+	model.ResponseMIMEType = "application/json"
+	return &GeminiAnalyzer{
+		client: client,
+		model:  model,
+	}, nil
+}
+
+// Close menutup koneksi client Gemini
+func (g *GeminiAnalyzer) Close() error {
+	return g.client.Close()
+}
+
+// Analyze mengambil teks dan mengembalikan skor emosi dan sentimen dari Gemini.
+// Respons kosong atau JSON yang tidak valid dikembalikan sebagai error.
+func (g *GeminiAnalyzer) Analyze(ctx context.Context, text string) (*AnalysisResult, error) {
+	prompt := `Analyze the following text for the emotions it expresses and its overall sentiment.
+	Return ONLY a JSON object with these keys:
+	- "scores": an object with a score from 0 to 1 for EACH of these Indonesian emotions,
+	  several emotions may score high at the same time:
+	  - "senang" (for happy, joy, excited)
+	  - "sedih" (for sad, crying, disappointed)
+	  - "marah" (for angry, frustrated, annoyed)
+	  - "takut" (for fear, scared, anxious)
+	  - "mengantuk" (for tired, sleepy, exhausted)
+	  - "berpikir" (for thinking, confused, wondering)
+	  - "cinta" (for love, crush, affection)
+	  - "percaya_diri" (for confident, cool, proud)
+	- "sentiment": one of "positive", "negative", "neutral"
+	- "sentiment_score": a number from -1 (very negative) to 1 (very positive)
+	- "confidence": a number from 0 to 1, how confident you are in this analysis
+
+	Example: {"scores": {"senang": 0.8, "sedih": 0, "marah": 0, "takut": 0.1, "mengantuk": 0, "berpikir": 0.3, "cinta": 0, "percaya_diri": 0.4}, "sentiment": "positive", "sentiment_score": 0.7, "confidence": 0.85}
+	Text: "` + text + `"`
+
+	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		log.Printf("Error generating content from Gemini Flash: %v", err)
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, errors.New("gemini returned no content")
+	}
+
+	result := ""
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if txt, ok := part.(genai.Text); ok {
+			result += string(txt)
+		}
+	}
+
+	var analysis struct {
+		Scores         map[string]float64 `json:"scores"`
+		Sentiment      string             `json:"sentiment"`
+		SentimentScore float64            `json:"sentiment_score"`
+		Confidence     float64            `json:"confidence"`
+	}
+
+	// Bersihkan result dari backtick dan blok markdown jika ada
+	cleanResult := strings.TrimSpace(result)
+	if after, ok := strings.CutPrefix(cleanResult, "```json"); ok {
+		cleanResult = after
+	}
+	if after, ok := strings.CutPrefix(cleanResult, "```"); ok {
+		cleanResult = after
+	}
+	cleanResult = strings.TrimSpace(cleanResult)
+	cleanResult = strings.TrimSuffix(cleanResult, "```")
+
+	if err := json.Unmarshal([]byte(cleanResult), &analysis); err != nil {
+		log.Printf("Error unmarshalling Gemini Flash response: %v, Raw Response: %s", err, result)
+		return nil, fmt.Errorf("invalid gemini response: %w", err)
+	}
+	if len(analysis.Scores) == 0 {
+		log.Printf("Gemini Flash response has no emotion scores, Raw Response: %s", result)
+		return nil, errors.New("gemini response has no emotion scores")
+	}
+
+	scores, sentiment, err := geminiLabels(analysis.Scores, analysis.Sentiment)
+	if err != nil {
+		log.Printf("Gemini Flash response has invalid labels: %v, Raw Response: %s", err, result)
+		return nil, err
+	}
+
+	out := newAnalysisResult(scores, sentiment, analysis.SentimentScore, analysis.Confidence)
+	out.Detail.Provider = config.AnalyzerGemini
+	out.Detail.Model = geminiModel
+	out.Detail.PromptVersion = geminiPromptVersion
+	return out, nil
+}
+
+// geminiLabels memetakan label emosi dan sentimen dari Gemini ke label kanonik. Gemini kadang
+// mengembalikan sinonim atau penulisan lain ("Senang", "happy", "positive "); label yang tetap
+// tidak dikenal membuat seluruh respons ditolak agar analisis dicoba ulang.
+func geminiLabels(raw map[string]float64, rawSentiment string) (map[string]float64, models.Sentiment, error) {
+	scores := map[string]float64{}
+	for label, score := range raw {
+		emotion, ok := models.MatchEmotion(label)
+		if !ok {
+			return nil, "", fmt.Errorf("gemini returned unknown emotion %q", label)
+		}
+		// Dua sinonim dari emosi yang sama memakai skor tertinggi
+		scores[string(emotion)] = max(scores[string(emotion)], score)
+	}
+
+	if strings.TrimSpace(rawSentiment) == "" {
+		return scores, "", nil
+	}
+	sentiment, ok := models.MatchSentiment(rawSentiment)
+	if !ok {
+		return nil, "", fmt.Errorf("gemini returned unknown sentiment %q", rawSentiment)
+	}
+	return scores, sentiment, nil
+}
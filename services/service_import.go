@@ -0,0 +1,484 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+)
+
+// Format file impor yang didukung
+const (
+	ImportAuto     = "auto"
+	ImportDayOne   = "dayone"
+	ImportJourney  = "journey"
+	ImportMarkdown = "markdown"
+	ImportWebDiary = "web-diary" // ekspor dari /api/profile/export
+)
+
+var ImportFormats = []string{ImportAuto, ImportDayOne, ImportJourney, ImportMarkdown, ImportWebDiary}
+
+// Isi ZIP yang sudah didekompresi boleh lebih besar dari file unggahan, tetapi dibatasi
+// agar arsip kecil yang mengembang sangat besar (zip bomb) tidak menghabiskan memori
+const importUnzipRatio = 10
+
+var (
+	ErrImportFormat   = errors.New("unrecognized import file; expected Day One JSON, Journey export, Markdown ZIP or a web-diary export")
+	ErrImportTooLarge = errors.New("import file is too large")
+)
+
+// ImportedEntry adalah satu entri hasil parsing file impor sebelum disimpan
+type ImportedEntry struct {
+	// Source menunjuk asal entri di file impor (nama file atau id), untuk preview dan pesan error
+	Source  string
+	Title   string
+	Content string
+	// ContentFormat adalah format isi (models.ContentPlain, ContentMarkdown, ContentHTML)
+	ContentFormat string
+	Tags          []string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	// Emotion, Sentiment dan rincian analisisnya hanya terisi dari ekspor web-diary yang sudah dianalisis
+	Emotion         models.Emotion
+	EmotionSource   string
+	Sentiment       models.Sentiment
+	SentimentSource string
+	AnalysisDetail  *models.AnalysisDetail
+	AnalyzedAt      *time.Time
+	// Skip berisi alasan entri tidak bisa diimpor, mis. entri terenkripsi end-to-end
+	Skip string
+}
+
+// ContentHash adalah hash isi entri untuk mendeteksi duplikat; spasi di awal/akhir dan
+// akhir baris Windows diabaikan
+func ContentHash(content string) string {
+	normalized := strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// importFile adalah satu file JSON/Markdown, langsung dari unggahan atau dari dalam ZIP
+type importFile struct {
+	name     string
+	modified time.Time
+	data     []byte
+}
+
+// ParseImport membaca file impor dan mengembalikan format yang terdeteksi beserta entrinya,
+// urut dari yang paling lama. format berisi salah satu ImportFormats.
+func ParseImport(data []byte, filename, format string, cfg config.ImportConfig) (string, []ImportedEntry, error) {
+	if len(data) > cfg.MaxSize {
+		return "", nil, ErrImportTooLarge
+	}
+
+	var files []importFile
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		if files, err = unzipImport(data, int64(cfg.MaxSize)*importUnzipRatio); err != nil {
+			return "", nil, err
+		}
+	} else {
+		files = []importFile{{name: path.Base(filename), modified: time.Now(), data: data}}
+	}
+
+	if format == "" || format == ImportAuto {
+		format = detectImportFormat(files)
+		if format == "" {
+			return "", nil, ErrImportFormat
+		}
+	}
+
+	var entries []ImportedEntry
+	var err error
+	switch format {
+	case ImportDayOne:
+		entries, err = parseDayOne(files)
+	case ImportJourney:
+		entries, err = parseJourney(files)
+	case ImportMarkdown:
+		entries, err = parseMarkdownFiles(files)
+	case ImportWebDiary:
+		entries, err = parseWebDiary(files)
+	default:
+		return "", nil, fmt.Errorf("format must be one of %s", strings.Join(ImportFormats, ", "))
+	}
+	if err != nil {
+		return format, nil, err
+	}
+	if len(entries) == 0 {
+		return format, nil, fmt.Errorf("no %s entries found in import file", format)
+	}
+	if len(entries) > cfg.MaxEntries {
+		return format, nil, fmt.Errorf("import file has %d entries, at most %d can be imported at once", len(entries), cfg.MaxEntries)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	return format, entries, nil
+}
+
+func unzipImport(data []byte, maxBytes int64) ([]importFile, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %w", err)
+	}
+
+	var files []importFile
+	var total int64
+	for _, f := range archive.File {
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		switch strings.ToLower(path.Ext(base)) {
+		case ".json", ".md", ".markdown", ".txt":
+		default:
+			continue // foto dan lampiran lain tidak diimpor
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+		raw, err := io.ReadAll(io.LimitReader(r, maxBytes-total+1))
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f.Name, err)
+		}
+		if total += int64(len(raw)); total > maxBytes {
+			return nil, ErrImportTooLarge
+		}
+		files = append(files, importFile{name: f.Name, modified: f.Modified, data: raw})
+	}
+	return files, nil
+}
+
+// detectImportFormat mengenali format dari nama dan struktur file
+func detectImportFormat(files []importFile) string {
+	markdown := false
+	for _, f := range files {
+		if path.Base(f.name) == "entries.json" {
+			return ImportWebDiary
+		}
+		if strings.ToLower(path.Ext(f.name)) != ".json" {
+			markdown = true
+			continue
+		}
+
+		var probe struct {
+			Profile     json.RawMessage   `json:"profile"`
+			Entries     []json.RawMessage `json:"entries"`
+			DateJournal json.RawMessage   `json:"date_journal"`
+		}
+		trimmed := bytes.TrimSpace(f.data)
+		if bytes.HasPrefix(trimmed, []byte("[")) {
+			// Journey juga bisa diekspor sebagai satu array entri
+			var list []struct {
+				DateJournal json.RawMessage `json:"date_journal"`
+			}
+			if json.Unmarshal(trimmed, &list) == nil && len(list) > 0 && list[0].DateJournal != nil {
+				return ImportJourney
+			}
+			continue
+		}
+		if json.Unmarshal(trimmed, &probe) != nil {
+			continue
+		}
+		switch {
+		case probe.Profile != nil && probe.Entries != nil:
+			return ImportWebDiary
+		case probe.DateJournal != nil:
+			return ImportJourney
+		case len(probe.Entries) > 0 && bytes.Contains(probe.Entries[0], []byte(`"creationDate"`)):
+			return ImportDayOne
+		}
+	}
+	if markdown {
+		return ImportMarkdown
+	}
+	return ""
+}
+
+type dayOneEntry struct {
+	UUID         string    `json:"uuid"`
+	CreationDate time.Time `json:"creationDate"`
+	ModifiedDate time.Time `json:"modifiedDate"`
+	Text         string    `json:"text"`
+	Tags         []string  `json:"tags"`
+}
+
+// parseDayOne membaca Journal.json dari ekspor JSON Day One (satu file per jurnal)
+func parseDayOne(files []importFile) ([]ImportedEntry, error) {
+	var entries []ImportedEntry
+	for _, f := range files {
+		if strings.ToLower(path.Ext(f.name)) != ".json" {
+			continue
+		}
+		var journal struct {
+			Entries []dayOneEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(f.data, &journal); err != nil {
+			return nil, fmt.Errorf("%s is not a Day One journal: %w", f.name, err)
+		}
+		for _, e := range journal.Entries {
+			title, content := splitHeading(unescapeDayOne(e.Text))
+			entries = append(entries, ImportedEntry{
+				Source:        f.name + "#" + e.UUID,
+				Title:         title,
+				Content:       content,
+				ContentFormat: models.ContentMarkdown, // teks Day One ditulis dalam Markdown
+				Tags:          e.Tags,
+				CreatedAt:     e.CreationDate,
+				UpdatedAt:     e.ModifiedDate,
+			})
+		}
+	}
+	return entries, nil
+}
+
+var dayOneEscape = regexp.MustCompile(`\\([\\.\-!#*_()\[\]{}+>|~` + "`" + `])`)
+
+// unescapeDayOne membuang backslash yang ditambahkan Day One di depan tanda baca Markdown
+func unescapeDayOne(text string) string {
+	return dayOneEscape.ReplaceAllString(text, "$1")
+}
+
+type journeyEntry struct {
+	ID           string   `json:"id"`
+	Text         string   `json:"text"`
+	DateJournal  int64    `json:"date_journal"` // milidetik sejak epoch
+	DateModified int64    `json:"date_modified"`
+	Tags         []string `json:"tags"`
+}
+
+// parseJourney membaca ekspor Journey: satu file JSON per entri, atau satu array entri
+func parseJourney(files []importFile) ([]ImportedEntry, error) {
+	var entries []ImportedEntry
+	for _, f := range files {
+		if strings.ToLower(path.Ext(f.name)) != ".json" {
+			continue
+		}
+		var list []journeyEntry
+		trimmed := bytes.TrimSpace(f.data)
+		if bytes.HasPrefix(trimmed, []byte("[")) {
+			if err := json.Unmarshal(trimmed, &list); err != nil {
+				return nil, fmt.Errorf("%s is not a Journey export: %w", f.name, err)
+			}
+		} else {
+			var single journeyEntry
+			if err := json.Unmarshal(trimmed, &single); err != nil {
+				return nil, fmt.Errorf("%s is not a Journey entry: %w", f.name, err)
+			}
+			list = []journeyEntry{single}
+		}
+
+		for _, e := range list {
+			if e.DateJournal == 0 {
+				return nil, fmt.Errorf("%s has a Journey entry without date_journal", f.name)
+			}
+			entry := ImportedEntry{
+				Source:        f.name,
+				Content:       e.Text,
+				ContentFormat: models.ContentPlain,
+				Tags:          e.Tags,
+				CreatedAt:     time.UnixMilli(e.DateJournal),
+			}
+			if htmlTag.MatchString(e.Text) {
+				// Journey menyimpan teks berformat sebagai HTML; disanitasi saat disimpan
+				entry.ContentFormat = models.ContentHTML
+			}
+			if e.ID != "" {
+				entry.Source += "#" + e.ID
+			}
+			if e.DateModified > 0 {
+				entry.UpdatedAt = time.UnixMilli(e.DateModified)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+var htmlTag = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+
+var datePrefix = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[-_ ]*`)
+
+// parseMarkdownFiles membaca satu file Markdown per entri. Judul diambil dari front-matter
+// "title", heading pertama, atau nama file; tanggal dari front-matter "date"/"created_at",
+// awalan YYYY-MM-DD pada nama file, atau waktu modifikasi file.
+func parseMarkdownFiles(files []importFile) ([]ImportedEntry, error) {
+	var entries []ImportedEntry
+	for _, f := range files {
+		switch strings.ToLower(path.Ext(f.name)) {
+		case ".md", ".markdown", ".txt":
+		default:
+			continue
+		}
+		meta, body := splitFrontMatter(string(f.data))
+
+		entry := ImportedEntry{Source: f.name, CreatedAt: f.modified, Tags: metaList(meta["tags"]), ContentFormat: models.ContentMarkdown}
+		if strings.ToLower(path.Ext(f.name)) == ".txt" {
+			entry.ContentFormat = models.ContentPlain
+		}
+		if format := metaString(meta["content_format"]); format != "" {
+			// Ditulis oleh ekspor web-diary untuk isi yang bukan Markdown
+			entry.ContentFormat = format
+		}
+		base := strings.TrimSuffix(path.Base(f.name), path.Ext(f.name))
+		if m := datePrefix.FindStringSubmatch(base); m != nil {
+			if t, err := time.Parse("2006-01-02", m[1]); err == nil {
+				entry.CreatedAt = t
+			}
+			base = strings.TrimPrefix(base, m[0])
+		}
+		for _, key := range []string{"created_at", "date"} {
+			if t, ok := metaTime(meta[key]); ok {
+				entry.CreatedAt = t
+				break
+			}
+		}
+		if t, ok := metaTime(meta["updated_at"]); ok {
+			entry.UpdatedAt = t
+		}
+
+		entry.Title, entry.Content = splitHeading(body)
+		if title := metaString(meta["title"]); title != "" {
+			entry.Title = title
+		}
+		if entry.Title == "" {
+			entry.Title = strings.TrimSpace(base)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// splitFrontMatter memisahkan front-matter YAML sederhana (key: value per baris) dari isi
+func splitFrontMatter(text string) (map[string]string, string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return nil, text
+	}
+	header, body, ok := strings.Cut(text[4:], "\n---\n")
+	if !ok {
+		return nil, text
+	}
+	meta := map[string]string{}
+	for _, line := range strings.Split(header, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			meta[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return meta, body
+}
+
+// metaString membaca nilai front-matter yang bisa berupa string berkutip atau tanpa kutip
+func metaString(value string) string {
+	var s string
+	if strings.HasPrefix(value, `"`) && json.Unmarshal([]byte(value), &s) == nil {
+		return s
+	}
+	return strings.Trim(value, `'"`)
+}
+
+// metaList membaca daftar seperti [a, b], ["a","b"] atau a, b
+func metaList(value string) []string {
+	var list []string
+	if json.Unmarshal([]byte(value), &list) == nil {
+		return list
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	for _, item := range strings.Split(value, ",") {
+		if item = metaString(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func metaTime(value string) (time.Time, bool) {
+	value = metaString(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// splitHeading menjadikan heading "# ..." di baris pertama sebagai judul
+func splitHeading(text string) (string, string) {
+	text = strings.TrimLeft(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	first, rest, _ := strings.Cut(text, "\n")
+	if title, ok := strings.CutPrefix(first, "# "); ok {
+		return strings.TrimSpace(title), strings.TrimSpace(rest)
+	}
+	return "", strings.TrimSpace(text)
+}
+
+// parseWebDiary membaca entries.json dari ZIP ekspor, atau dokumen dari ekspor JSON
+func parseWebDiary(files []importFile) ([]ImportedEntry, error) {
+	for _, f := range files {
+		var exported []ExportEntry
+		switch {
+		case path.Base(f.name) == "entries.json":
+			if err := json.Unmarshal(f.data, &exported); err != nil {
+				return nil, fmt.Errorf("invalid entries.json: %w", err)
+			}
+		case strings.ToLower(path.Ext(f.name)) == ".json":
+			var doc struct {
+				Entries []ExportEntry `json:"entries"`
+			}
+			if err := json.Unmarshal(f.data, &doc); err != nil || doc.Entries == nil {
+				continue
+			}
+			exported = doc.Entries
+		default:
+			continue
+		}
+
+		entries := make([]ImportedEntry, 0, len(exported))
+		for _, e := range exported {
+			entry := ImportedEntry{
+				Source:          e.ID.Hex(),
+				Title:           e.Title,
+				Content:         e.Content,
+				ContentFormat:   e.ContentFormat,
+				Tags:            e.Tags,
+				CreatedAt:       e.CreatedAt,
+				UpdatedAt:       e.UpdatedAt,
+				EmotionSource:   e.EmotionSource,
+				SentimentSource: e.SentimentSource,
+				AnalysisDetail:  e.AnalysisDetail,
+				AnalyzedAt:      e.AnalyzedAt,
+			}
+			// Ekspor lama bisa berisi label yang belum kanonik; label yang tidak dikenal dibuang
+			// sehingga entri diimpor tanpa hasil analisis
+			emotion, emotionOK := models.MatchEmotion(string(e.Emotion))
+			sentiment, sentimentOK := models.MatchSentiment(string(e.Sentiment))
+			if emotionOK && sentimentOK {
+				entry.Emotion, entry.Sentiment = emotion, sentiment
+			}
+			switch {
+			case e.Encrypted:
+				entry.Skip = "end-to-end encrypted entries can only be imported by the client"
+			case e.DeletedAt != nil:
+				entry.Skip = "entry was in the trash"
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+	return nil, errors.New("web-diary export has no entries.json")
+}
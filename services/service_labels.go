@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+// Jumlah entri yang dibaca per batch oleh NormalizeLabels
+const normalizeLabelsBatch = 500
+
+// LabelReport merangkum hasil satu kali NormalizeLabels
+type LabelReport struct {
+	Scanned    int // entri yang diperiksa
+	Normalized int // entri yang labelnya ditulis ulang ke bentuk kanonik
+	// Invalid adalah entri dengan label yang tidak dikenal sama sekali; emosinya diganti
+	// models.EmotionUnknown dan sentimennya models.SentimentNeutral, seperti analisis yang gagal
+	Invalid int
+	// Skipped adalah entri yang berubah selama diproses; tulisan barunya sudah tervalidasi
+	Skipped int
+}
+
+// canonicalLabels mengembalikan label kanonik untuk label tersimpan, dan melaporkan apakah
+// semua label dikenali. Label kosong dibiarkan kosong.
+func canonicalLabels(labels repositories.StoredLabels) (repositories.StoredLabels, bool) {
+	next, valid := labels, true
+	if labels.Emotion != "" {
+		if emotion, ok := models.MatchEmotion(labels.Emotion); ok {
+			next.Emotion = string(emotion)
+		} else {
+			valid = strings.EqualFold(strings.TrimSpace(labels.Emotion), string(models.EmotionUnknown))
+			next.Emotion = string(models.EmotionUnknown)
+		}
+	}
+	if labels.Sentiment != "" {
+		if sentiment, ok := models.MatchSentiment(labels.Sentiment); ok {
+			next.Sentiment = string(sentiment)
+		} else {
+			valid = false
+			next.Sentiment = string(models.SentimentNeutral)
+		}
+	}
+	return next, valid
+}
+
+// NormalizeLabels menulis ulang emosi dan sentimen semua entri yang belum memakai label
+// kanonik, mis. "Senang", "happy" atau "positive " dari versi analyzer lama. Dengan dryRun
+// tidak ada yang ditulis; laporan berisi entri yang akan diubah. Aman dijalankan ulang dan
+// bersamaan dengan server yang sedang berjalan.
+func NormalizeLabels(ctx context.Context, store repositories.LabelStore, dryRun bool) (*LabelReport, error) {
+	report := &LabelReport{}
+	after := primitive.NilObjectID
+	for {
+		labels, err := store.ScanLabels(ctx, after, normalizeLabelsBatch)
+		if err != nil {
+			return report, err
+		}
+		for _, stored := range labels {
+			after = stored.ID
+			report.Scanned++
+
+			next, valid := canonicalLabels(stored)
+			if next == stored {
+				continue
+			}
+			replaced := true
+			if !dryRun {
+				if replaced, err = store.ReplaceLabels(ctx, stored, next); err != nil {
+					return report, err
+				}
+			}
+			switch {
+			case !replaced:
+				report.Skipped++
+			case valid:
+				report.Normalized++
+			default:
+				report.Invalid++
+			}
+		}
+		if len(labels) < normalizeLabelsBatch {
+			return report, nil
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+)
+
+// LexiconAnalyzer menganalisis emosi secara offline dengan kamus kata
+// Indonesia/Inggris, tanpa memanggil API eksternal.
+type LexiconAnalyzer struct {
+	emotions  map[string]string
+	sentiment map[string]int
+	negations map[string]bool
+}
+
+// Versi kamus, dicatat di hasil analisis sebagai model
+const lexiconVersion = "lexicon-v1"
+
+var lexiconEmotionWords = map[string][]string{
+	"senang": {
+		"senang", "bahagia", "gembira", "seru", "asyik", "asik", "lega", "ceria", "syukur", "bersyukur", "tertawa", "ketawa", "hore", "yay",
+		"happy", "joy", "glad", "excited", "fun", "great", "awesome", "cheerful", "laugh", "laughed", "grateful", "delighted",
+	},
+	"sedih": {
+		"sedih", "kecewa", "menangis", "nangis", "galau", "kesepian", "sepi", "patah", "hancur", "murung", "rindu", "kangen", "duka",
+		"sad", "cry", "cried", "crying", "disappointed", "lonely", "heartbroken", "miss", "upset", "depressed", "grief",
+	},
+	"marah": {
+		"marah", "kesal", "jengkel", "benci", "sebal", "sebel", "emosi", "muak", "geram", "dongkol",
+		"angry", "mad", "furious", "annoyed", "frustrated", "hate", "irritated", "rage", "pissed",
+	},
+	"takut": {
+		"takut", "cemas", "khawatir", "panik", "gelisah", "was-was", "ngeri", "gugup", "deg-degan",
+		"fear", "afraid", "scared", "anxious", "worried", "nervous", "panic", "terrified",
+	},
+	"mengantuk": {
+		"mengantuk", "ngantuk", "lelah", "capek", "capai", "letih", "lemas", "begadang", "tidur",
+		"tired", "sleepy", "exhausted", "drained", "fatigue", "fatigued", "sleep", "weary",
+	},
+	"berpikir": {
+		"berpikir", "mikir", "bingung", "penasaran", "merenung", "bertanya", "ragu", "heran", "entah",
+		"think", "thinking", "confused", "wonder", "wondering", "curious", "unsure", "reflect", "doubt",
+	},
+	"cinta": {
+		"cinta", "sayang", "naksir", "rindu", "pacar", "romantis", "mesra",
+		"love", "loved", "crush", "affection", "darling", "romantic", "adore", "sweetheart",
+	},
+	"percaya_diri": {
+		"percaya", "yakin", "bangga", "berhasil", "sukses", "mantap", "keren", "hebat", "menang",
+		"confident", "proud", "cool", "succeed", "succeeded", "success", "win", "won", "achieved", "accomplished",
+	},
+}
+
+var lexiconPositiveWords = []string{
+	"senang", "bahagia", "gembira", "seru", "asyik", "asik", "lega", "ceria", "syukur", "bersyukur", "baik", "bagus", "indah",
+	"cinta", "sayang", "bangga", "berhasil", "sukses", "mantap", "keren", "hebat", "menang", "yakin", "tenang", "nyaman",
+	"happy", "joy", "glad", "excited", "fun", "great", "awesome", "good", "nice", "love", "proud", "grateful", "calm",
+	"success", "win", "won", "confident", "cool", "delighted", "wonderful", "amazing", "relieved",
+}
+
+var lexiconNegativeWords = []string{
+	"sedih", "kecewa", "menangis", "nangis", "galau", "kesepian", "hancur", "murung", "marah", "kesal", "jengkel", "benci",
+	"sebal", "sebel", "muak", "takut", "cemas", "khawatir", "panik", "gelisah", "lelah", "capek", "letih", "buruk", "jelek",
+	"gagal", "sakit", "stres", "stress", "bingung",
+	"sad", "cry", "crying", "disappointed", "lonely", "upset", "depressed", "angry", "mad", "furious", "annoyed",
+	"frustrated", "hate", "afraid", "scared", "anxious", "worried", "tired", "exhausted", "bad", "awful", "terrible",
+	"fail", "failed", "sick", "confused",
+}
+
+var lexiconNegationWords = []string{
+	"tidak", "tak", "bukan", "gak", "ga", "nggak", "enggak", "belum", "jangan",
+	"not", "no", "never", "dont", "don't", "didnt", "didn't", "isnt", "isn't", "wasnt", "wasn't",
+}
+
+// NewLexiconAnalyzer membuat analyzer berbasis kamus bawaan
+func NewLexiconAnalyzer() *LexiconAnalyzer {
+	l := &LexiconAnalyzer{
+		emotions:  map[string]string{},
+		sentiment: map[string]int{},
+		negations: map[string]bool{},
+	}
+
+	// Kata yang muncul di beberapa emosi masuk ke label pertama sesuai urutan
+	for _, emotion := range EmotionLabels {
+		for _, word := range lexiconEmotionWords[emotion] {
+			if _, exists := l.emotions[word]; !exists {
+				l.emotions[word] = emotion
+			}
+		}
+	}
+	for _, word := range lexiconPositiveWords {
+		l.sentiment[word] = 1
+	}
+	for _, word := range lexiconNegativeWords {
+		l.sentiment[word] = -1
+	}
+	for _, word := range lexiconNegationWords {
+		l.negations[word] = true
+	}
+
+	return l
+}
+
+// Analyze menghitung emosi dan sentimen dari kemunculan kata di kamus. Skor emosi adalah
+// proporsi kata emosi yang cocok dengan label tersebut, skor sentimen adalah rata-rata polaritas
+// kata bersentimen, dan confidence naik seiring banyaknya kata emosi yang ditemukan.
+func (l *LexiconAnalyzer) Analyze(ctx context.Context, text string) (*AnalysisResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	score, polarWords, emotionWords := 0, 0, 0
+	negated := false
+
+	for _, token := range tokenize(text) {
+		if l.negations[token] {
+			negated = true
+			continue
+		}
+
+		// Kata yang dinegasikan ("tidak senang") tidak dihitung sebagai emosinya,
+		// tetapi polaritas sentimennya dibalik
+		if emotion, ok := l.emotions[token]; ok && !negated {
+			counts[emotion]++
+			emotionWords++
+		}
+		if polarity, ok := l.sentiment[token]; ok {
+			if negated {
+				polarity = -polarity
+			}
+			score += polarity
+			polarWords++
+		}
+		negated = false
+	}
+
+	scores := map[string]float64{}
+	best := 0
+	for _, label := range EmotionLabels {
+		if emotionWords > 0 {
+			scores[label] = float64(counts[label]) / float64(emotionWords)
+		}
+		best = max(best, counts[label])
+	}
+
+	sentiment := models.SentimentNeutral
+	switch {
+	case score > 0:
+		sentiment = models.SentimentPositive
+	case score < 0:
+		sentiment = models.SentimentNegative
+	}
+	sentimentScore := 0.0
+	if polarWords > 0 {
+		sentimentScore = float64(score) / float64(polarWords)
+	}
+	confidence := 0.0
+	if emotionWords > 0 {
+		// Satu kata emosi saja memberi confidence 0.5 meskipun label lain tidak muncul
+		confidence = float64(best) / float64(emotionWords+1)
+	}
+
+	result := newAnalysisResult(scores, sentiment, sentimentScore, confidence)
+	result.Detail.Provider = config.AnalyzerLexicon
+	result.Detail.Model = lexiconVersion
+	return result, nil
+}
+
+// tokenize memecah teks menjadi kata huruf kecil; tanda hubung dan apostrof
+// dipertahankan agar "deg-degan" dan "don't" tetap satu token
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '\''
+	})
+}
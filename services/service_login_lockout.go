@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"web-diary-be/config"
+)
+
+// Kegagalan login dihitung dalam window ini; login berhasil me-reset hitungannya
+const loginFailureWindow = 24 * time.Hour
+
+// LoginLockout mengunci login untuk satu email setelah beberapa kali gagal berturut-turut.
+// Lama penguncian bertambah dua kali lipat untuk setiap kegagalan berikutnya.
+type LoginLockout struct {
+	store     CounterStore
+	threshold int
+	base      time.Duration
+	max       time.Duration
+}
+
+// NewLoginLockout membuat lockout sesuai konfigurasi rate limit
+func NewLoginLockout(store CounterStore, cfg config.RateLimitConfig) *LoginLockout {
+	return &LoginLockout{
+		store:     store,
+		threshold: cfg.LockoutThreshold,
+		base:      cfg.LockoutBase,
+		max:       cfg.LockoutMax,
+	}
+}
+
+// Locked melaporkan berapa lama lagi email tersebut terkunci (0 jika tidak terkunci)
+func (l *LoginLockout) Locked(ctx context.Context, email string) (time.Duration, error) {
+	count, expiresAt, err := l.store.Get(ctx, lockKey(email))
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	return time.Until(expiresAt), nil
+}
+
+// Fail mencatat login gagal dan mengembalikan lama penguncian jika batas terlampaui
+func (l *LoginLockout) Fail(ctx context.Context, email string) (time.Duration, error) {
+	failures, _, err := l.store.Increment(ctx, failureKey(email), loginFailureWindow)
+	if err != nil || failures < l.threshold {
+		return 0, err
+	}
+
+	lock := l.base << (failures - l.threshold)
+	if lock <= 0 || lock > l.max {
+		lock = l.max
+	}
+	if _, _, err := l.store.Increment(ctx, lockKey(email), lock); err != nil {
+		return 0, err
+	}
+	return lock, nil
+}
+
+// Succeed me-reset hitungan kegagalan setelah login berhasil
+func (l *LoginLockout) Succeed(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, failureKey(email))
+}
+
+func failureKey(email string) string {
+	return "login-fail:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func lockKey(email string) string {
+	return "login-lock:" + strings.ToLower(strings.TrimSpace(email))
+}
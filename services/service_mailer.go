@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"web-diary-be/config"
+)
+
+// Message adalah email teks sederhana yang dikirim aplikasi
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer adalah backend pengiriman email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer membuat mailer sesuai backend yang dipilih di konfigurasi
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Backend {
+	case config.MailerSMTP:
+		return &SMTPMailer{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+		}, nil
+	case config.MailerLog:
+		return &LogMailer{Path: cfg.SinkFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Backend)
+	}
+}
+
+// SMTPMailer mengirim email lewat server SMTP (STARTTLS otomatis jika didukung server)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// Header sederhana; subject dan alamat berasal dari aplikasi, bukan input bebas user,
+	// tetapi CR/LF tetap dibuang untuk mencegah header injection
+	clean := func(s string) string { return strings.NewReplacer("\r", "", "\n", "").Replace(s) }
+	raw := "From: " + clean(m.From) + "\r\n" +
+		"To: " + clean(msg.To) + "\r\n" +
+		"Subject: " + clean(msg.Subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + msg.Body
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, []byte(raw))
+}
+
+// LogMailer tidak mengirim email sungguhan: isi email ditulis ke log dan, jika Path diisi,
+// ditambahkan ke file tersebut. Dipakai untuk development dan testing.
+type LogMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	entry := fmt.Sprintf("=== %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+
+	if m.Path == "" {
+		log.Printf("📧 Mail (not sent):\n%s", entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(entry)
+	return err
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetService menerbitkan dan memakai token reset password
+type PasswordResetService struct {
+	resets   repositories.PasswordResetRepository
+	users    repositories.UserRepository
+	sessions *SessionService
+	mailer   Mailer
+	ttl      time.Duration
+	url      string
+}
+
+func NewPasswordResetService(cfg config.AuthConfig, resets repositories.PasswordResetRepository, users repositories.UserRepository, sessions *SessionService, mailer Mailer) *PasswordResetService {
+	return &PasswordResetService{
+		resets:   resets,
+		users:    users,
+		sessions: sessions,
+		mailer:   mailer,
+		ttl:      cfg.PasswordResetTTL,
+		url:      cfg.PasswordResetURL,
+	}
+}
+
+// RequestPasswordReset membuat token reset untuk email yang diberikan dan mengirimkannya.
+// Jika email tidak terdaftar tidak terjadi apa-apa, supaya pemanggil tidak bisa
+// membedakan email terdaftar dan tidak.
+func (r *PasswordResetService) RequestPasswordReset(ctx context.Context, email, ip string) error {
+	user, err := r.users.FindByEmail(ctx, email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	// Hanya token terakhir yang berlaku
+	now := time.Now()
+	if err := r.resets.InvalidateForUser(ctx, user.ID, now); err != nil {
+		return err
+	}
+
+	reset := models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: HashToken(token),
+		RequestIP: ip,
+		CreatedAt: now,
+		ExpiresAt: now.Add(r.ttl),
+	}
+	if err := r.resets.Create(ctx, &reset); err != nil {
+		return err
+	}
+
+	link := r.url + "?token=" + url.QueryEscape(token)
+	return r.mailer.Send(ctx, Message{
+		To:      user.Email,
+		Subject: "Reset password Web Diary",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKami menerima permintaan reset password untuk akun kamu. Buka tautan berikut untuk membuat password baru:\n%s\n\nTautan berlaku selama %s dan hanya bisa dipakai sekali. Abaikan email ini jika kamu tidak memintanya.\n",
+			user.Username, link, r.ttl,
+		),
+	})
+}
+
+// ResetPassword memakai token reset (sekali pakai) untuk mengganti password,
+// lalu mencabut semua sesi user agar token yang mungkin dicuri tidak berlaku lagi
+func (r *PasswordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	// Hash dulu: jika bcrypt gagal (mis. password lebih dari 72 byte) token belum terpakai
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), 14)
+	if err != nil {
+		return err
+	}
+
+	// Token ditandai terpakai secara atomik sehingga tidak bisa dipakai dua kali
+	now := time.Now()
+	reset, err := r.resets.Consume(ctx, HashToken(token), now)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	password := string(hashed)
+	_, err = r.users.Update(ctx, reset.UserID, repositories.UserUpdate{Password: &password, UpdatedAt: now})
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	revoked, err := r.sessions.RevokeOtherSessions(ctx, reset.UserID, primitive.NilObjectID, "password reset")
+	if err != nil {
+		return err
+	}
+	log.Printf("Password reset for user %s, revoked %d sessions", reset.UserID.Hex(), revoked)
+	return nil
+}
+
+// DeleteUserResets menghapus semua token reset milik user, dipakai saat akun dihapus
+func (r *PasswordResetService) DeleteUserResets(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.resets.DeleteByUser(ctx, userID)
+	return err
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"web-diary-be/config"
+)
+
+// CounterStore menyimpan counter ber-TTL untuk rate limiting dan lockout login.
+// Counter memakai fixed window: TTL hanya ditentukan saat counter dibuat, dan
+// counter yang sudah kedaluwarsa dianggap tidak ada.
+type CounterStore interface {
+	// Increment menambah counter dan mengembalikan nilai baru beserta waktu kedaluwarsanya
+	Increment(ctx context.Context, key string, ttl time.Duration) (int, time.Time, error)
+	// Get mengembalikan nilai counter; 0 jika tidak ada atau sudah kedaluwarsa
+	Get(ctx context.Context, key string) (int, time.Time, error)
+	// Reset menghapus counter
+	Reset(ctx context.Context, key string) error
+}
+
+// NewCounterStore membuat store sesuai RATE_LIMIT_STORE
+func NewCounterStore(cfg config.RateLimitConfig, db *config.DB) (CounterStore, error) {
+	switch cfg.Store {
+	case config.RateLimitStoreMemory:
+		return NewMemoryCounterStore(), nil
+	case config.RateLimitStoreMongo:
+		return &MongoCounterStore{collection: db.RateLimits}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+}
+
+// MemoryCounterStore menyimpan counter di memori proses. Cocok untuk satu instance;
+// gunakan MongoCounterStore jika aplikasi dijalankan di beberapa instance.
+type MemoryCounterStore struct {
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+}
+
+type memoryCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{counters: map[string]memoryCounter{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryCounterStore) Increment(ctx context.Context, key string, ttl time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	counter, ok := s.counters[key]
+	if !ok || !now.Before(counter.expiresAt) {
+		counter = memoryCounter{expiresAt: now.Add(ttl)}
+	}
+	counter.count++
+	s.counters[key] = counter
+	return counter.count, counter.expiresAt, nil
+}
+
+func (s *MemoryCounterStore) Get(ctx context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || !time.Now().Before(counter.expiresAt) {
+		return 0, time.Time{}, nil
+	}
+	return counter.count, counter.expiresAt, nil
+}
+
+func (s *MemoryCounterStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	return nil
+}
+
+// sweep membuang counter kedaluwarsa paling sering sekali per menit
+func (s *MemoryCounterStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	for key, counter := range s.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// MongoCounterStore menyimpan counter di koleksi 'rate_limits' sehingga
+// batasnya berlaku bersama untuk semua instance aplikasi
+type MongoCounterStore struct {
+	collection *mongo.Collection
+}
+
+func (s *MongoCounterStore) Increment(ctx context.Context, key string, ttl time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	// Pipeline update: counter yang sudah kedaluwarsa (tapi belum dihapus TTL monitor)
+	// dimulai ulang dari 1 dengan window baru
+	live := bson.M{"$gt": bson.A{"$expires_at", now}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"count":      bson.M{"$cond": bson.A{live, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+			"expires_at": bson.M{"$cond": bson.A{live, "$expires_at", now.Add(ttl)}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc struct {
+		Count     int       `bson:"count"`
+		ExpiresAt time.Time `bson:"expires_at"`
+	}
+	if err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&doc); err != nil {
+		return 0, time.Time{}, err
+	}
+	return doc.Count, doc.ExpiresAt, nil
+}
+
+func (s *MongoCounterStore) Get(ctx context.Context, key string) (int, time.Time, error) {
+	var doc struct {
+		Count     int       `bson:"count"`
+		ExpiresAt time.Time `bson:"expires_at"`
+	}
+	err := s.collection.FindOne(ctx, bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return doc.Count, doc.ExpiresAt, nil
+}
+
+func (s *MongoCounterStore) Reset(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
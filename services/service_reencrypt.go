@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+// Jumlah dokumen yang dibaca per batch oleh Reencrypt
+const reencryptBatch = 200
+
+// ReencryptReport merangkum hasil satu kali Reencrypt
+type ReencryptReport struct {
+	RewrappedKeys int // data key yang dibungkus ulang dengan master key aktif
+	RotatedKeys   int // data key versi baru yang dibuat
+	Documents     int // entri/revisi yang dienkripsi ulang, termasuk entri plaintext lama
+	// Skipped adalah dokumen yang berubah selama diproses; tulisan barunya sudah memakai kunci terbaru
+	Skipped int
+}
+
+// Reencrypt membungkus ulang semua data key yang masih memakai master key lama, membuat data
+// key versi baru untuk setiap user jika rotate diisi, lalu mengenkripsi ulang judul/isi di
+// stores yang masih plaintext atau belum memakai data key terbaru milik pemiliknya.
+// Aman dijalankan ulang dan bersamaan dengan server yang sedang berjalan.
+func (c *AtRestCipher) Reencrypt(ctx context.Context, rotate bool, stores ...repositories.TextStore) (*ReencryptReport, error) {
+	report := &ReencryptReport{}
+
+	users := map[primitive.ObjectID]bool{}
+	after := primitive.NilObjectID
+	for {
+		keys, err := c.keys.Scan(ctx, after, reencryptBatch)
+		if err != nil {
+			return report, err
+		}
+		for i := range keys {
+			after = keys[i].ID
+			users[keys[i].UserID] = true
+			rewrapped, err := c.Rewrap(ctx, &keys[i])
+			if err != nil {
+				return report, err
+			}
+			if rewrapped {
+				report.RewrappedKeys++
+			}
+		}
+		if len(keys) < reencryptBatch {
+			break
+		}
+	}
+
+	if rotate {
+		for userID := range users {
+			if _, err := c.RotateDataKey(ctx, userID); err != nil {
+				return report, err
+			}
+			report.RotatedKeys++
+		}
+	}
+
+	latest := map[primitive.ObjectID]int{}
+	for _, store := range stores {
+		if err := c.reencryptStore(ctx, store, latest, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (c *AtRestCipher) reencryptStore(ctx context.Context, store repositories.TextStore, latest map[primitive.ObjectID]int, report *ReencryptReport) error {
+	after := primitive.NilObjectID
+	for {
+		texts, err := store.ScanText(ctx, after, reencryptBatch)
+		if err != nil {
+			return err
+		}
+		for _, text := range texts {
+			after = text.ID
+
+			version, ok := latest[text.UserID]
+			if !ok {
+				key, err := c.latestKey(ctx, text.UserID)
+				if err != nil {
+					return err
+				}
+				version = key.Version
+				latest[text.UserID] = version
+			}
+			if text.Format == models.StorageAESGCM && text.KeyVersion == version {
+				continue
+			}
+
+			plain, err := c.Open(ctx, text)
+			if err != nil {
+				return err
+			}
+			sealed, err := c.Seal(ctx, plain)
+			if err != nil {
+				return err
+			}
+			replaced, err := store.ReplaceText(ctx, text, sealed)
+			if err != nil {
+				return err
+			}
+			if replaced {
+				report.Documents++
+			} else {
+				report.Skipped++
+			}
+		}
+		if len(texts) < reencryptBatch {
+			return nil
+		}
+	}
+}
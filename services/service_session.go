@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+const (
+	// Jumlah hash refresh token lama yang disimpan per sesi untuk deteksi reuse
+	maxPreviousTokenHashes = 50
+	// last_seen_at hanya ditulis ulang jika sudah lebih lama dari ini,
+	// agar setiap request terautentikasi tidak selalu menulis ke database
+	lastSeenResolution = time.Minute
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// SessionService mengelola sesi login dan rotasi refresh token
+type SessionService struct {
+	sessions   repositories.SessionRepository
+	refreshTTL time.Duration
+}
+
+func NewSessionService(sessions repositories.SessionRepository, cfg config.AuthConfig) *SessionService {
+	return &SessionService{sessions: sessions, refreshTTL: cfg.RefreshTokenTTL}
+}
+
+// CreateSession membuat sesi baru untuk user yang berhasil login dan
+// mengembalikan refresh token (plaintext, hanya dikirim sekali ke client)
+func (s *SessionService) CreateSession(ctx context.Context, userID primitive.ObjectID, userAgent, ip string) (*models.Session, string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:               primitive.NewObjectID(),
+		UserID:           userID,
+		RefreshTokenHash: HashToken(token),
+		UserAgent:        userAgent,
+		IP:               ip,
+		CreatedAt:        now,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(s.refreshTTL),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// RotateRefreshToken menukar refresh token dengan yang baru. Token yang sudah pernah
+// dirotasi dianggap dicuri: seluruh sesi (keluarga token) langsung dicabut.
+func (s *SessionService) RotateRefreshToken(ctx context.Context, token, userAgent, ip string) (*models.Session, string, error) {
+	hash := HashToken(token)
+	now := time.Now()
+
+	session, err := s.sessions.FindByRefreshHash(ctx, hash)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, "", s.detectReuse(ctx, hash)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	newToken, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rotation := repositories.SessionRotation{
+		NewHash:      HashToken(newToken),
+		UserAgent:    userAgent,
+		IP:           ip,
+		At:           now,
+		ExpiresAt:    now.Add(s.refreshTTL),
+		KeepPrevious: maxPreviousTokenHashes,
+	}
+	rotated, err := s.sessions.Rotate(ctx, session.ID, hash, rotation)
+	if err != nil {
+		return nil, "", err
+	}
+	if !rotated {
+		// Request refresh lain dengan token yang sama menang lebih dulu
+		return nil, "", s.detectReuse(ctx, hash)
+	}
+
+	session.RefreshTokenHash = rotation.NewHash
+	session.UserAgent = userAgent
+	session.IP = ip
+	session.LastSeenAt = now
+	session.ExpiresAt = rotation.ExpiresAt
+	return session, newToken, nil
+}
+
+// detectReuse mencabut sesi jika hash adalah refresh token yang sudah dirotasi
+func (s *SessionService) detectReuse(ctx context.Context, hash string) error {
+	session, err := s.sessions.FindByPreviousHash(ctx, hash)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Refresh token reuse detected for session %s (user %s), revoking", session.ID.Hex(), session.UserID.Hex())
+	if err := s.RevokeSession(ctx, session.ID, "refresh token reuse"); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// FindSessionByRefreshToken mengambil sesi aktif pemilik refresh token
+func (s *SessionService) FindSessionByRefreshToken(ctx context.Context, token string) (*models.Session, error) {
+	session, err := s.sessions.FindByRefreshHash(ctx, HashToken(token))
+	if errors.Is(err, repositories.ErrNotFound) || err == nil && session.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RevokeSession mencabut satu sesi; access token yang membawa session id ini langsung ditolak
+func (s *SessionService) RevokeSession(ctx context.Context, sessionID primitive.ObjectID, reason string) error {
+	_, err := s.sessions.Revoke(ctx, primitive.NilObjectID, sessionID, reason, time.Now())
+	return err
+}
+
+// IsSessionActive memastikan sesi milik user, belum dicabut dan belum kedaluwarsa
+func (s *SessionService) IsSessionActive(ctx context.Context, sessionID, userID primitive.ObjectID) (bool, error) {
+	return s.sessions.IsActive(ctx, sessionID, userID, time.Now())
+}
+
+// TouchSession memperbarui waktu terakhir sesi dipakai beserta IP dan user-agent-nya
+func (s *SessionService) TouchSession(ctx context.Context, sessionID primitive.ObjectID, userAgent, ip string) error {
+	now := time.Now()
+	return s.sessions.Touch(ctx, sessionID, userAgent, ip, now, now.Add(-lastSeenResolution))
+}
+
+// ListActiveSessions mengembalikan sesi user yang belum dicabut/kedaluwarsa, terbaru dipakai lebih dulu
+func (s *SessionService) ListActiveSessions(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	return s.sessions.ListActive(ctx, userID, time.Now())
+}
+
+// RevokeUserSession mencabut satu sesi milik user dan melaporkan apakah sesi tersebut ada
+func (s *SessionService) RevokeUserSession(ctx context.Context, userID, sessionID primitive.ObjectID, reason string) (bool, error) {
+	return s.sessions.Revoke(ctx, userID, sessionID, reason, time.Now())
+}
+
+// RevokeOtherSessions mencabut semua sesi user kecuali keepID (biasanya sesi saat ini).
+// keepID kosong berarti semua sesi dicabut.
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userID, keepID primitive.ObjectID, reason string) (int64, error) {
+	return s.sessions.RevokeAll(ctx, userID, keepID, reason, time.Now())
+}
+
+// DeleteUserSessions menghapus semua sesi user, dipakai saat akun dihapus
+func (s *SessionService) DeleteUserSessions(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.sessions.DeleteByUser(ctx, userID)
+	return err
+}
+
+// HashToken menghasilkan hash SHA-256 (hex) untuk token acak yang disimpan di database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken membuat token acak 256-bit yang aman untuk URL
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
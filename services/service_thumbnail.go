@@ -0,0 +1,69 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif" // decoder untuk thumbnail
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Gambar yang lebih besar dari ini tidak dibuatkan thumbnail agar decode tidak menghabiskan memori
+const maxThumbnailSourcePixels = 40_000_000
+
+// imageThumbnail mengecilkan gambar sehingga sisi terpanjangnya maxSide pixel dan
+// mengembalikan JPEG beserta ukuran gambar asli. ok bernilai false jika format
+// tidak bisa di-decode (mis. WebP) atau gambar terlalu besar.
+func imageThumbnail(data []byte, maxSide int) (thumb []byte, width, height int, ok bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return nil, 0, 0, false
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, false
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			tw, th = maxSide, max(1, h*maxSide/w)
+		} else {
+			tw, th = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	// Box filter: setiap pixel thumbnail adalah rata-rata area sumber yang diwakilinya.
+	// Pixel transparan dicampur dengan latar putih karena JPEG tidak punya alpha.
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := bounds.Min.Y + y*h/th
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*h/th)
+		for x := 0; x < tw; x++ {
+			x0 := bounds.Min.X + x*w/tw
+			x1 := max(x0+1, bounds.Min.X+(x+1)*w/tw)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					white := uint64(0xffff - ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					b += uint64(cb) + white
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: 0xffff})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, false
+	}
+	return out.Bytes(), w, h, true
+}
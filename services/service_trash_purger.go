@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/repositories"
+)
+
+// TrashPurger menghapus permanen entri diary yang sudah melewati masa retensi di tempat sampah
+type TrashPurger struct {
+	diaries     repositories.DiaryRepository
+	revisions   repositories.RevisionRepository
+	attachments *AttachmentService
+	retention   time.Duration
+	interval    time.Duration
+	wg          sync.WaitGroup
+}
+
+func NewTrashPurger(diaries repositories.DiaryRepository, revisions repositories.RevisionRepository, attachments *AttachmentService, cfg config.TrashConfig) *TrashPurger {
+	return &TrashPurger{
+		diaries:     diaries,
+		revisions:   revisions,
+		attachments: attachments,
+		retention:   cfg.Retention,
+		interval:    cfg.PurgeInterval,
+	}
+}
+
+// Start menjalankan purger di background sampai ctx dibatalkan
+func (p *TrashPurger) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := p.PurgeExpired(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to purge trashed diary entries: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("Started trash purger (retention %s)", p.retention)
+}
+
+// Wait menunggu purger berhenti setelah ctx dibatalkan
+func (p *TrashPurger) Wait() {
+	p.wg.Wait()
+}
+
+// PurgeExpired menghapus entri (beserta revisi dan lampirannya) yang masuk tempat sampah lebih lama
+// dari masa retensi dihitung dari now, dan mengembalikan jumlah entri yang dihapus. Lampiran dan
+// revisi dihapus sebelum entrinya: jika gagal, entri tetap di tempat sampah dan dicoba lagi di
+// putaran berikutnya.
+func (p *TrashPurger) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	before := now.Add(-p.retention)
+	expired, err := p.diaries.ExpiredTrash(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var errs []error
+	for _, id := range expired {
+		ids := []primitive.ObjectID{id}
+		if err := p.attachments.DeleteForEntries(ctx, ids); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := p.revisions.DeleteByEntries(ctx, ids); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted, err := p.diaries.PurgeTrashed(ctx, id, before)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if deleted {
+			purged++
+		}
+	}
+	if purged > 0 {
+		log.Printf("Purged %d diary entries from trash", purged)
+	}
+	return purged, errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"web-diary-be/config"
+	"web-diary-be/models"
+	"web-diary-be/repositories"
+)
+
+const verifyEmailPurpose = "verify_email"
+
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// EmailVerifier mengirim dan memvalidasi token verifikasi email
+type EmailVerifier struct {
+	users   repositories.UserRepository
+	mailer  Mailer
+	secret  []byte
+	ttl     time.Duration
+	baseURL string
+}
+
+func NewEmailVerifier(cfg *config.Config, users repositories.UserRepository, mailer Mailer) *EmailVerifier {
+	return &EmailVerifier{
+		users:   users,
+		mailer:  mailer,
+		secret:  []byte(cfg.JWTSecret),
+		ttl:     cfg.Auth.EmailVerificationTTL,
+		baseURL: strings.TrimSuffix(cfg.Mail.AppBaseURL, "/"),
+	}
+}
+
+// StartEmailVerification membuat nonce baru untuk user (membatalkan token sebelumnya)
+// lalu mengirim email berisi token verifikasi yang ditandatangani
+func (v *EmailVerifier) StartEmailVerification(ctx context.Context, user *models.User) error {
+	nonce, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	if err := v.users.SetVerificationNonce(ctx, user.ID, nonce); err != nil {
+		return err
+	}
+	user.VerificationNonce = nonce
+
+	claims := jwt.MapClaims{
+		"purpose": verifyEmailPurpose,
+		"user_id": user.ID.Hex(),
+		"nonce":   nonce,
+		"exp":     jwt.NewNumericDate(time.Now().Add(v.ttl)),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(v.secret)
+	if err != nil {
+		return err
+	}
+
+	link := v.baseURL + "/api/auth/verify?token=" + url.QueryEscape(token)
+	return v.mailer.Send(ctx, Message{
+		To:      user.Email,
+		Subject: "Verifikasi email Web Diary",
+		Body: fmt.Sprintf(
+			"Halo %s,\n\nKlik tautan berikut untuk memverifikasi email kamu:\n%s\n\nTautan berlaku selama %s dan hanya bisa dipakai sekali.\n",
+			user.Username, link, v.ttl,
+		),
+	})
+}
+
+// VerifyEmail memvalidasi token verifikasi dan menandai email user sebagai terverifikasi.
+// Nonce dihapus setelah dipakai sehingga token yang sama tidak bisa dipakai lagi.
+func (v *EmailVerifier) VerifyEmail(ctx context.Context, token string) error {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		return v.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !parsed.Valid {
+		return ErrInvalidVerificationToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != verifyEmailPurpose {
+		return ErrInvalidVerificationToken
+	}
+	userID, _ := claims["user_id"].(string)
+	nonce, _ := claims["nonce"].(string)
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil || nonce == "" {
+		return ErrInvalidVerificationToken
+	}
+
+	verified, err := v.users.MarkEmailVerified(ctx, objID, nonce, time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}